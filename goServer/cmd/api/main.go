@@ -1,7 +1,11 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
+	"time"
 
 	"github.com/gofiber/fiber/v3"
 	"github.com/gofiber/fiber/v3/middleware/cors"
@@ -9,8 +13,12 @@ import (
 
 	"goServer/internal/config"
 	"goServer/internal/db"
+	"goServer/internal/mailer"
+	"goServer/internal/metrics"
+	"goServer/internal/middleware"
 	"goServer/internal/model"
 	"goServer/internal/router"
+	"goServer/internal/tracing"
 )
 
 func init() {
@@ -23,40 +31,107 @@ func init() {
 }
 
 func main() {
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// run starts the server and blocks until it stops. Failures are returned
+// rather than fatal so the deferred trace flush runs before the process exits.
+func run() error {
 
 	cfg := config.Load()
 
 	if cfg.DatabaseURL == "" {
-		log.Fatal("DATABASE_URL is not set")
+		return errors.New("DATABASE_URL is not set")
 	}
 	if cfg.JWTSecret == "" {
-		log.Fatal("JWT_SECRET is not set")
+		return errors.New("JWT_SECRET is not set")
 	}
 	if cfg.AppPort == "" {
 		cfg.AppPort = "8080"
 	}
 
-	database := db.Connect(cfg)
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg)
+	if err != nil {
+		return fmt.Errorf("tracing setup failed: %w", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			log.Printf("[main] failed to flush traces: %v", err)
+		}
+	}()
+
+	database, err := db.Connect(cfg)
+	if err != nil {
+		return err
+	}
 
 	//database migrations
-	if err := database.AutoMigrate(&model.User{}); err != nil {
-		log.Fatal("Migration failed:", err)
+	if err := db.DropStaleForeignKeys(database); err != nil {
+		return fmt.Errorf("migration failed: %w", err)
+	}
+	if err := database.AutoMigrate(
+		&model.User{},
+		&model.Post{},
+		&model.Media{},
+		&model.Notification{},
+		&model.AuditLog{},
+		&model.UserToken{},
+		&model.RecoveryCode{},
+		&model.LoginThrottle{},
+		&model.UsernameHistory{},
+		&model.FederatedIdentity{},
+		&model.OIDCAuthRequest{},
+		&model.PersonalAccessToken{},
+		&model.Session{},
+		&model.DataExport{},
+		&model.Block{},
+		&model.Mute{},
+		&model.FollowRequest{},
+		&model.FollowSuggestion{},
+		&model.SuggestionDismissal{},
+		&model.PostRevision{},
+		&model.PostHashtag{},
+		&model.PostDraft{},
+		&model.BookmarkCollection{},
+		&model.Bookmark{},
+		&model.Poll{},
+		&model.PollOption{},
+		&model.PollVote{},
+		&model.PinnedPost{},
+	); err != nil {
+		return fmt.Errorf("migration failed: %w", err)
+	}
+	if err := db.CreateIndexes(database); err != nil {
+		return fmt.Errorf("migration failed: %w", err)
 	}
 	log.Println("[main] Database migrations completed successfully")
 
+	go metrics.Serve(cfg.MetricsPort)
+
 	app := fiber.New()
 
+	app.Use(middleware.Tracing())
+	app.Use(middleware.Metrics())
+
 	app.Use(cors.New(cors.Config{
 		AllowOrigins: []string{"*"},
 		AllowMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		AllowHeaders: []string{"Content-Type", "Authorization"},
 	}))
 
-	router.SetupRoutes(app, database, cfg)
+	mail, err := mailer.New(cfg)
+	if err != nil {
+		return fmt.Errorf("mailer setup failed: %w", err)
+	}
 
-	log.Printf("Server listening on port %s", cfg.AppPort)
-	if err := app.Listen(cfg.AppPort); err != nil {
-		log.Fatal(err)
+	if err := router.SetupRoutes(app, database, cfg, mail); err != nil {
+		return fmt.Errorf("route setup failed: %w", err)
 	}
 
+	log.Printf("Server listening on port %s", cfg.AppPort)
+	return app.Listen(cfg.AppPort)
 }
@@ -1,13 +1,114 @@
 package config
 
 import (
+	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// OIDCProviderConfig configures one OpenID Connect identity provider
+type OIDCProviderConfig struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+}
+
 type Config struct {
 	DatabaseURL string
 	JWTSecret   string
 	AppPort     string
+	MetricsPort string
+
+	ServiceName   string
+	TraceExporter string
+	OTLPEndpoint  string
+
+	StatsRefreshInterval time.Duration // how often the cached system stats are recomputed
+	StatsMaxAge          time.Duration // oldest cached system stats served before recomputing on request
+
+	SoftDeleteRetention time.Duration
+	PurgeInterval       time.Duration
+
+	AppBaseURL               string
+	RequireEmailVerification bool
+	EmailVerifyTTL           time.Duration
+	PasswordResetTTL         time.Duration
+
+	LoginMaxFailures      int
+	LoginMaxFailuresPerIP int
+	LoginFailureWindow    time.Duration
+	LoginLockoutBase      time.Duration
+	LoginLockoutMax       time.Duration
+
+	PasswordMinLength     int
+	PasswordMaxLength     int
+	PasswordBlocklistFile string
+	Argon2Memory          int
+	Argon2Time            int
+	Argon2Threads         int
+
+	UsernameChangeCooldown time.Duration
+	UsernameRedirectGrace  time.Duration
+
+	OIDCProviders      []OIDCProviderConfig
+	OIDCAuthRequestTTL time.Duration
+	OIDCSignupTTL      time.Duration
+
+	AccountDeletionGrace time.Duration
+
+	ExportDir           string
+	ExportLinkTTL       time.Duration
+	ExportRetention     time.Duration
+	ExportMediaMaxBytes int
+	ExportPollInterval  time.Duration
+
+	PostEditWindow        time.Duration // how long after posting a post can be edited
+	PostMaxEdits          int
+	PostMaxDrafts         int           // drafts and scheduled posts a user may keep at once
+	PostMaxPins           int           // posts a user may pin to their profile
+	ScheduledPostInterval time.Duration // how often due scheduled posts are published
+	PollResultsInterval   time.Duration // how often closed polls' results are sent out
+
+	FeedRankWindow          time.Duration // oldest post the ranked feed considers
+	FeedRankCandidates      int           // per candidate source
+	FeedTrendingWindow      time.Duration
+	FeedTrendingPosts       int
+	FeedAffinityWindow      time.Duration
+	FeedRecencyHalfLife     time.Duration
+	FeedRecencyWeight       float64
+	FeedVelocityWeight      float64
+	FeedAffinityWeight      float64
+	FeedOutOfNetworkFactor  float64 // multiplies the score of posts not written by followees
+	FeedAuthorRepeatPenalty float64 // multiplies the score once per post already placed from the same author
+
+	SuggestionInterval         time.Duration
+	SuggestionsPerUser         int
+	SuggestionEngagementWindow time.Duration
+	SuggestionMutualWeight     float64
+	SuggestionEngagementWeight float64
+	SuggestionPopularityWeight float64
+
+	SessionTTL              time.Duration
+	SessionHistoryRetention time.Duration
+
+	AccessTokenMaxPerUser int
+	AccessTokenMaxTTL     time.Duration // 0 allows tokens that never expire
+
+	MFAIssuer    string
+	MFATokenTTL  time.Duration
+	MFASecretKey string // encrypts stored TOTP secrets; defaults to JWTSecret
+
+	MailDriver   string
+	MailFrom     string
+	MailDir      string
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
 }
 
 func getEnv(key, fallback string) string {
@@ -18,10 +119,164 @@ func getEnv(key, fallback string) string {
 
 }
 
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Printf("[config] invalid duration for %s: %q, using %s", key, v, fallback)
+		return fallback
+	}
+	return d
+}
+
+func getEnvInt(key string, fallback int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		log.Printf("[config] invalid integer for %s: %q, using %d", key, v, fallback)
+		return fallback
+	}
+	return n
+}
+
+func getEnvFloat(key string, fallback float64) float64 {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		log.Printf("[config] invalid number for %s: %q, using %g", key, v, fallback)
+		return fallback
+	}
+	return f
+}
+
+// loadOIDCProviders reads OIDC_PROVIDERS (e.g. "google,gitlab") and, for
+// each name, OIDC_<NAME>_ISSUER, _CLIENT_ID, _CLIENT_SECRET and _SCOPES
+func loadOIDCProviders() []OIDCProviderConfig {
+	var providers []OIDCProviderConfig
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		p := OIDCProviderConfig{
+			Name:         name,
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			Scopes:       strings.Fields(getEnv(prefix+"SCOPES", "openid email profile")),
+		}
+		if p.Issuer == "" || p.ClientID == "" {
+			log.Printf("[config] skipping OIDC provider %s: issuer and client id are required", name)
+			continue
+		}
+		providers = append(providers, p)
+	}
+	return providers
+}
+
 func Load() Config {
 	return Config{
 		DatabaseURL: os.Getenv("DATABASE_URL"),
 		JWTSecret:   os.Getenv("JWT_SECRET"),
 		AppPort:     os.Getenv("APP_PORT"),
+		MetricsPort: getEnv("METRICS_PORT", ":9090"),
+
+		ServiceName:   getEnv("SERVICE_NAME", "goServer"),
+		TraceExporter: getEnv("TRACE_EXPORTER", "none"),
+		OTLPEndpoint:  os.Getenv("OTLP_ENDPOINT"),
+
+		StatsRefreshInterval: getEnvDuration("STATS_REFRESH_INTERVAL", time.Minute),
+		StatsMaxAge:          getEnvDuration("STATS_MAX_AGE", 5*time.Minute),
+
+		SoftDeleteRetention: getEnvDuration("SOFT_DELETE_RETENTION", 30*24*time.Hour),
+		PurgeInterval:       getEnvDuration("PURGE_INTERVAL", time.Hour),
+
+		AppBaseURL:               getEnv("APP_BASE_URL", "http://localhost:8080"),
+		RequireEmailVerification: getEnv("REQUIRE_EMAIL_VERIFICATION", "false") == "true",
+		EmailVerifyTTL:           getEnvDuration("EMAIL_VERIFY_TTL", 48*time.Hour),
+		PasswordResetTTL:         getEnvDuration("PASSWORD_RESET_TTL", time.Hour),
+
+		LoginMaxFailures:      getEnvInt("LOGIN_MAX_FAILURES", 5),
+		LoginMaxFailuresPerIP: getEnvInt("LOGIN_MAX_FAILURES_PER_IP", 20),
+		LoginFailureWindow:    getEnvDuration("LOGIN_FAILURE_WINDOW", 15*time.Minute),
+		LoginLockoutBase:      getEnvDuration("LOGIN_LOCKOUT_BASE", time.Minute),
+		LoginLockoutMax:       getEnvDuration("LOGIN_LOCKOUT_MAX", time.Hour),
+
+		PasswordMinLength:     getEnvInt("PASSWORD_MIN_LENGTH", 8),
+		PasswordMaxLength:     getEnvInt("PASSWORD_MAX_LENGTH", 128),
+		PasswordBlocklistFile: os.Getenv("PASSWORD_BLOCKLIST_FILE"),
+		Argon2Memory:          getEnvInt("ARGON2_MEMORY_KIB", 64*1024),
+		Argon2Time:            getEnvInt("ARGON2_TIME", 1),
+		Argon2Threads:         getEnvInt("ARGON2_THREADS", 4),
+
+		UsernameChangeCooldown: getEnvDuration("USERNAME_CHANGE_COOLDOWN", 30*24*time.Hour),
+		UsernameRedirectGrace:  getEnvDuration("USERNAME_REDIRECT_GRACE", 90*24*time.Hour),
+
+		OIDCProviders:      loadOIDCProviders(),
+		OIDCAuthRequestTTL: getEnvDuration("OIDC_AUTH_REQUEST_TTL", 10*time.Minute),
+		OIDCSignupTTL:      getEnvDuration("OIDC_SIGNUP_TTL", 15*time.Minute),
+
+		AccountDeletionGrace: getEnvDuration("ACCOUNT_DELETION_GRACE", 30*24*time.Hour),
+
+		ExportDir:           getEnv("EXPORT_DIR", "exports"),
+		ExportLinkTTL:       getEnvDuration("EXPORT_LINK_TTL", 24*time.Hour),
+		ExportRetention:     getEnvDuration("EXPORT_RETENTION", 7*24*time.Hour),
+		ExportMediaMaxBytes: getEnvInt("EXPORT_MEDIA_MAX_BYTES", 25<<20),
+		ExportPollInterval:  getEnvDuration("EXPORT_POLL_INTERVAL", 30*time.Second),
+
+		PostEditWindow:        getEnvDuration("POST_EDIT_WINDOW", time.Hour),
+		PostMaxEdits:          getEnvInt("POST_MAX_EDITS", 5),
+		PostMaxDrafts:         getEnvInt("POST_MAX_DRAFTS", 100),
+		PostMaxPins:           getEnvInt("POST_MAX_PINS", 3),
+		ScheduledPostInterval: getEnvDuration("SCHEDULED_POST_INTERVAL", 30*time.Second),
+		PollResultsInterval:   getEnvDuration("POLL_RESULTS_INTERVAL", time.Minute),
+
+		FeedRankWindow:          getEnvDuration("FEED_RANK_WINDOW", 72*time.Hour),
+		FeedRankCandidates:      getEnvInt("FEED_RANK_CANDIDATES", 500),
+		FeedTrendingWindow:      getEnvDuration("FEED_TRENDING_WINDOW", 6*time.Hour),
+		FeedTrendingPosts:       getEnvInt("FEED_TRENDING_POSTS", 50),
+		FeedAffinityWindow:      getEnvDuration("FEED_AFFINITY_WINDOW", 30*24*time.Hour),
+		FeedRecencyHalfLife:     getEnvDuration("FEED_RECENCY_HALF_LIFE", 12*time.Hour),
+		FeedRecencyWeight:       getEnvFloat("FEED_RECENCY_WEIGHT", 1.0),
+		FeedVelocityWeight:      getEnvFloat("FEED_VELOCITY_WEIGHT", 0.8),
+		FeedAffinityWeight:      getEnvFloat("FEED_AFFINITY_WEIGHT", 0.6),
+		FeedOutOfNetworkFactor:  getEnvFloat("FEED_OUT_OF_NETWORK_FACTOR", 0.7),
+		FeedAuthorRepeatPenalty: getEnvFloat("FEED_AUTHOR_REPEAT_PENALTY", 0.5),
+
+		SuggestionInterval:         getEnvDuration("SUGGESTION_INTERVAL", 6*time.Hour),
+		SuggestionsPerUser:         getEnvInt("SUGGESTIONS_PER_USER", 50),
+		SuggestionEngagementWindow: getEnvDuration("SUGGESTION_ENGAGEMENT_WINDOW", 30*24*time.Hour),
+		SuggestionMutualWeight:     getEnvFloat("SUGGESTION_MUTUAL_WEIGHT", 1.0),
+		SuggestionEngagementWeight: getEnvFloat("SUGGESTION_ENGAGEMENT_WEIGHT", 0.6),
+		SuggestionPopularityWeight: getEnvFloat("SUGGESTION_POPULARITY_WEIGHT", 0.2),
+
+		SessionTTL:              getEnvDuration("SESSION_TTL", 24*time.Hour),
+		SessionHistoryRetention: getEnvDuration("SESSION_HISTORY_RETENTION", 90*24*time.Hour),
+
+		AccessTokenMaxPerUser: getEnvInt("ACCESS_TOKEN_MAX_PER_USER", 25),
+		AccessTokenMaxTTL:     getEnvDuration("ACCESS_TOKEN_MAX_TTL", 0),
+
+		MFAIssuer:    getEnv("MFA_ISSUER", "goServer"),
+		MFATokenTTL:  getEnvDuration("MFA_TOKEN_TTL", 5*time.Minute),
+		MFASecretKey: getEnv("MFA_SECRET_KEY", os.Getenv("JWT_SECRET")),
+
+		MailDriver:   getEnv("MAIL_DRIVER", "log"),
+		MailFrom:     getEnv("MAIL_FROM", "no-reply@localhost"),
+		MailDir:      getEnv("MAIL_DIR", "mail"),
+		SMTPHost:     getEnv("SMTP_HOST", "localhost"),
+		SMTPPort:     getEnv("SMTP_PORT", "1025"),
+		SMTPUsername: os.Getenv("SMTP_USERNAME"),
+		SMTPPassword: os.Getenv("SMTP_PASSWORD"),
 	}
 }
@@ -0,0 +1,42 @@
+package db
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// staleForeignKeys are constraints whose ON DELETE action changed since
+// they were first created, with the action they should have now. Postgres
+// encodes actions as 'a' no action, 'c' cascade and 'n' set null.
+var staleForeignKeys = []struct {
+	table, name string
+	action      string
+}{
+	// Replies and quotes used to be deleted along with the post they
+	// pointed at
+	{"posts", "fk_posts_replied_post", "n"},
+	{"posts", "fk_posts_quoted_post", "n"},
+}
+
+// DropStaleForeignKeys drops foreign keys whose ON DELETE action no longer
+// matches the models. AutoMigrate only creates missing constraints and never
+// alters existing ones, so run this before it to have them recreated.
+func DropStaleForeignKeys(db *gorm.DB) error {
+	for _, fk := range staleForeignKeys {
+		var stale bool
+		if err := db.Raw(
+			`SELECT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = ? AND confdeltype <> ?)`,
+			fk.name, fk.action,
+		).Scan(&stale).Error; err != nil {
+			return fmt.Errorf("failed to check constraint %s: %w", fk.name, err)
+		}
+		if !stale {
+			continue
+		}
+		if err := db.Exec(fmt.Sprintf(`ALTER TABLE %s DROP CONSTRAINT %s`, fk.table, fk.name)).Error; err != nil {
+			return fmt.Errorf("failed to drop constraint %s: %w", fk.name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package db
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// indexes holds indexes AutoMigrate can't express through struct tags
+var indexes = []string{
+	// Usernames and emails are unique regardless of case. Lookups go
+	// through lower(...) so they use these indexes too.
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username_lower ON users (lower(username))`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_lower ON users (lower(email))`,
+
+	// Follower and following lists are paged newest first
+	`CREATE INDEX IF NOT EXISTS idx_follows_followee_created ON follows (followee_id, created_at DESC, follower_id DESC)`,
+	`CREATE INDEX IF NOT EXISTS idx_follows_follower_created ON follows (follower_id, created_at DESC, followee_id DESC)`,
+
+	// Bookmarks are paged newest first; collection names are unique per
+	// user regardless of case
+	`CREATE INDEX IF NOT EXISTS idx_bookmarks_user_created ON bookmarks (user_id, created_at DESC, post_id DESC)`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_bookmark_collections_name_lower ON bookmark_collections (user_id, lower(name))`,
+}
+
+// CreateIndexes creates the indexes missing from the database. Run it after
+// AutoMigrate; it fails if existing rows violate a unique index, e.g. two
+// accounts whose usernames differ only in case.
+func CreateIndexes(db *gorm.DB) error {
+	for _, stmt := range indexes {
+		if err := db.Exec(stmt).Error; err != nil {
+			return fmt.Errorf("failed to create index: %w", err)
+		}
+	}
+	return nil
+}
@@ -1,27 +1,47 @@
 package db
 
 import (
+	"fmt"
 	"log"
 
 	"goServer/internal/config"
+	"goServer/internal/metrics"
 	"goServer/internal/model"
+	"goServer/internal/tracing"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
-func Connect(cfg config.Config) *gorm.DB {
+// Connect opens the database and registers the metrics and tracing plugins
+func Connect(cfg config.Config) (*gorm.DB, error) {
 	dial := postgres.Open(cfg.DatabaseURL)
 	db, err := gorm.Open(dial, &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Error),
+		// Unique violations come back as gorm.ErrDuplicatedKey, so services
+		// can tell a lost race from a failed query
+		TranslateError: true,
 	})
 	if err != nil {
-		log.Fatalf("failed to connect database: %v", err)
+		return nil, fmt.Errorf("failed to connect database: %w", err)
+	}
+	if err := db.Use(metrics.NewGormPlugin("postgres")); err != nil {
+		return nil, fmt.Errorf("failed to register metrics plugin: %w", err)
+	}
+	if err := db.Use(tracing.NewGormPlugin()); err != nil {
+		return nil, fmt.Errorf("failed to register tracing plugin: %w", err)
+	}
+	// follows carries a created_at column, so it's backed by model.Follow
+	// rather than a bare join table
+	for _, field := range []string{"Followers", "Following"} {
+		if err := db.SetupJoinTable(&model.User{}, field, &model.Follow{}); err != nil {
+			return nil, fmt.Errorf("failed to set up follows join table: %w", err)
+		}
 	}
 	if err := db.AutoMigrate(&model.User{}); err != nil {
 		log.Printf("AutoMigrate warning/error: %v", err)
 	}
 
-	return db
+	return db, nil
 }
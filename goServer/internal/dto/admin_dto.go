@@ -1,7 +1,8 @@
 package dto
 
 type UpdateUserRoleReq struct {
-	Role string `json:"role" validate:"required,oneof=USER ADMIN"`
+	Role   string `json:"role" validate:"required,oneof=USER ADMIN"`
+	Reason string `json:"reason" validate:"max=500"`
 }
 
 type AdminDeleteUserReq struct {
@@ -11,3 +12,35 @@ type AdminDeleteUserReq struct {
 type AdminDeletePostReq struct {
 	Reason string `json:"reason" validate:"required,max=500"`
 }
+
+type AdminRestoreReq struct {
+	Reason string `json:"reason" validate:"max=500"`
+}
+
+type AuditFilterReq struct {
+	ActorID    string `query:"actor_id"`
+	Action     string `query:"action"`
+	TargetType string `query:"target_type"`
+	TargetID   string `query:"target_id"`
+	From       string `query:"from"`                                     // RFC3339
+	To         string `query:"to"`                                       // RFC3339
+	Limit      int    `query:"limit" validate:"omitempty,min=1,max=100"` // 0 uses the default
+	Offset     int    `query:"offset" validate:"min=0"`
+}
+
+type AuditLogRes struct {
+	ID         string      `json:"id"`
+	Seq        int64       `json:"seq"`
+	ActorID    string      `json:"actor_id"`
+	Action     string      `json:"action"`
+	TargetType string      `json:"target_type"`
+	TargetID   string      `json:"target_id"`
+	Reason     string      `json:"reason"`
+	Before     interface{} `json:"before"`
+	After      interface{} `json:"after"`
+	IP         string      `json:"ip"`
+	UserAgent  string      `json:"user_agent"`
+	PrevHash   string      `json:"prev_hash"`
+	Hash       string      `json:"hash"`
+	CreatedAt  string      `json:"created_at"`
+}
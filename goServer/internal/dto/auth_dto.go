@@ -3,5 +3,106 @@ package dto
 type RegisterRequest struct {
 	Username string `json:"username" validate:"required"`
 	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required,min=6"`
+	Password string `json:"password" validate:"required,min=8"`
+}
+
+type VerifyEmailReq struct {
+	Token string `json:"token" query:"token" validate:"required"`
+}
+
+type ForgotPasswordReq struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPasswordReq struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=8"`
+}
+
+// LoginReq identifies the account by Login, which may be a username or an
+// email address. Username and Email are accepted as aliases.
+type LoginReq struct {
+	Login    string `json:"login"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	MFAToken string `json:"mfa_token"`
+	Code     string `json:"code"`
+}
+
+type MFAStatusRes struct {
+	Enabled                bool `json:"enabled"`
+	RecoveryCodesRemaining int  `json:"recovery_codes_remaining"`
+}
+
+type MFAEnrollRes struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type MFACodeReq struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type MFADisableReq struct {
+	Password string `json:"password" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+type RecoveryCodesRes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type OIDCSignupReq struct {
+	SignupToken string `json:"signup_token" validate:"required"`
+	Username    string `json:"username" validate:"required,min=3,max=30"`
+}
+
+type FederatedIdentityRes struct {
+	Provider    string  `json:"provider"`
+	Email       string  `json:"email"`
+	CreatedAt   string  `json:"created_at"`
+	LastLoginAt *string `json:"last_login_at"`
+}
+
+type CreateAccessTokenReq struct {
+	Name          string   `json:"name" validate:"required,max=100"`
+	Scopes        []string `json:"scopes" validate:"required,min=1"`
+	ExpiresInDays int      `json:"expires_in_days"` // 0 means the token never expires, if ACCESS_TOKEN_MAX_TTL allows it
+}
+
+type AccessTokenRes struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Prefix     string   `json:"prefix"`
+	Scopes     []string `json:"scopes"`
+	ExpiresAt  *string  `json:"expires_at"`
+	LastUsedAt *string  `json:"last_used_at"`
+	LastUsedIP string   `json:"last_used_ip"`
+	RevokedAt  *string  `json:"revoked_at"`
+	CreatedAt  string   `json:"created_at"`
+	Token      string   `json:"token,omitempty"` // only set in the response to creation
+}
+
+type SessionRes struct {
+	ID         string `json:"id"`
+	Device     string `json:"device"`
+	UserAgent  string `json:"user_agent"`
+	IP         string `json:"ip"`
+	MFA        bool   `json:"mfa"`
+	Current    bool   `json:"current"`
+	CreatedAt  string `json:"created_at"`
+	LastSeenAt string `json:"last_seen_at"`
+	ExpiresAt  string `json:"expires_at"`
+}
+
+type DataExportRes struct {
+	ID          string  `json:"id"`
+	Status      string  `json:"status"`
+	Size        int64   `json:"size"`
+	Error       string  `json:"error,omitempty"`
+	CreatedAt   string  `json:"created_at"`
+	CompletedAt *string `json:"completed_at"`
+	ExpiresAt   *string `json:"expires_at"`
+	DownloadURL string  `json:"download_url,omitempty"`
 }
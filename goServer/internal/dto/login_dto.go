@@ -1,6 +0,0 @@
-package dto
-
-type LoginRequest struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
-}
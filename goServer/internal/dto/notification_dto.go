@@ -4,6 +4,7 @@ type NotificationRes struct {
 	ID        string `json:"id"`
 	UserID    string `json:"user_id"`
 	Type      string `json:"type"` // LIKE, REPOST, MENTION, FOLLOW, REPLY
+	PostID    string `json:"post_id,omitempty"`
 	Message   string `json:"message"`
 	Read      bool   `json:"read"`
 	CreatedAt string `json:"created_at"`
@@ -12,3 +12,11 @@ type PaginatedRes struct {
 	Offset  int         `json:"offset"`
 	HasMore bool        `json:"has_more"`
 }
+
+// CursorRes is one page of a cursor-paginated list. Pass NextCursor back as
+// ?cursor= to get the next page; it's empty on the last one.
+type CursorRes struct {
+	Items      interface{} `json:"items"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+	HasMore    bool        `json:"has_more"`
+}
@@ -1,11 +1,23 @@
 package dto
 
+import "time"
+
 type CreatePostReq struct {
 	Text         string   `json:"text" validate:"required,max=500"`
 	ReplyTo      *string  `json:"reply_to" validate:"omitempty,uuid4"`
 	IsQuote      bool     `json:"is_quote"`
 	QuotedPostID *string  `json:"quoted_post_id" validate:"omitempty,uuid4"`
 	MediaURLs    []string `json:"media_urls" validate:"dive,url"`
+	Poll         *PollReq `json:"poll"`
+}
+
+type PollReq struct {
+	Options         []string `json:"options" validate:"min=2,max=4,dive,required,max=50"`
+	DurationMinutes int      `json:"duration_minutes" validate:"required"`
+}
+
+type PollVoteReq struct {
+	OptionID string `json:"option_id" validate:"required,uuid4"`
 }
 
 type UpdatePostReq struct {
@@ -27,8 +39,54 @@ type PostRes struct {
 	ReplyCount   int64      `json:"reply_count"`
 	IsLiked      bool       `json:"is_liked"`
 	IsReposted   bool       `json:"is_reposted"`
+	IsBookmarked bool       `json:"is_bookmarked"`
+	IsPinned     bool       `json:"is_pinned"` // only set on the author's profile timeline
+	IsDeleted    bool       `json:"is_deleted"`
+	EditedAt     *string    `json:"edited_at"` // null if never edited
+	EditCount    int        `json:"edit_count"`
+	Poll         *PollRes   `json:"poll"`
 	CreatedAt    string     `json:"created_at"`
 	UpdatedAt    string     `json:"updated_at"`
+
+	Ranking *FeedRankingRes `json:"ranking,omitempty"` // only in the ranked feed with explain=true
+}
+
+// FeedRankingRes explains how a post was scored in the ranked feed.
+// Score = (Recency + Velocity + Affinity) * SourceFactor * DiversityFactor
+type FeedRankingRes struct {
+	Source          string  `json:"source"`
+	Score           float64 `json:"score"`
+	Recency         float64 `json:"recency"`
+	Velocity        float64 `json:"velocity"`
+	Affinity        float64 `json:"affinity"`
+	SourceFactor    float64 `json:"source_factor"`
+	DiversityFactor float64 `json:"diversity_factor"`
+}
+
+// PollRes is a poll as the caller sees it. Vote counts are null until the
+// caller has voted or the poll has closed.
+type PollRes struct {
+	ID            string          `json:"id"`
+	Options       []PollOptionRes `json:"options"`
+	TotalVotes    *int64          `json:"total_votes"`
+	ClosesAt      string          `json:"closes_at"`
+	Closed        bool            `json:"closed"`
+	VotedOptionID *string         `json:"voted_option_id"`
+}
+
+type PollOptionRes struct {
+	ID    string `json:"id"`
+	Text  string `json:"text"`
+	Votes *int64 `json:"votes"`
+}
+
+// PostRevisionRes is one version of a post in its edit history
+type PostRevisionRes struct {
+	Version    int    `json:"version"`
+	Text       string `json:"text"`
+	WrittenAt  string `json:"written_at"`
+	ReplacedAt string `json:"replaced_at,omitempty"`
+	Current    bool   `json:"current"`
 }
 
 type PostDetailRes struct {
@@ -48,6 +106,7 @@ type PostDetailRes struct {
 	ReplyCount   int64      `json:"reply_count"`
 	IsLiked      bool       `json:"is_liked"`
 	IsReposted   bool       `json:"is_reposted"`
+	IsDeleted    bool       `json:"is_deleted"`
 	CreatedAt    string     `json:"created_at"`
 	UpdatedAt    string     `json:"updated_at"`
 }
@@ -58,3 +117,50 @@ type MediaRes struct {
 	MediaType string `json:"media_type"`
 	Position  int    `json:"position"`
 }
+
+// CreateDraftReq saves a post without publishing it. With PublishAt set it
+// is published automatically at that time.
+type CreateDraftReq struct {
+	CreatePostReq
+	PublishAt *time.Time `json:"publish_at"`
+}
+
+// ScheduleDraftReq sets when a draft is published; null unschedules it
+type ScheduleDraftReq struct {
+	PublishAt *time.Time `json:"publish_at"`
+}
+
+type DraftRes struct {
+	ID           string   `json:"id"`
+	Text         string   `json:"text"`
+	ReplyTo      *string  `json:"reply_to"`
+	IsQuote      bool     `json:"is_quote"`
+	QuotedPostID *string  `json:"quoted_post_id"`
+	MediaURLs    []string `json:"media_urls"`
+	Status       string   `json:"status"`
+	PublishAt    *string  `json:"publish_at"`
+	Error        string   `json:"error,omitempty"` // why a scheduled post couldn't be published
+	CreatedAt    string   `json:"created_at"`
+	UpdatedAt    string   `json:"updated_at"`
+}
+
+type BookmarkReq struct {
+	CollectionID string `json:"collection_id" validate:"omitempty,uuid4"` // empty leaves it unsorted
+}
+
+type BookmarkCollectionReq struct {
+	Name string `json:"name" validate:"required,max=50"`
+}
+
+type BookmarkRes struct {
+	Post         PostRes `json:"post"`
+	CollectionID *string `json:"collection_id"`
+	BookmarkedAt string  `json:"bookmarked_at"`
+}
+
+type BookmarkCollectionRes struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	BookmarkCount int64  `json:"bookmark_count"`
+	CreatedAt     string `json:"created_at"`
+}
@@ -3,47 +3,78 @@ package dto
 type UserRegisterReq struct {
 	Email    string `json:"email" validate:"required,email"`
 	Username string `json:"username" validate:"required,min=3,max=30"`
-	Password string `json:"password" validate:"required,min=6"`
-}
-
-type UserLoginReq struct {
-	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required"`
+	Password string `json:"password" validate:"required,min=8"`
 }
 
 type UserUpdateReq struct {
 	DisplayName string `json:"display_name" validate:"max=50"`
 	Bio         string `json:"bio" validate:"max=500"`
 	AvatarURL   string `json:"avatar_url" validate:"url"`
+	IsPrivate   *bool  `json:"is_private"`
+}
+
+type ChangeUsernameReq struct {
+	Username string `json:"username" validate:"required,min=3,max=30"`
 }
 
+// UserRes is how any user appears to others; it never carries an email
 type UserRes struct {
+	ID          string `json:"id"`
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name"`
+	Bio         string `json:"bio"`
+	AvatarURL   string `json:"avatar_url"`
+	IsPrivate   bool   `json:"is_private"`
+	Role        string `json:"role"`
+	CreatedAt   string `json:"created_at"`
+}
+
+// AccountRes is a user as they or an admin see it, with their email
+type AccountRes struct {
 	ID          string `json:"id"`
 	Email       string `json:"email"`
 	Username    string `json:"username"`
 	DisplayName string `json:"display_name"`
 	Bio         string `json:"bio"`
 	AvatarURL   string `json:"avatar_url"`
+	IsPrivate   bool   `json:"is_private"`
 	Role        string `json:"role"`
 	CreatedAt   string `json:"created_at"`
 }
 
 type UserDetailRes struct {
 	ID             string `json:"id"`
-	Email          string `json:"email"`
+	Email          string `json:"email,omitempty"` // only on the caller's own account
 	Username       string `json:"username"`
 	DisplayName    string `json:"display_name"`
 	Bio            string `json:"bio"`
 	AvatarURL      string `json:"avatar_url"`
+	IsPrivate      bool   `json:"is_private"`
 	Role           string `json:"role"`
 	FollowerCount  int64  `json:"follower_count"`
 	FollowingCount int64  `json:"following_count"`
 	PostCount      int64  `json:"post_count"`
 	IsFollowing    bool   `json:"is_following"`
+	FollowsYou     bool   `json:"follows_you"`
 	CreatedAt      string `json:"created_at"`
+	MovedFrom      string `json:"moved_from,omitempty"` // set when looked up by a former username
+}
+
+// FollowRequestRes is a pending follow request; User is the other party
+// (the requester for incoming requests, the target for outgoing ones)
+type FollowRequestRes struct {
+	User      UserDetailRes `json:"user"`
+	CreatedAt string        `json:"created_at"`
+}
+
+// SuggestionRes is a who-to-follow recommendation
+type SuggestionRes struct {
+	User        UserDetailRes `json:"user"`
+	MutualCount int           `json:"mutual_count"` // accounts the caller follows that follow this one
+	Reason      string        `json:"reason"`
 }
 
 type LoginRes struct {
-	AccessToken string  `json:"access_token"`
-	User        UserRes `json:"user"`
+	AccessToken string     `json:"access_token"`
+	User        AccountRes `json:"user"`
 }
@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"goServer/internal/dto"
+	"goServer/internal/model"
+	"goServer/internal/service"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+type AccessTokenHandler struct {
+	service *service.AccessTokenService
+}
+
+func NewAccessTokenHandler(s *service.AccessTokenService) *AccessTokenHandler {
+	return &AccessTokenHandler{service: s}
+}
+
+// GetTokens lists the current user's personal access tokens
+func (h *AccessTokenHandler) GetTokens(c fiber.Ctx) error {
+	userID, ok := c.Locals("sub").(string)
+	if !ok || userID == "" {
+		return fiber.ErrUnauthorized
+	}
+
+	tokens, err := h.service.List(c.Context(), userID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to list tokens")
+	}
+
+	res := make([]dto.AccessTokenRes, len(tokens))
+	for i := range tokens {
+		res[i] = accessTokenToRes(&tokens[i])
+	}
+
+	return c.JSON(res)
+}
+
+// CreateToken issues a personal access token. The token is only ever
+// returned in this response.
+func (h *AccessTokenHandler) CreateToken(c fiber.Ctx) error {
+	userID, ok := c.Locals("sub").(string)
+	if !ok || userID == "" {
+		return fiber.ErrUnauthorized
+	}
+
+	var req dto.CreateAccessTokenReq
+	if err := c.Bind().Body(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request")
+	}
+
+	token, plain, err := h.service.Create(c.Context(), userID, req.Name, req.Scopes, req.ExpiresInDays)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	res := accessTokenToRes(token)
+	res.Token = plain
+
+	return c.Status(fiber.StatusCreated).JSON(res)
+}
+
+// RevokeToken revokes one of the current user's tokens
+func (h *AccessTokenHandler) RevokeToken(c fiber.Ctx) error {
+	userID, ok := c.Locals("sub").(string)
+	if !ok || userID == "" {
+		return fiber.ErrUnauthorized
+	}
+
+	if err := h.service.Revoke(c.Context(), userID, c.Params("id")); err != nil {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+
+	return c.JSON(fiber.Map{"message": "token revoked"})
+}
+
+// GetScopes lists the scopes that can be granted to a token
+func (h *AccessTokenHandler) GetScopes(c fiber.Ctx) error {
+	return c.JSON(model.Scopes)
+}
+
+func accessTokenToRes(t *model.PersonalAccessToken) dto.AccessTokenRes {
+	res := dto.AccessTokenRes{
+		ID:         t.ID,
+		Name:       t.Name,
+		Prefix:     t.Prefix,
+		Scopes:     t.ScopeList(),
+		LastUsedIP: t.LastUsedIP,
+		CreatedAt:  t.CreatedAt.String(),
+	}
+	if t.ExpiresAt != nil {
+		s := t.ExpiresAt.String()
+		res.ExpiresAt = &s
+	}
+	if t.LastUsedAt != nil {
+		s := t.LastUsedAt.String()
+		res.LastUsedAt = &s
+	}
+	if t.RevokedAt != nil {
+		s := t.RevokedAt.String()
+		res.RevokedAt = &s
+	}
+	return res
+}
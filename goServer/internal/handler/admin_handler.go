@@ -0,0 +1,231 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"goServer/internal/dto"
+	"goServer/internal/model"
+	"goServer/internal/service"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+type AdminHandler struct {
+	adminService *service.AdminService
+}
+
+func NewAdminHandler(as *service.AdminService) *AdminHandler {
+	return &AdminHandler{adminService: as}
+}
+
+// AdminDeleteUser deletes a user (admin)
+func (h *AdminHandler) AdminDeleteUser(c fiber.Ctx) error {
+	userID := c.Params("id")
+	var req dto.AdminDeleteUserReq
+
+	if err := c.Bind().Body(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+
+	if err := h.adminService.DeleteUser(c.Context(), auditActor(c), userID, req.Reason); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{"message": "user deleted successfully"})
+}
+
+// UpdateUserRole updates a user's role (admin)
+func (h *AdminHandler) UpdateUserRole(c fiber.Ctx) error {
+	userID := c.Params("id")
+	var req dto.UpdateUserRoleReq
+
+	if err := c.Bind().Body(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+
+	user, err := h.adminService.UpdateUserRole(c.Context(), auditActor(c), userID, req.Role, req.Reason)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(accountToRes(user))
+}
+
+// AdminDeletePost deletes a post (admin)
+func (h *AdminHandler) AdminDeletePost(c fiber.Ctx) error {
+	postID := c.Params("id")
+	var req dto.AdminDeletePostReq
+
+	if err := c.Bind().Body(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+
+	if err := h.adminService.DeletePost(c.Context(), auditActor(c), postID, req.Reason); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{"message": "post deleted"})
+}
+
+// RestoreUser restores a soft-deleted user (admin)
+func (h *AdminHandler) RestoreUser(c fiber.Ctx) error {
+	userID := c.Params("id")
+	var req dto.AdminRestoreReq
+
+	if len(c.Body()) > 0 {
+		if err := c.Bind().Body(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+		}
+	}
+
+	user, err := h.adminService.RestoreUser(c.Context(), auditActor(c), userID, req.Reason)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(accountToRes(user))
+}
+
+// RestorePost restores a soft-deleted post (admin)
+func (h *AdminHandler) RestorePost(c fiber.Ctx) error {
+	postID := c.Params("id")
+	var req dto.AdminRestoreReq
+
+	if len(c.Body()) > 0 {
+		if err := c.Bind().Body(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+		}
+	}
+
+	post, err := h.adminService.RestorePost(c.Context(), auditActor(c), postID, req.Reason)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(postToRes(post))
+}
+
+// GetAuditLogs lists audit log entries with optional filters (admin)
+func (h *AdminHandler) GetAuditLogs(c fiber.Ctx) error {
+	var req dto.AuditFilterReq
+
+	if err := c.Bind().Query(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid query"})
+	}
+
+	logs, total, err := h.adminService.ListAuditLogs(c.Context(), req)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if req.Limit <= 0 || req.Limit > 100 {
+		req.Limit = 20
+	}
+
+	items := make([]dto.AuditLogRes, len(logs))
+	for i, a := range logs {
+		items[i] = auditLogToRes(&a)
+	}
+
+	return c.JSON(dto.PaginatedRes{
+		Items:   items,
+		Total:   total,
+		Limit:   req.Limit,
+		Offset:  req.Offset,
+		HasMore: int64(req.Offset+len(items)) < total,
+	})
+}
+
+// ExportAuditLogs exports filtered audit log entries as CSV or JSON (admin)
+func (h *AdminHandler) ExportAuditLogs(c fiber.Ctx) error {
+	var req dto.AuditFilterReq
+
+	if err := c.Bind().Query(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid query"})
+	}
+
+	format := c.Query("format", "csv")
+	filename := "audit-" + time.Now().UTC().Format("20060102T150405Z") + "." + format
+
+	var buf bytes.Buffer
+	var err error
+
+	switch format {
+	case "csv":
+		w := csv.NewWriter(&buf)
+		_ = w.Write([]string{"seq", "id", "created_at", "actor_id", "action", "target_type", "target_id", "reason", "before", "after", "ip", "user_agent", "prev_hash", "hash"})
+		err = h.adminService.ExportAuditLogs(c.Context(), req, func(a *model.AuditLog) error {
+			return w.Write([]string{
+				strconv.FormatInt(a.Seq, 10), a.ID, a.CreatedAt.UTC().Format(time.RFC3339Nano),
+				a.ActorID, a.Action, a.TargetType, a.TargetID, a.Reason,
+				string(a.Before), string(a.After), a.IP, a.UserAgent, a.PrevHash, a.Hash,
+			})
+		})
+		w.Flush()
+		c.Set(fiber.HeaderContentType, "text/csv")
+	case "json":
+		// JSON lines keep the export streamable and easy to diff
+		enc := json.NewEncoder(&buf)
+		err = h.adminService.ExportAuditLogs(c.Context(), req, func(a *model.AuditLog) error {
+			return enc.Encode(auditLogToRes(a))
+		})
+		c.Set(fiber.HeaderContentType, "application/x-ndjson")
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "format must be csv or json"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="`+filename+`"`)
+	return c.Send(buf.Bytes())
+}
+
+// VerifyAuditLogs walks the audit hash chain and reports whether it is intact (admin)
+func (h *AdminHandler) VerifyAuditLogs(c fiber.Ctx) error {
+	res, err := h.adminService.VerifyAuditChain(c.Context())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(res)
+}
+
+// auditActor collects who is performing an admin action from the request
+func auditActor(c fiber.Ctx) service.AuditActor {
+	actorID, _ := c.Locals("sub").(string)
+	return service.AuditActor{
+		ID:        actorID,
+		IP:        c.IP(),
+		UserAgent: c.Get(fiber.HeaderUserAgent),
+	}
+}
+
+// Helper function to convert AuditLog model to AuditLogRes DTO
+func auditLogToRes(a *model.AuditLog) dto.AuditLogRes {
+	res := dto.AuditLogRes{
+		ID:         a.ID,
+		Seq:        a.Seq,
+		ActorID:    a.ActorID,
+		Action:     a.Action,
+		TargetType: a.TargetType,
+		TargetID:   a.TargetID,
+		Reason:     a.Reason,
+		IP:         a.IP,
+		UserAgent:  a.UserAgent,
+		PrevHash:   a.PrevHash,
+		Hash:       a.Hash,
+		CreatedAt:  a.CreatedAt.UTC().Format(time.RFC3339Nano),
+	}
+	if len(a.Before) > 0 {
+		res.Before = a.Before
+	}
+	if len(a.After) > 0 {
+		res.After = a.After
+	}
+	return res
+}
@@ -1,23 +1,33 @@
 package handler
 
 import (
-	"context"
+	"errors"
+	"log"
+	"math"
+	"strconv"
 	"time"
 
 	"github.com/gofiber/fiber/v3"
 	"github.com/golang-jwt/jwt/v5"
 
 	"goServer/internal/config"
+	"goServer/internal/dto"
+	"goServer/internal/model"
 	"goServer/internal/service"
 )
 
 type AuthHandler struct {
-	service *service.UserService
-	cfg     config.Config
+	service             *service.UserService
+	accountService      *service.AccountService
+	mfaService          *service.MFAService
+	loginGuard          *service.LoginGuardService
+	sessionService      *service.SessionService
+	notificationService *service.NotificationService
+	cfg                 config.Config
 }
 
-func NewAuthHandler(s *service.UserService, cfg config.Config) *AuthHandler {
-	return &AuthHandler{service: s, cfg: cfg}
+func NewAuthHandler(s *service.UserService, as *service.AccountService, ms *service.MFAService, lg *service.LoginGuardService, ss *service.SessionService, ns *service.NotificationService, cfg config.Config) *AuthHandler {
+	return &AuthHandler{service: s, accountService: as, mfaService: ms, loginGuard: lg, sessionService: ss, notificationService: ns, cfg: cfg}
 }
 
 type registerReq struct {
@@ -34,48 +44,365 @@ func (h *AuthHandler) Register(c fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
 	}
 
-	print("Reached here")
-
-	user, err := h.service.Register(context.Background(), req.Email, req.Username, req.Password)
+	user, err := h.service.Register(c.Context(), req.Email, req.Username, req.Password)
 	if err != nil {
 		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
 
+	if err := h.accountService.SendVerificationEmail(c.Context(), user.ID); err != nil {
+		log.Printf("[auth] failed to send verification email to user %s: %v", user.ID, err)
+	}
+
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
 		"id":    user.ID,
 		"email": user.Email,
 	})
 }
 
+// Login is a two-step challenge for accounts with 2FA: a valid password
+// returns a short-lived MFA token, which is then exchanged for the access
+// token together with a TOTP or recovery code
 func (h *AuthHandler) Login(c fiber.Ctx) error {
-	type loginReq struct {
-		Username string `json:"username"`
-		Password string `json:"password"`
-	}
-
-	var req loginReq
+	var req dto.LoginReq
 
 	if err := c.Bind().Body(&req); err != nil {
 		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
 	}
 
-	user, err := h.service.Authenticate(context.Background(), req.Username, req.Password)
+	if req.MFAToken != "" {
+		return h.completeMFALogin(c, req)
+	}
+
+	identifier := req.Login
+	if identifier == "" {
+		identifier = req.Username
+	}
+	if identifier == "" {
+		identifier = req.Email
+	}
+
+	if err := h.checkLoginThrottle(c, identifier); err != nil {
+		return err
+	}
+
+	user, err := h.service.Authenticate(c.Context(), identifier, req.Password)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCredentials) {
+			if err := h.loginGuard.RecordFailure(c.Context(), c.IP(), identifier); err != nil {
+				log.Printf("[auth] %v", err)
+			}
+		}
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid credentials")
+	}
+
+	if err := h.loginGuard.RecordSuccess(c.Context(), identifier); err != nil {
+		log.Printf("[auth] %v", err)
+	}
+
+	return h.startSession(c, user)
+}
+
+// startSession finishes the first login step for an authenticated user:
+// accounts with 2FA get an MFA token, everyone else the access token
+func (h *AuthHandler) startSession(c fiber.Ctx, user *model.User) error {
+	if h.cfg.RequireEmailVerification && user.EmailVerifiedAt == nil {
+		return fiber.NewError(fiber.StatusForbidden, "email not verified")
+	}
+
+	if user.TOTPEnabledAt != nil {
+		signed, err := h.sign(jwt.MapClaims{
+			"sub": user.ID,
+			"typ": "mfa",
+			"tv":  user.TokenVersion,
+			"exp": time.Now().Add(h.cfg.MFATokenTTL).Unix(),
+		})
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "failed to sign token")
+		}
+		return c.JSON(fiber.Map{"mfa_required": true, "mfa_token": signed})
+	}
+
+	return h.issueAccessToken(c, user, false)
+}
+
+// completeMFALogin exchanges an MFA token and a second-factor code for an access token
+func (h *AuthHandler) completeMFALogin(c fiber.Ctx, req dto.LoginReq) error {
+	tok, err := jwt.Parse(req.MFAToken, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fiber.ErrUnauthorized
+		}
+		return []byte(h.cfg.JWTSecret), nil
+	})
+	if err != nil || !tok.Valid {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid or expired mfa token")
+	}
+
+	claims := tok.Claims.(jwt.MapClaims)
+	sub, _ := claims["sub"].(string)
+	typ, _ := claims["typ"].(string)
+	tokenVersion, _ := claims["tv"].(float64)
+	if sub == "" || typ != "mfa" {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid or expired mfa token")
+	}
+	if err := h.service.ValidateToken(c.Context(), sub, int(tokenVersion)); err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid or expired mfa token")
+	}
+
+	user, err := h.service.GetUserByID(c.Context(), sub)
 	if err != nil {
 		return fiber.NewError(fiber.StatusUnauthorized, "invalid credentials")
 	}
 
-	// JWT
-	claims := jwt.MapClaims{
+	// Second-factor guesses count against the same limits as passwords;
+	// the username resolves to the same account counter as the first step
+	if err := h.checkLoginThrottle(c, user.Username); err != nil {
+		return err
+	}
+
+	if err := h.mfaService.Verify(c.Context(), sub, req.Code); err != nil {
+		if errors.Is(err, service.ErrInvalidMFACode) {
+			if err := h.loginGuard.RecordFailure(c.Context(), c.IP(), user.Username); err != nil {
+				log.Printf("[auth] %v", err)
+			}
+			return fiber.NewError(fiber.StatusUnauthorized, err.Error())
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to verify code")
+	}
+
+	if err := h.loginGuard.RecordSuccess(c.Context(), user.Username); err != nil {
+		log.Printf("[auth] %v", err)
+	}
+
+	return h.issueAccessToken(c, user, true)
+}
+
+// checkLoginThrottle rejects the attempt with 429 and a Retry-After header
+// while the client IP or the account behind identifier is locked out
+func (h *AuthHandler) checkLoginThrottle(c fiber.Ctx, identifier string) error {
+	wait, err := h.loginGuard.Check(c.Context(), c.IP(), identifier)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "rate limit check failed")
+	}
+	if wait > 0 {
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		return fiber.NewError(fiber.StatusTooManyRequests, "too many failed login attempts, try again later")
+	}
+	return nil
+}
+
+// issueAccessToken starts a session and signs its access token; mfa records
+// whether the session was established with a second factor
+func (h *AuthHandler) issueAccessToken(c fiber.Ctx, user *model.User, mfa bool) error {
+	session, newDevice, err := h.sessionService.Create(c.Context(), user, c.Get(fiber.HeaderUserAgent), c.Get("X-Device-ID"), c.IP(), mfa)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to start session")
+	}
+
+	if newDevice {
+		if err := h.notificationService.NotifyNewDeviceLogin(c.Context(), user.ID, session.Device, session.IP); err != nil {
+			log.Printf("[auth] %v", err)
+		}
+	}
+
+	signed, err := h.sign(jwt.MapClaims{
 		"sub":  user.ID,
 		"role": user.Role,
-		"exp":  time.Now().Add(24 * time.Hour).Unix(),
+		"typ":  "access",
+		"mfa":  mfa,
+		"tv":   user.TokenVersion,
+		"sid":  session.ID,
+		"exp":  session.ExpiresAt.Unix(),
+	})
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to sign token")
 	}
 
+	res := fiber.Map{"access_token": signed}
+
+	// Logging in during the deletion grace period keeps the account
+	if user.DeletionScheduledAt != nil {
+		cancelled, err := h.accountService.CancelDeletion(c.Context(), user.ID)
+		if err != nil {
+			log.Printf("[auth] %v", err)
+		}
+		res["deletion_cancelled"] = cancelled
+	}
+
+	return c.JSON(res)
+}
+
+func (h *AuthHandler) sign(claims jwt.MapClaims) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signed, err := token.SignedString([]byte(h.cfg.JWTSecret))
+	return token.SignedString([]byte(h.cfg.JWTSecret))
+}
+
+// VerifyEmail confirms an email address using the token from the verification email
+func (h *AuthHandler) VerifyEmail(c fiber.Ctx) error {
+	var req dto.VerifyEmailReq
+
+	if c.Method() == fiber.MethodGet {
+		req.Token = c.Query("token")
+	} else if err := c.Bind().Body(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+
+	if err := h.accountService.VerifyEmail(c.Context(), req.Token); err != nil {
+		if errors.Is(err, service.ErrInvalidToken) {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to verify email")
+	}
+
+	return c.JSON(fiber.Map{"message": "email verified"})
+}
+
+// ResendVerification sends a new verification email to the current user
+func (h *AuthHandler) ResendVerification(c fiber.Ctx) error {
+	userID, ok := c.Locals("sub").(string)
+	if !ok || userID == "" {
+		return fiber.ErrUnauthorized
+	}
+
+	if err := h.accountService.SendVerificationEmail(c.Context(), userID); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(fiber.Map{"message": "verification email sent"})
+}
+
+// ForgotPassword starts the password reset flow
+func (h *AuthHandler) ForgotPassword(c fiber.Ctx) error {
+	var req dto.ForgotPasswordReq
+
+	if err := c.Bind().Body(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+
+	if err := h.accountService.ForgotPassword(c.Context(), req.Email); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(fiber.Map{"message": "if an account exists for that email, a reset link has been sent"})
+}
+
+// ResetPassword sets a new password using the token from the reset email
+func (h *AuthHandler) ResetPassword(c fiber.Ctx) error {
+	var req dto.ResetPasswordReq
+
+	if err := c.Bind().Body(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+
+	if err := h.accountService.ResetPassword(c.Context(), req.Token, req.Password); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(fiber.Map{"message": "password has been reset"})
+}
+
+// GetMFAStatus reports whether the current user has 2FA enabled
+func (h *AuthHandler) GetMFAStatus(c fiber.Ctx) error {
+	userID, ok := c.Locals("sub").(string)
+	if !ok || userID == "" {
+		return fiber.ErrUnauthorized
+	}
+
+	status, err := h.mfaService.Status(c.Context(), userID)
 	if err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, "failed to sign token")
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(status)
+}
+
+// EnrollMFA starts TOTP enrollment and returns the secret and provisioning URI
+func (h *AuthHandler) EnrollMFA(c fiber.Ctx) error {
+	userID, ok := c.Locals("sub").(string)
+	if !ok || userID == "" {
+		return fiber.ErrUnauthorized
 	}
 
-	return c.JSON(fiber.Map{"access_token": signed})
+	res, err := h.mfaService.Enroll(c.Context(), userID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(res)
+}
+
+// ConfirmMFA enables 2FA and returns the recovery codes
+func (h *AuthHandler) ConfirmMFA(c fiber.Ctx) error {
+	userID, ok := c.Locals("sub").(string)
+	if !ok || userID == "" {
+		return fiber.ErrUnauthorized
+	}
+
+	var req dto.MFACodeReq
+	if err := c.Bind().Body(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+
+	codes, err := h.mfaService.Confirm(c.Context(), userID, req.Code)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(dto.RecoveryCodesRes{RecoveryCodes: codes})
+}
+
+// DisableMFA turns 2FA off
+func (h *AuthHandler) DisableMFA(c fiber.Ctx) error {
+	userID, ok := c.Locals("sub").(string)
+	if !ok || userID == "" {
+		return fiber.ErrUnauthorized
+	}
+
+	var req dto.MFADisableReq
+	if err := c.Bind().Body(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+
+	if err := h.mfaService.Disable(c.Context(), userID, req.Password, req.Code); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(fiber.Map{"message": "two-factor authentication disabled; log in again"})
+}
+
+// RegenerateRecoveryCodes replaces the current user's recovery codes
+func (h *AuthHandler) RegenerateRecoveryCodes(c fiber.Ctx) error {
+	userID, ok := c.Locals("sub").(string)
+	if !ok || userID == "" {
+		return fiber.ErrUnauthorized
+	}
+
+	var req dto.MFACodeReq
+	if err := c.Bind().Body(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+
+	codes, err := h.mfaService.RegenerateRecoveryCodes(c.Context(), userID, req.Code)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(dto.RecoveryCodesRes{RecoveryCodes: codes})
+}
+
+// DeleteAccount schedules the current user's account for deletion
+func (h *AuthHandler) DeleteAccount(c fiber.Ctx) error {
+	userID, ok := c.Locals("sub").(string)
+	if !ok || userID == "" {
+		return fiber.ErrUnauthorized
+	}
+
+	at, err := h.accountService.ScheduleDeletion(c.Context(), userID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"message":               "account scheduled for deletion; log in again before then to cancel",
+		"deletion_scheduled_at": at,
+	})
 }
@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"strconv"
+
+	"goServer/internal/service"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+type BlockHandler struct {
+	service     *service.BlockService
+	userService *service.UserService
+}
+
+func NewBlockHandler(s *service.BlockService, us *service.UserService) *BlockHandler {
+	return &BlockHandler{
+		service:     s,
+		userService: us,
+	}
+}
+
+// GetBlocked lists the users the current user blocked
+func (h *BlockHandler) GetBlocked(c fiber.Ctx) error {
+	userID, ok := c.Locals("sub").(string)
+	if !ok || userID == "" {
+		return fiber.ErrUnauthorized
+	}
+
+	limit, _ := strconv.Atoi(c.Query("limit", "20"))
+	offset, _ := strconv.Atoi(c.Query("offset", "0"))
+
+	users, err := h.service.GetBlocked(c.Context(), userID, limit, offset)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to list blocked users")
+	}
+
+	res, err := h.userService.DescribeUsers(c.Context(), userID, users)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to list blocked users")
+	}
+
+	return c.JSON(res)
+}
+
+// BlockUser blocks a user
+func (h *BlockHandler) BlockUser(c fiber.Ctx) error {
+	userID, ok := c.Locals("sub").(string)
+	if !ok || userID == "" {
+		return fiber.ErrUnauthorized
+	}
+
+	if err := h.service.Block(c.Context(), userID, c.Params("id")); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(fiber.Map{"message": "user blocked"})
+}
+
+// UnblockUser removes a block
+func (h *BlockHandler) UnblockUser(c fiber.Ctx) error {
+	userID, ok := c.Locals("sub").(string)
+	if !ok || userID == "" {
+		return fiber.ErrUnauthorized
+	}
+
+	if err := h.service.Unblock(c.Context(), userID, c.Params("id")); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(fiber.Map{"message": "user unblocked"})
+}
+
+// GetMuted lists the users the current user muted
+func (h *BlockHandler) GetMuted(c fiber.Ctx) error {
+	userID, ok := c.Locals("sub").(string)
+	if !ok || userID == "" {
+		return fiber.ErrUnauthorized
+	}
+
+	limit, _ := strconv.Atoi(c.Query("limit", "20"))
+	offset, _ := strconv.Atoi(c.Query("offset", "0"))
+
+	users, err := h.service.GetMuted(c.Context(), userID, limit, offset)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to list muted users")
+	}
+
+	res, err := h.userService.DescribeUsers(c.Context(), userID, users)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to list muted users")
+	}
+
+	return c.JSON(res)
+}
+
+// MuteUser mutes a user
+func (h *BlockHandler) MuteUser(c fiber.Ctx) error {
+	userID, ok := c.Locals("sub").(string)
+	if !ok || userID == "" {
+		return fiber.ErrUnauthorized
+	}
+
+	if err := h.service.Mute(c.Context(), userID, c.Params("id")); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(fiber.Map{"message": "user muted"})
+}
+
+// UnmuteUser removes a mute
+func (h *BlockHandler) UnmuteUser(c fiber.Ctx) error {
+	userID, ok := c.Locals("sub").(string)
+	if !ok || userID == "" {
+		return fiber.ErrUnauthorized
+	}
+
+	if err := h.service.Unmute(c.Context(), userID, c.Params("id")); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(fiber.Map{"message": "user unmuted"})
+}
@@ -0,0 +1,206 @@
+package handler
+
+import (
+	"errors"
+	"strconv"
+
+	"goServer/internal/dto"
+	"goServer/internal/model"
+	"goServer/internal/repository"
+	"goServer/internal/service"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+type BookmarkHandler struct {
+	service     *service.BookmarkService
+	postService *service.PostService
+	pollService *service.PollService
+}
+
+func NewBookmarkHandler(s *service.BookmarkService, ps *service.PostService, pls *service.PollService) *BookmarkHandler {
+	return &BookmarkHandler{
+		service:     s,
+		postService: ps,
+		pollService: pls,
+	}
+}
+
+// AddBookmark bookmarks a post, optionally into a collection
+func (h *BookmarkHandler) AddBookmark(c fiber.Ctx) error {
+	userID, ok := c.Locals("sub").(string)
+	if !ok || userID == "" {
+		return fiber.ErrUnauthorized
+	}
+
+	var req dto.BookmarkReq
+	if len(c.Body()) > 0 {
+		if err := c.Bind().Body(&req); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid request")
+		}
+	}
+
+	if err := h.service.Add(c.Context(), userID, c.Params("id"), req.CollectionID); err != nil {
+		return bookmarkError(err)
+	}
+
+	return c.JSON(fiber.Map{"message": "post bookmarked"})
+}
+
+// RemoveBookmark removes a bookmark
+func (h *BookmarkHandler) RemoveBookmark(c fiber.Ctx) error {
+	userID, ok := c.Locals("sub").(string)
+	if !ok || userID == "" {
+		return fiber.ErrUnauthorized
+	}
+
+	if err := h.service.Remove(c.Context(), userID, c.Params("id")); err != nil {
+		return bookmarkError(err)
+	}
+
+	return c.JSON(fiber.Map{"message": "bookmark removed"})
+}
+
+// GetBookmarks lists the current user's bookmarks, newest first.
+// ?collection=<id> limits it to one collection, ?collection=unsorted to
+// bookmarks outside any collection.
+func (h *BookmarkHandler) GetBookmarks(c fiber.Ctx) error {
+	userID, ok := c.Locals("sub").(string)
+	if !ok || userID == "" {
+		return fiber.ErrUnauthorized
+	}
+
+	limit, _ := strconv.Atoi(c.Query("limit", "20"))
+
+	var filter repository.BookmarkFilter
+	switch collection := c.Query("collection"); collection {
+	case "":
+	case "unsorted":
+		filter.Unsorted = true
+	default:
+		filter.CollectionID = collection
+	}
+
+	bookmarks, next, err := h.service.List(c.Context(), userID, filter, c.Query("cursor"), limit)
+	if err != nil {
+		return bookmarkError(err)
+	}
+
+	posts := make([]dto.PostRes, len(bookmarks))
+	for i := range bookmarks {
+		posts[i] = postToRes(&bookmarks[i].Post)
+		posts[i].IsLiked, _ = h.postService.IsPostLiked(c.Context(), userID, bookmarks[i].PostID)
+		posts[i].IsReposted, _ = h.postService.IsPostReposted(c.Context(), userID, bookmarks[i].PostID)
+		posts[i].IsBookmarked = true
+	}
+	attachPolls(c, h.pollService, userID, posts)
+
+	items := make([]dto.BookmarkRes, len(bookmarks))
+	for i, b := range bookmarks {
+		items[i] = dto.BookmarkRes{
+			Post:         posts[i],
+			CollectionID: b.CollectionID,
+			BookmarkedAt: b.CreatedAt.String(),
+		}
+	}
+
+	return c.JSON(dto.CursorRes{
+		Items:      items,
+		NextCursor: next,
+		HasMore:    next != "",
+	})
+}
+
+// GetCollections lists the current user's bookmark collections
+func (h *BookmarkHandler) GetCollections(c fiber.Ctx) error {
+	userID, ok := c.Locals("sub").(string)
+	if !ok || userID == "" {
+		return fiber.ErrUnauthorized
+	}
+
+	collections, counts, err := h.service.ListCollections(c.Context(), userID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to list collections")
+	}
+
+	res := make([]dto.BookmarkCollectionRes, len(collections))
+	for i := range collections {
+		res[i] = collectionToRes(&collections[i], counts[collections[i].ID])
+	}
+
+	return c.JSON(res)
+}
+
+// CreateCollection creates a bookmark collection
+func (h *BookmarkHandler) CreateCollection(c fiber.Ctx) error {
+	userID, ok := c.Locals("sub").(string)
+	if !ok || userID == "" {
+		return fiber.ErrUnauthorized
+	}
+
+	var req dto.BookmarkCollectionReq
+	if err := c.Bind().Body(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request")
+	}
+
+	collection, err := h.service.CreateCollection(c.Context(), userID, req.Name)
+	if err != nil {
+		return bookmarkError(err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(collectionToRes(collection, 0))
+}
+
+// RenameCollection renames a bookmark collection
+func (h *BookmarkHandler) RenameCollection(c fiber.Ctx) error {
+	userID, ok := c.Locals("sub").(string)
+	if !ok || userID == "" {
+		return fiber.ErrUnauthorized
+	}
+
+	var req dto.BookmarkCollectionReq
+	if err := c.Bind().Body(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request")
+	}
+
+	if _, err := h.service.RenameCollection(c.Context(), c.Params("id"), userID, req.Name); err != nil {
+		return bookmarkError(err)
+	}
+
+	return c.JSON(fiber.Map{"message": "collection renamed"})
+}
+
+// DeleteCollection deletes a bookmark collection, keeping its bookmarks
+func (h *BookmarkHandler) DeleteCollection(c fiber.Ctx) error {
+	userID, ok := c.Locals("sub").(string)
+	if !ok || userID == "" {
+		return fiber.ErrUnauthorized
+	}
+
+	if err := h.service.DeleteCollection(c.Context(), c.Params("id"), userID); err != nil {
+		return bookmarkError(err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// bookmarkError maps bookmark service errors to responses
+func bookmarkError(err error) error {
+	switch {
+	case errors.Is(err, service.ErrCollectionNotFound):
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrCollectionExists), errors.Is(err, service.ErrCollectionLimit):
+		return fiber.NewError(fiber.StatusConflict, err.Error())
+	default:
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+}
+
+func collectionToRes(c *model.BookmarkCollection, count int64) dto.BookmarkCollectionRes {
+	return dto.BookmarkCollectionRes{
+		ID:            c.ID,
+		Name:          c.Name,
+		BookmarkCount: count,
+		CreatedAt:     c.CreatedAt.String(),
+	}
+}
@@ -0,0 +1,179 @@
+package handler
+
+import (
+	"errors"
+	"log"
+
+	"goServer/internal/dto"
+	"goServer/internal/model"
+	"goServer/internal/service"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+type DraftHandler struct {
+	service             *service.DraftService
+	notificationService *service.NotificationService
+	pollService         *service.PollService
+}
+
+func NewDraftHandler(s *service.DraftService, ns *service.NotificationService, pls *service.PollService) *DraftHandler {
+	return &DraftHandler{
+		service:             s,
+		notificationService: ns,
+		pollService:         pls,
+	}
+}
+
+// GetDrafts lists the current user's drafts and scheduled posts
+func (h *DraftHandler) GetDrafts(c fiber.Ctx) error {
+	userID, ok := c.Locals("sub").(string)
+	if !ok || userID == "" {
+		return fiber.ErrUnauthorized
+	}
+
+	drafts, err := h.service.List(c.Context(), userID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to list drafts")
+	}
+
+	res := make([]dto.DraftRes, len(drafts))
+	for i := range drafts {
+		res[i] = draftToRes(&drafts[i])
+	}
+
+	return c.JSON(res)
+}
+
+// CreateDraft saves a post without publishing it, or schedules it when
+// publish_at is set
+func (h *DraftHandler) CreateDraft(c fiber.Ctx) error {
+	userID, ok := c.Locals("sub").(string)
+	if !ok || userID == "" {
+		return fiber.ErrUnauthorized
+	}
+
+	var req dto.CreateDraftReq
+	if err := c.Bind().Body(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request")
+	}
+
+	draft, err := h.service.Create(c.Context(), userID, req)
+	if err != nil {
+		return draftError(err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(draftToRes(draft))
+}
+
+// UpdateDraft replaces a draft's content
+func (h *DraftHandler) UpdateDraft(c fiber.Ctx) error {
+	userID, ok := c.Locals("sub").(string)
+	if !ok || userID == "" {
+		return fiber.ErrUnauthorized
+	}
+
+	var req dto.CreatePostReq
+	if err := c.Bind().Body(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request")
+	}
+
+	draft, err := h.service.Update(c.Context(), c.Params("id"), userID, req)
+	if err != nil {
+		return draftError(err)
+	}
+
+	return c.JSON(draftToRes(draft))
+}
+
+// ScheduleDraft sets or clears when a draft is published
+func (h *DraftHandler) ScheduleDraft(c fiber.Ctx) error {
+	userID, ok := c.Locals("sub").(string)
+	if !ok || userID == "" {
+		return fiber.ErrUnauthorized
+	}
+
+	var req dto.ScheduleDraftReq
+	if err := c.Bind().Body(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request")
+	}
+
+	draft, err := h.service.Schedule(c.Context(), c.Params("id"), userID, req.PublishAt)
+	if err != nil {
+		return draftError(err)
+	}
+
+	return c.JSON(draftToRes(draft))
+}
+
+// PublishDraft publishes a draft immediately
+func (h *DraftHandler) PublishDraft(c fiber.Ctx) error {
+	userID, ok := c.Locals("sub").(string)
+	if !ok || userID == "" {
+		return fiber.ErrUnauthorized
+	}
+
+	post, mentioned, err := h.service.Publish(c.Context(), c.Params("id"), userID)
+	if err != nil {
+		return draftError(err)
+	}
+	for _, id := range mentioned {
+		if err := h.notificationService.NotifyMention(c.Context(), id, userID); err != nil {
+			log.Printf("[drafts] failed to notify mention: %v", err)
+		}
+	}
+
+	res := []dto.PostRes{postToRes(post)}
+	attachPolls(c, h.pollService, userID, res)
+
+	return c.Status(fiber.StatusCreated).JSON(res[0])
+}
+
+// DeleteDraft discards a draft or cancels a scheduled post
+func (h *DraftHandler) DeleteDraft(c fiber.Ctx) error {
+	userID, ok := c.Locals("sub").(string)
+	if !ok || userID == "" {
+		return fiber.ErrUnauthorized
+	}
+
+	if err := h.service.Delete(c.Context(), c.Params("id"), userID); err != nil {
+		return draftError(err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// draftError maps draft service errors to responses. Anything unexpected is
+// a validation failure from the post checks, as in PostHandler.CreatePost.
+func draftError(err error) error {
+	switch {
+	case errors.Is(err, service.ErrDraftNotFound):
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrDraftLimit):
+		return fiber.NewError(fiber.StatusConflict, err.Error())
+	case errors.Is(err, service.ErrBlocked):
+		return fiber.NewError(fiber.StatusForbidden, err.Error())
+	default:
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+}
+
+func draftToRes(d *model.PostDraft) dto.DraftRes {
+	res := dto.DraftRes{
+		ID:           d.ID,
+		Text:         d.Text,
+		ReplyTo:      d.ReplyTo,
+		IsQuote:      d.IsQuote,
+		QuotedPostID: d.QuotedPostID,
+		MediaURLs:    d.MediaURLs,
+		Status:       d.Status,
+		Error:        d.Error,
+		CreatedAt:    d.CreatedAt.String(),
+		UpdatedAt:    d.UpdatedAt.String(),
+	}
+	if d.PublishAt != nil {
+		publishAt := d.PublishAt.String()
+		res.PublishAt = &publishAt
+	}
+	return res
+}
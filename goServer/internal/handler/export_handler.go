@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"errors"
+	"strconv"
+
+	"goServer/internal/dto"
+	"goServer/internal/model"
+	"goServer/internal/service"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+type ExportHandler struct {
+	service *service.ExportService
+}
+
+func NewExportHandler(s *service.ExportService) *ExportHandler {
+	return &ExportHandler{service: s}
+}
+
+// RequestExport queues an export of the current user's data
+func (h *ExportHandler) RequestExport(c fiber.Ctx) error {
+	userID, ok := c.Locals("sub").(string)
+	if !ok || userID == "" {
+		return fiber.ErrUnauthorized
+	}
+
+	export, err := h.service.Request(c.Context(), userID)
+	if err != nil {
+		if errors.Is(err, service.ErrExportInProgress) {
+			return fiber.NewError(fiber.StatusConflict, err.Error())
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to request export")
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(h.exportToRes(export))
+}
+
+// GetExports lists the current user's exports, with download links for ready ones
+func (h *ExportHandler) GetExports(c fiber.Ctx) error {
+	userID, ok := c.Locals("sub").(string)
+	if !ok || userID == "" {
+		return fiber.ErrUnauthorized
+	}
+
+	exports, err := h.service.List(c.Context(), userID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to list exports")
+	}
+
+	res := make([]dto.DataExportRes, len(exports))
+	for i := range exports {
+		res[i] = h.exportToRes(&exports[i])
+	}
+
+	return c.JSON(res)
+}
+
+// Download serves an export archive. The signed link is the credential, so
+// it works without a login, e.g. straight from the email.
+func (h *ExportHandler) Download(c fiber.Ctx) error {
+	expires, err := strconv.ParseInt(c.Query("expires"), 10, 64)
+	if err != nil {
+		return fiber.NewError(fiber.StatusNotFound, service.ErrExportNotFound.Error())
+	}
+
+	export, path, err := h.service.Open(c.Context(), c.Params("id"), expires, c.Query("sig"))
+	if err != nil {
+		if errors.Is(err, service.ErrExportNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, err.Error())
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to open export")
+	}
+
+	c.Set(fiber.HeaderCacheControl, "private, no-store")
+	return c.Download(path, "export-"+export.CreatedAt.Format("2006-01-02")+".zip")
+}
+
+func (h *ExportHandler) exportToRes(e *model.DataExport) dto.DataExportRes {
+	res := dto.DataExportRes{
+		ID:          e.ID,
+		Status:      e.Status,
+		Size:        e.Size,
+		Error:       e.Error,
+		CreatedAt:   e.CreatedAt.String(),
+		DownloadURL: h.service.DownloadURL(e),
+	}
+	if e.CompletedAt != nil {
+		s := e.CompletedAt.String()
+		res.CompletedAt = &s
+	}
+	if e.ExpiresAt != nil {
+		s := e.ExpiresAt.String()
+		res.ExpiresAt = &s
+	}
+	return res
+}
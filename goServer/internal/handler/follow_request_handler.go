@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"log"
+	"strconv"
+
+	"goServer/internal/dto"
+	"goServer/internal/model"
+	"goServer/internal/service"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+type FollowRequestHandler struct {
+	service             *service.FollowRequestService
+	userService         *service.UserService
+	notificationService *service.NotificationService
+}
+
+func NewFollowRequestHandler(s *service.FollowRequestService, us *service.UserService, ns *service.NotificationService) *FollowRequestHandler {
+	return &FollowRequestHandler{
+		service:             s,
+		userService:         us,
+		notificationService: ns,
+	}
+}
+
+// GetIncoming lists the follow requests waiting on the current user
+func (h *FollowRequestHandler) GetIncoming(c fiber.Ctx) error {
+	userID, ok := c.Locals("sub").(string)
+	if !ok || userID == "" {
+		return fiber.ErrUnauthorized
+	}
+
+	limit, _ := strconv.Atoi(c.Query("limit", "20"))
+	offset, _ := strconv.Atoi(c.Query("offset", "0"))
+
+	requests, err := h.service.ListIncoming(c.Context(), userID, limit, offset)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to list follow requests")
+	}
+
+	users := make([]model.User, len(requests))
+	for i := range requests {
+		users[i] = requests[i].Requester
+	}
+	return h.followRequestList(c, userID, requests, users)
+}
+
+// GetOutgoing lists the current user's pending follow requests
+func (h *FollowRequestHandler) GetOutgoing(c fiber.Ctx) error {
+	userID, ok := c.Locals("sub").(string)
+	if !ok || userID == "" {
+		return fiber.ErrUnauthorized
+	}
+
+	limit, _ := strconv.Atoi(c.Query("limit", "20"))
+	offset, _ := strconv.Atoi(c.Query("offset", "0"))
+
+	requests, err := h.service.ListOutgoing(c.Context(), userID, limit, offset)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to list follow requests")
+	}
+
+	users := make([]model.User, len(requests))
+	for i := range requests {
+		users[i] = requests[i].Target
+	}
+	return h.followRequestList(c, userID, requests, users)
+}
+
+// Approve accepts a follow request from the user in the path
+func (h *FollowRequestHandler) Approve(c fiber.Ctx) error {
+	userID, ok := c.Locals("sub").(string)
+	if !ok || userID == "" {
+		return fiber.ErrUnauthorized
+	}
+
+	requesterID := c.Params("id")
+	if err := h.service.Approve(c.Context(), userID, requesterID); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	if err := h.notificationService.NotifyFollowRequestApproved(c.Context(), requesterID, userID); err != nil {
+		log.Printf("[follow] failed to notify approved follow request: %v", err)
+	}
+
+	return c.JSON(fiber.Map{"message": "follow request approved"})
+}
+
+// Reject declines a follow request from the user in the path
+func (h *FollowRequestHandler) Reject(c fiber.Ctx) error {
+	userID, ok := c.Locals("sub").(string)
+	if !ok || userID == "" {
+		return fiber.ErrUnauthorized
+	}
+
+	if err := h.service.Reject(c.Context(), userID, c.Params("id")); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(fiber.Map{"message": "follow request rejected"})
+}
+
+// followRequestList answers with follow requests, each paired with the
+// other party in users
+func (h *FollowRequestHandler) followRequestList(c fiber.Ctx, userID string, requests []model.FollowRequest, users []model.User) error {
+	details, err := h.userService.DescribeUsers(c.Context(), userID, users)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to list follow requests")
+	}
+
+	res := make([]dto.FollowRequestRes, len(requests))
+	for i := range requests {
+		res[i] = dto.FollowRequestRes{
+			User:      details[i],
+			CreatedAt: requests[i].CreatedAt.String(),
+		}
+	}
+
+	return c.JSON(res)
+}
@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v3"
+
+	"goServer/internal/dto"
+	"goServer/internal/model"
+	"goServer/internal/oidc"
+	"goServer/internal/service"
+)
+
+type OIDCHandler struct {
+	service *service.FederationService
+	auth    *AuthHandler
+}
+
+func NewOIDCHandler(s *service.FederationService, auth *AuthHandler) *OIDCHandler {
+	return &OIDCHandler{service: s, auth: auth}
+}
+
+// GetProviders lists the identity providers users can sign in with
+func (h *OIDCHandler) GetProviders(c fiber.Ctx) error {
+	return c.JSON(fiber.Map{"providers": h.service.Providers()})
+}
+
+// Login redirects the browser to the provider's authorization page
+func (h *OIDCHandler) Login(c fiber.Ctx) error {
+	url, err := h.service.BeginLogin(c.Context(), c.Params("provider"))
+	if err != nil {
+		return oidcError(err)
+	}
+
+	return c.Redirect().Status(fiber.StatusFound).To(url)
+}
+
+// Callback handles the provider redirect. It answers like Login does for
+// existing accounts, and with a signup token when the user still has to
+// pick a username.
+func (h *OIDCHandler) Callback(c fiber.Ctx) error {
+	if e := c.Query("error"); e != "" {
+		msg := c.Query("error_description")
+		if msg == "" {
+			msg = e
+		}
+		return fiber.NewError(fiber.StatusBadRequest, msg)
+	}
+
+	res, err := h.service.Callback(c.Context(), c.Params("provider"), c.Query("code"), c.Query("state"))
+	if err != nil {
+		return oidcError(err)
+	}
+
+	switch {
+	case res.User != nil:
+		return h.auth.startSession(c, res.User)
+	case res.Linked:
+		return c.JSON(fiber.Map{"message": "identity linked"})
+	default:
+		return c.JSON(fiber.Map{
+			"signup_required":    true,
+			"signup_token":       res.SignupToken,
+			"suggested_username": res.SuggestedUsername,
+			"email":              res.Email,
+		})
+	}
+}
+
+// Signup creates the account for a first-time provider login
+func (h *OIDCHandler) Signup(c fiber.Ctx) error {
+	var req dto.OIDCSignupReq
+
+	if err := c.Bind().Body(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+
+	user, err := h.service.CompleteSignup(c.Context(), req.SignupToken, req.Username)
+	if err != nil {
+		return oidcError(err)
+	}
+
+	return h.auth.startSession(c, user)
+}
+
+// GetIdentities lists the providers linked to the current user
+func (h *OIDCHandler) GetIdentities(c fiber.Ctx) error {
+	userID, ok := c.Locals("sub").(string)
+	if !ok || userID == "" {
+		return fiber.ErrUnauthorized
+	}
+
+	identities, err := h.service.ListIdentities(c.Context(), userID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to list identities")
+	}
+
+	res := make([]dto.FederatedIdentityRes, len(identities))
+	for i := range identities {
+		res[i] = identityToRes(&identities[i])
+	}
+
+	return c.JSON(res)
+}
+
+// LinkIdentity starts linking a provider and returns the URL to open
+func (h *OIDCHandler) LinkIdentity(c fiber.Ctx) error {
+	userID, ok := c.Locals("sub").(string)
+	if !ok || userID == "" {
+		return fiber.ErrUnauthorized
+	}
+
+	url, err := h.service.BeginLink(c.Context(), userID, c.Params("provider"))
+	if err != nil {
+		return oidcError(err)
+	}
+
+	return c.JSON(fiber.Map{"authorization_url": url})
+}
+
+// UnlinkIdentity removes a linked provider from the current user
+func (h *OIDCHandler) UnlinkIdentity(c fiber.Ctx) error {
+	userID, ok := c.Locals("sub").(string)
+	if !ok || userID == "" {
+		return fiber.ErrUnauthorized
+	}
+
+	if err := h.service.Unlink(c.Context(), userID, c.Params("provider")); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(fiber.Map{"message": "identity unlinked"})
+}
+
+func oidcError(err error) error {
+	switch {
+	case errors.Is(err, oidc.ErrUnknownProvider):
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrInvalidOIDCState):
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrOIDCEmailInUse):
+		return fiber.NewError(fiber.StatusConflict, err.Error())
+	default:
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+}
+
+func identityToRes(f *model.FederatedIdentity) dto.FederatedIdentityRes {
+	res := dto.FederatedIdentityRes{
+		Provider:  f.Provider,
+		Email:     f.Email,
+		CreatedAt: f.CreatedAt.String(),
+	}
+	if f.LastLoginAt != nil {
+		s := f.LastLoginAt.String()
+		res.LastLoginAt = &s
+	}
+	return res
+}
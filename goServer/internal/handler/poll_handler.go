@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"errors"
+	"log"
+
+	"goServer/internal/dto"
+	"goServer/internal/service"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+type PollHandler struct {
+	service *service.PollService
+}
+
+func NewPollHandler(s *service.PollService) *PollHandler {
+	return &PollHandler{service: s}
+}
+
+// Vote casts the current user's vote in a post's poll and returns the
+// results
+func (h *PollHandler) Vote(c fiber.Ctx) error {
+	userID, ok := c.Locals("sub").(string)
+	if !ok || userID == "" {
+		return fiber.ErrUnauthorized
+	}
+
+	var req dto.PollVoteReq
+	if err := c.Bind().Body(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request")
+	}
+
+	view, err := h.service.Vote(c.Context(), userID, c.Params("id"), req.OptionID)
+	switch {
+	case errors.Is(err, service.ErrPollNotFound):
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrPollClosed), errors.Is(err, service.ErrAlreadyVoted):
+		return fiber.NewError(fiber.StatusConflict, err.Error())
+	case err != nil:
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(pollToRes(view))
+}
+
+// attachPolls fills in the polls on a page of posts as viewerID sees them,
+// looking them all up at once. Failures are logged and leave Poll empty.
+func attachPolls(c fiber.Ctx, polls *service.PollService, viewerID string, res []dto.PostRes) {
+	if len(res) == 0 {
+		return
+	}
+
+	ids := make([]string, len(res))
+	for i := range res {
+		ids[i] = res[i].ID
+	}
+	views, err := polls.Views(c.Context(), viewerID, ids)
+	if err != nil {
+		log.Printf("[poll] failed to load polls: %v", err)
+		return
+	}
+	for i := range res {
+		if view, ok := views[res[i].ID]; ok {
+			res[i].Poll = pollToRes(view)
+		}
+	}
+}
+
+func pollToRes(v *service.PollView) *dto.PollRes {
+	res := &dto.PollRes{
+		ID:       v.Poll.ID,
+		Options:  make([]dto.PollOptionRes, len(v.Poll.Options)),
+		ClosesAt: v.Poll.ClosesAt.String(),
+		Closed:   v.Closed,
+	}
+	if v.VotedOption != "" {
+		voted := v.VotedOption
+		res.VotedOptionID = &voted
+	}
+	if v.Counts != nil {
+		total := v.Total
+		res.TotalVotes = &total
+	}
+
+	for i, o := range v.Poll.Options {
+		res.Options[i] = dto.PollOptionRes{ID: o.ID, Text: o.Text}
+		if v.Counts != nil {
+			votes := v.Counts[o.ID]
+			res.Options[i].Votes = &votes
+		}
+	}
+	return res
+}
@@ -1,7 +1,8 @@
 package handler
 
 import (
-	"context"
+	"errors"
+	"log"
 	"strconv"
 
 	"goServer/internal/dto"
@@ -12,11 +13,15 @@ import (
 )
 
 type PostHandler struct {
-	postService *service.PostService
+	postService         *service.PostService
+	feedService         *service.FeedService
+	notificationService *service.NotificationService
+	bookmarkService     *service.BookmarkService
+	pollService         *service.PollService
 }
 
-func NewPostHandler(ps *service.PostService) *PostHandler {
-	return &PostHandler{postService: ps}
+func NewPostHandler(ps *service.PostService, fs *service.FeedService, ns *service.NotificationService, bs *service.BookmarkService, pls *service.PollService) *PostHandler {
+	return &PostHandler{postService: ps, feedService: fs, notificationService: ns, bookmarkService: bs, pollService: pls}
 }
 
 // CreatePost creates a new post
@@ -28,31 +33,38 @@ func (h *PostHandler) CreatePost(c fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
 	}
 
-	post, err := h.postService.CreatePost(context.Background(), userID, req)
+	post, mentioned, err := h.postService.CreatePost(c.Context(), userID, req)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
+	h.notifyMentions(c, userID, mentioned)
 
-	return c.Status(fiber.StatusCreated).JSON(postToRes(post))
+	res := []dto.PostRes{postToRes(post)}
+	h.personalize(c, userID, res)
+
+	return c.Status(fiber.StatusCreated).JSON(res[0])
 }
 
 // GetPost retrieves a post by ID
 func (h *PostHandler) GetPost(c fiber.Ctx) error {
 	postID := c.Params("id")
 	currentUserID := c.Locals("sub")
+	viewerID, _ := currentUserID.(string)
 
-	post, err := h.postService.GetPostByID(context.Background(), postID)
+	post, err := h.postService.GetPostByID(c.Context(), postID, viewerID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "post not found"})
 	}
 
 	res := postToRes(post)
 	if currentUserID != nil {
-		res.IsLiked, _ = h.postService.IsPostLiked(context.Background(), currentUserID.(string), postID)
-		res.IsReposted, _ = h.postService.IsPostReposted(context.Background(), currentUserID.(string), postID)
+		res.IsLiked, _ = h.postService.IsPostLiked(c.Context(), currentUserID.(string), postID)
+		res.IsReposted, _ = h.postService.IsPostReposted(c.Context(), currentUserID.(string), postID)
 	}
+	page := []dto.PostRes{res}
+	h.personalize(c, viewerID, page)
 
-	return c.JSON(res)
+	return c.JSON(page[0])
 }
 
 // UpdatePost updates a post
@@ -65,12 +77,86 @@ func (h *PostHandler) UpdatePost(c fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
 	}
 
-	post, err := h.postService.UpdatePost(context.Background(), postID, userID, req.Text)
+	post, mentioned, err := h.postService.UpdatePost(c.Context(), postID, userID, req.Text)
+	if errors.Is(err, service.ErrEditNotAllowed) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	}
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
+	h.notifyMentions(c, userID, mentioned)
+
+	res := []dto.PostRes{postToRes(post)}
+	h.personalize(c, userID, res)
+
+	return c.JSON(res[0])
+}
+
+// GetPostHistory lists the earlier versions of an edited post, oldest first,
+// followed by the current one
+func (h *PostHandler) GetPostHistory(c fiber.Ctx) error {
+	viewerID, _ := c.Locals("sub").(string)
+
+	post, revisions, err := h.postService.GetPostHistory(c.Context(), c.Params("id"), viewerID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "post not found"})
+	}
+
+	res := make([]dto.PostRevisionRes, 0, len(revisions)+1)
+	for _, r := range revisions {
+		res = append(res, dto.PostRevisionRes{
+			Version:    r.Version,
+			Text:       r.Text,
+			WrittenAt:  r.WrittenAt.String(),
+			ReplacedAt: r.ReplacedAt.String(),
+		})
+	}
+
+	current := dto.PostRevisionRes{
+		Version:   post.EditCount,
+		Text:      post.Text,
+		WrittenAt: post.CreatedAt.String(),
+		Current:   true,
+	}
+	if post.EditedAt != nil {
+		current.WrittenAt = post.EditedAt.String()
+	}
+	res = append(res, current)
+
+	return c.JSON(res)
+}
+
+// notifyMentions tells newly mentioned users about a post. Failures are
+// logged; the post itself was saved.
+func (h *PostHandler) notifyMentions(c fiber.Ctx, authorID string, mentioned []string) {
+	for _, id := range mentioned {
+		if err := h.notificationService.NotifyMention(c.Context(), id, authorID); err != nil {
+			log.Printf("[post] failed to notify mention: %v", err)
+		}
+	}
+}
+
+// personalize fills in the parts of a page of posts that depend on the
+// viewer: polls, and which posts they bookmarked. Bookmarks are private, so
+// nothing is flagged for anyone else.
+func (h *PostHandler) personalize(c fiber.Ctx, viewerID string, res []dto.PostRes) {
+	attachPolls(c, h.pollService, viewerID, res)
+	if viewerID == "" || len(res) == 0 {
+		return
+	}
 
-	return c.JSON(postToRes(post))
+	ids := make([]string, len(res))
+	for i := range res {
+		ids[i] = res[i].ID
+	}
+	bookmarked, err := h.bookmarkService.BookmarkedIDs(c.Context(), viewerID, ids)
+	if err != nil {
+		log.Printf("[post] failed to load bookmarks: %v", err)
+		return
+	}
+	for i := range res {
+		res[i].IsBookmarked = bookmarked[res[i].ID]
+	}
 }
 
 // DeletePost deletes a post
@@ -78,20 +164,30 @@ func (h *PostHandler) DeletePost(c fiber.Ctx) error {
 	userID := c.Locals("sub").(string)
 	postID := c.Params("id")
 
-	if err := h.postService.DeletePost(context.Background(), postID, userID); err != nil {
+	if err := h.postService.DeletePost(c.Context(), postID, userID); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
 
 	return c.JSON(fiber.Map{"message": "post deleted"})
 }
 
-// GetFeed retrieves user's feed
+// GetFeed retrieves user's feed: newest first by default, or scored with
+// ?mode=ranked (add &explain=true to see how each post was scored). The
+// ranked feed is paged with ?cursor= rather than ?offset=.
 func (h *PostHandler) GetFeed(c fiber.Ctx) error {
 	userID := c.Locals("sub").(string)
 	limit, _ := strconv.Atoi(c.Query("limit", "20"))
 	offset, _ := strconv.Atoi(c.Query("offset", "0"))
 
-	posts, err := h.postService.GetFeed(context.Background(), userID, limit, offset)
+	switch c.Query("mode", "chronological") {
+	case "chronological":
+	case "ranked":
+		return h.getRankedFeed(c, userID, limit)
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "mode must be chronological or ranked"})
+	}
+
+	posts, err := h.postService.GetFeed(c.Context(), userID, limit, offset)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
@@ -99,45 +195,118 @@ func (h *PostHandler) GetFeed(c fiber.Ctx) error {
 	res := make([]dto.PostRes, len(posts))
 	for i, p := range posts {
 		r := postToRes(&p)
-		r.IsLiked, _ = h.postService.IsPostLiked(context.Background(), userID, p.ID)
-		r.IsReposted, _ = h.postService.IsPostReposted(context.Background(), userID, p.ID)
+		r.IsLiked, _ = h.postService.IsPostLiked(c.Context(), userID, p.ID)
+		r.IsReposted, _ = h.postService.IsPostReposted(c.Context(), userID, p.ID)
 		res[i] = r
 	}
+	h.personalize(c, userID, res)
 
 	return c.JSON(res)
 }
 
+func (h *PostHandler) getRankedFeed(c fiber.Ctx, userID string, limit int) error {
+	explain := c.Query("explain") == "true"
+
+	ranked, next, err := h.feedService.GetRankedFeed(c.Context(), userID, c.Query("cursor"), limit)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	res := make([]dto.PostRes, len(ranked))
+	for i, rp := range ranked {
+		r := postToRes(&rp.Post)
+		r.LikeCount = rp.Likes
+		r.RepostCount = rp.Reposts
+		r.ReplyCount = rp.Replies
+		r.IsLiked, _ = h.postService.IsPostLiked(c.Context(), userID, rp.Post.ID)
+		r.IsReposted, _ = h.postService.IsPostReposted(c.Context(), userID, rp.Post.ID)
+		if explain {
+			r.Ranking = &dto.FeedRankingRes{
+				Source:          rp.Score.Source,
+				Score:           rp.Score.Total,
+				Recency:         rp.Score.Recency,
+				Velocity:        rp.Score.Velocity,
+				Affinity:        rp.Score.Affinity,
+				SourceFactor:    rp.Score.SourceFactor,
+				DiversityFactor: rp.Score.DiversityFactor,
+			}
+		}
+		res[i] = r
+	}
+	h.personalize(c, userID, res)
+
+	return c.JSON(dto.CursorRes{
+		Items:      res,
+		NextCursor: next,
+		HasMore:    next != "",
+	})
+}
+
 // GetUserTimeline retrieves user's timeline
 func (h *PostHandler) GetUserTimeline(c fiber.Ctx) error {
 	username := c.Params("username")
 	limit, _ := strconv.Atoi(c.Query("limit", "20"))
 	offset, _ := strconv.Atoi(c.Query("offset", "0"))
 	currentUserID := c.Locals("sub")
+	viewerID, _ := currentUserID.(string)
 
-	posts, err := h.postService.GetUserTimeline(context.Background(), username, limit, offset)
+	pinned, posts, err := h.postService.GetUserTimeline(c.Context(), username, viewerID, limit, offset)
+	if errors.Is(err, service.ErrPrivateAccount) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	}
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	res := make([]dto.PostRes, len(posts))
-	for i, p := range posts {
+	res := make([]dto.PostRes, len(pinned)+len(posts))
+	for i, p := range append(pinned, posts...) {
 		r := postToRes(&p)
+		r.IsPinned = i < len(pinned)
 		if currentUserID != nil {
-			r.IsLiked, _ = h.postService.IsPostLiked(context.Background(), currentUserID.(string), p.ID)
-			r.IsReposted, _ = h.postService.IsPostReposted(context.Background(), currentUserID.(string), p.ID)
+			r.IsLiked, _ = h.postService.IsPostLiked(c.Context(), currentUserID.(string), p.ID)
+			r.IsReposted, _ = h.postService.IsPostReposted(c.Context(), currentUserID.(string), p.ID)
 		}
 		res[i] = r
 	}
+	h.personalize(c, viewerID, res)
 
 	return c.JSON(res)
 }
 
+// PinPost pins one of the current user's posts to their profile
+func (h *PostHandler) PinPost(c fiber.Ctx) error {
+	userID := c.Locals("sub").(string)
+	postID := c.Params("id")
+
+	err := h.postService.PinPost(c.Context(), userID, postID)
+	if errors.Is(err, service.ErrPinLimit) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{"message": "post pinned"})
+}
+
+// UnpinPost unpins a post from the current user's profile
+func (h *PostHandler) UnpinPost(c fiber.Ctx) error {
+	userID := c.Locals("sub").(string)
+	postID := c.Params("id")
+
+	if err := h.postService.UnpinPost(c.Context(), userID, postID); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{"message": "post unpinned"})
+}
+
 // LikePost likes a post
 func (h *PostHandler) LikePost(c fiber.Ctx) error {
 	userID := c.Locals("sub").(string)
 	postID := c.Params("id")
 
-	if err := h.postService.LikePost(context.Background(), userID, postID); err != nil {
+	if err := h.postService.LikePost(c.Context(), userID, postID); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
 
@@ -149,7 +318,7 @@ func (h *PostHandler) UnlikePost(c fiber.Ctx) error {
 	userID := c.Locals("sub").(string)
 	postID := c.Params("id")
 
-	if err := h.postService.UnlikePost(context.Background(), userID, postID); err != nil {
+	if err := h.postService.UnlikePost(c.Context(), userID, postID); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
 
@@ -161,7 +330,7 @@ func (h *PostHandler) RepostPost(c fiber.Ctx) error {
 	userID := c.Locals("sub").(string)
 	postID := c.Params("id")
 
-	if err := h.postService.RepostPost(context.Background(), userID, postID); err != nil {
+	if err := h.postService.RepostPost(c.Context(), userID, postID); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
 
@@ -173,7 +342,7 @@ func (h *PostHandler) UndoRepost(c fiber.Ctx) error {
 	userID := c.Locals("sub").(string)
 	postID := c.Params("id")
 
-	if err := h.postService.UndoRepost(context.Background(), userID, postID); err != nil {
+	if err := h.postService.UndoRepost(c.Context(), userID, postID); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
 
@@ -185,8 +354,15 @@ func (h *PostHandler) GetPostLikes(c fiber.Ctx) error {
 	postID := c.Params("id")
 	limit, _ := strconv.Atoi(c.Query("limit", "20"))
 	offset, _ := strconv.Atoi(c.Query("offset", "0"))
+	viewerID, _ := c.Locals("sub").(string)
 
-	users, err := h.postService.GetPostLikes(context.Background(), postID, limit, offset)
+	users, err := h.postService.GetPostLikes(c.Context(), postID, viewerID, limit, offset)
+	if errors.Is(err, service.ErrPostNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	if errors.Is(err, service.ErrPrivateAccount) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	}
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
@@ -204,8 +380,15 @@ func (h *PostHandler) GetPostReposts(c fiber.Ctx) error {
 	postID := c.Params("id")
 	limit, _ := strconv.Atoi(c.Query("limit", "20"))
 	offset, _ := strconv.Atoi(c.Query("offset", "0"))
+	viewerID, _ := c.Locals("sub").(string)
 
-	users, err := h.postService.GetPostReposts(context.Background(), postID, limit, offset)
+	users, err := h.postService.GetPostReposts(c.Context(), postID, viewerID, limit, offset)
+	if errors.Is(err, service.ErrPostNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	if errors.Is(err, service.ErrPrivateAccount) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	}
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
@@ -224,8 +407,9 @@ func (h *PostHandler) GetReplies(c fiber.Ctx) error {
 	limit, _ := strconv.Atoi(c.Query("limit", "20"))
 	offset, _ := strconv.Atoi(c.Query("offset", "0"))
 	currentUserID := c.Locals("sub")
+	viewerID, _ := currentUserID.(string)
 
-	posts, err := h.postService.GetReplies(context.Background(), postID, limit, offset)
+	posts, err := h.postService.GetReplies(c.Context(), postID, viewerID, limit, offset)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
@@ -234,11 +418,12 @@ func (h *PostHandler) GetReplies(c fiber.Ctx) error {
 	for i, p := range posts {
 		r := postToRes(&p)
 		if currentUserID != nil {
-			r.IsLiked, _ = h.postService.IsPostLiked(context.Background(), currentUserID.(string), p.ID)
-			r.IsReposted, _ = h.postService.IsPostReposted(context.Background(), currentUserID.(string), p.ID)
+			r.IsLiked, _ = h.postService.IsPostLiked(c.Context(), currentUserID.(string), p.ID)
+			r.IsReposted, _ = h.postService.IsPostReposted(c.Context(), currentUserID.(string), p.ID)
 		}
 		res[i] = r
 	}
+	h.personalize(c, viewerID, res)
 
 	return c.JSON(res)
 }
@@ -249,12 +434,13 @@ func (h *PostHandler) SearchPosts(c fiber.Ctx) error {
 	limit, _ := strconv.Atoi(c.Query("limit", "20"))
 	offset, _ := strconv.Atoi(c.Query("offset", "0"))
 	currentUserID := c.Locals("sub")
+	viewerID, _ := currentUserID.(string)
 
 	if query == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "query is required"})
 	}
 
-	posts, err := h.postService.SearchPosts(context.Background(), query, limit, offset)
+	posts, err := h.postService.SearchPosts(c.Context(), query, viewerID, limit, offset)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
@@ -263,11 +449,12 @@ func (h *PostHandler) SearchPosts(c fiber.Ctx) error {
 	for i, p := range posts {
 		r := postToRes(&p)
 		if currentUserID != nil {
-			r.IsLiked, _ = h.postService.IsPostLiked(context.Background(), currentUserID.(string), p.ID)
-			r.IsReposted, _ = h.postService.IsPostReposted(context.Background(), currentUserID.(string), p.ID)
+			r.IsLiked, _ = h.postService.IsPostLiked(c.Context(), currentUserID.(string), p.ID)
+			r.IsReposted, _ = h.postService.IsPostReposted(c.Context(), currentUserID.(string), p.ID)
 		}
 		res[i] = r
 	}
+	h.personalize(c, viewerID, res)
 
 	return c.JSON(res)
 }
@@ -277,7 +464,7 @@ func (h *PostHandler) GetAllPosts(c fiber.Ctx) error {
 	limit, _ := strconv.Atoi(c.Query("limit", "20"))
 	offset, _ := strconv.Atoi(c.Query("offset", "0"))
 
-	posts, err := h.postService.GetFeed(context.Background(), "", limit, offset) // Get all posts
+	posts, err := h.postService.GetFeed(c.Context(), "", limit, offset) // Get all posts
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
@@ -290,19 +477,12 @@ func (h *PostHandler) GetAllPosts(c fiber.Ctx) error {
 	return c.JSON(res)
 }
 
-// AdminDeletePost deletes a post (admin)
-func (h *PostHandler) AdminDeletePost(c fiber.Ctx) error {
-	postID := c.Params("id")
-
-	if err := h.postService.DeletePost(context.Background(), postID, ""); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
-	}
-
-	return c.JSON(fiber.Map{"message": "post deleted"})
-}
-
 // Helper function to convert Post model to PostRes DTO
 func postToRes(p *model.Post) dto.PostRes {
+	if p.DeletedAt.Valid {
+		return tombstoneRes(p)
+	}
+
 	media := make([]dto.MediaRes, len(p.Media))
 	for i, m := range p.Media {
 		media[i] = dto.MediaRes{
@@ -313,7 +493,7 @@ func postToRes(p *model.Post) dto.PostRes {
 		}
 	}
 
-	return dto.PostRes{
+	res := dto.PostRes{
 		ID:           p.ID,
 		UserID:       p.UserID,
 		User:         userToRes(&p.User),
@@ -323,7 +503,25 @@ func postToRes(p *model.Post) dto.PostRes {
 		IsQuote:      p.IsQuote,
 		QuotedPostID: p.QuotedTweetID,
 		Media:        media,
+		EditCount:    p.EditCount,
 		CreatedAt:    p.CreatedAt.String(),
 		UpdatedAt:    p.UpdatedAt.String(),
 	}
+	if p.EditedAt != nil {
+		editedAt := p.EditedAt.String()
+		res.EditedAt = &editedAt
+	}
+	return res
+}
+
+// tombstoneRes keeps a deleted post's place in a thread without exposing its content
+func tombstoneRes(p *model.Post) dto.PostRes {
+	return dto.PostRes{
+		ID:        p.ID,
+		ReplyTo:   p.ReplyTo,
+		Media:     []dto.MediaRes{},
+		IsDeleted: true,
+		CreatedAt: p.CreatedAt.String(),
+		UpdatedAt: p.DeletedAt.Time.String(),
+	}
 }
@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"goServer/internal/dto"
+	"goServer/internal/model"
+	"goServer/internal/service"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+type SessionHandler struct {
+	service *service.SessionService
+}
+
+func NewSessionHandler(s *service.SessionService) *SessionHandler {
+	return &SessionHandler{service: s}
+}
+
+// GetSessions lists the devices the current user is logged in on
+func (h *SessionHandler) GetSessions(c fiber.Ctx) error {
+	userID, ok := c.Locals("sub").(string)
+	if !ok || userID == "" {
+		return fiber.ErrUnauthorized
+	}
+	current, _ := c.Locals("sid").(string)
+
+	sessions, err := h.service.List(c.Context(), userID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to list sessions")
+	}
+
+	res := make([]dto.SessionRes, len(sessions))
+	for i := range sessions {
+		res[i] = sessionToRes(&sessions[i], current)
+	}
+
+	return c.JSON(res)
+}
+
+// RevokeSession logs one of the current user's sessions out
+func (h *SessionHandler) RevokeSession(c fiber.Ctx) error {
+	userID, ok := c.Locals("sub").(string)
+	if !ok || userID == "" {
+		return fiber.ErrUnauthorized
+	}
+
+	if err := h.service.Revoke(c.Context(), userID, c.Params("id")); err != nil {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+
+	return c.JSON(fiber.Map{"message": "session revoked"})
+}
+
+// RevokeOtherSessions logs the current user out everywhere else
+func (h *SessionHandler) RevokeOtherSessions(c fiber.Ctx) error {
+	userID, ok := c.Locals("sub").(string)
+	if !ok || userID == "" {
+		return fiber.ErrUnauthorized
+	}
+	current, _ := c.Locals("sid").(string)
+
+	count, err := h.service.RevokeOthers(c.Context(), userID, current)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to revoke sessions")
+	}
+
+	return c.JSON(fiber.Map{"message": "logged out of other sessions", "revoked": count})
+}
+
+// Logout ends the current session
+func (h *SessionHandler) Logout(c fiber.Ctx) error {
+	userID, ok := c.Locals("sub").(string)
+	if !ok || userID == "" {
+		return fiber.ErrUnauthorized
+	}
+	current, _ := c.Locals("sid").(string)
+
+	if err := h.service.Revoke(c.Context(), userID, current); err != nil {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+
+	return c.JSON(fiber.Map{"message": "logged out"})
+}
+
+func sessionToRes(s *model.Session, currentID string) dto.SessionRes {
+	return dto.SessionRes{
+		ID:         s.ID,
+		Device:     s.Device,
+		UserAgent:  s.UserAgent,
+		IP:         s.IP,
+		MFA:        s.MFA,
+		Current:    s.ID == currentID,
+		CreatedAt:  s.CreatedAt.String(),
+		LastSeenAt: s.LastSeenAt.String(),
+		ExpiresAt:  s.ExpiresAt.String(),
+	}
+}
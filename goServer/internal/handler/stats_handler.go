@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"errors"
+
+	"goServer/internal/service"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+type StatsHandler struct {
+	statsService *service.StatsService
+}
+
+func NewStatsHandler(ss *service.StatsService) *StatsHandler {
+	return &StatsHandler{statsService: ss}
+}
+
+// GetSystemStats retrieves system statistics (admin)
+func (h *StatsHandler) GetSystemStats(c fiber.Ctx) error {
+	interval := c.Query("interval", "day")
+
+	stats, err := h.statsService.GetSystemStats(c.Context(), interval)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(stats)
+}
+
+// GetUserStats retrieves engagement statistics for a user
+func (h *StatsHandler) GetUserStats(c fiber.Ctx) error {
+	username := c.Params("username")
+	interval := c.Query("interval", "day")
+	viewerID, _ := c.Locals("sub").(string)
+
+	stats, err := h.statsService.GetUserStats(c.Context(), username, viewerID, interval)
+	if errors.Is(err, service.ErrPrivateAccount) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(stats)
+}
@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"fmt"
+	"strconv"
+
+	"goServer/internal/dto"
+	"goServer/internal/model"
+	"goServer/internal/service"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+type SuggestionHandler struct {
+	service     *service.SuggestionService
+	userService *service.UserService
+}
+
+func NewSuggestionHandler(s *service.SuggestionService, us *service.UserService) *SuggestionHandler {
+	return &SuggestionHandler{
+		service:     s,
+		userService: us,
+	}
+}
+
+// GetSuggestions lists accounts the current user may want to follow
+func (h *SuggestionHandler) GetSuggestions(c fiber.Ctx) error {
+	userID, ok := c.Locals("sub").(string)
+	if !ok || userID == "" {
+		return fiber.ErrUnauthorized
+	}
+
+	limit, _ := strconv.Atoi(c.Query("limit", "20"))
+
+	suggestions, err := h.service.List(c.Context(), userID, limit)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to load suggestions")
+	}
+
+	users := make([]model.User, len(suggestions))
+	for i := range suggestions {
+		users[i] = suggestions[i].Suggested
+	}
+	details, err := h.userService.DescribeUsers(c.Context(), userID, users)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to load suggestions")
+	}
+
+	res := make([]dto.SuggestionRes, len(suggestions))
+	for i, s := range suggestions {
+		res[i] = dto.SuggestionRes{
+			User:        details[i],
+			MutualCount: s.MutualCount,
+			Reason:      suggestionReason(&s),
+		}
+	}
+
+	return c.JSON(res)
+}
+
+// DismissSuggestion stops an account from being suggested again
+func (h *SuggestionHandler) DismissSuggestion(c fiber.Ctx) error {
+	userID, ok := c.Locals("sub").(string)
+	if !ok || userID == "" {
+		return fiber.ErrUnauthorized
+	}
+
+	if err := h.service.Dismiss(c.Context(), userID, c.Params("id")); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(fiber.Map{"message": "suggestion dismissed"})
+}
+
+// suggestionReason explains a suggestion by its strongest signal
+func suggestionReason(s *model.FollowSuggestion) string {
+	switch {
+	case s.MutualCount == 1:
+		return "Followed by 1 account you follow"
+	case s.MutualCount > 1:
+		return fmt.Sprintf("Followed by %d accounts you follow", s.MutualCount)
+	case s.SharedCount > 0:
+		return "Likes the same posts as you"
+	default:
+		return "Popular account"
+	}
+}
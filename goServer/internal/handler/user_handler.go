@@ -2,11 +2,15 @@ package handler
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log"
 	"strconv"
 
 	"goServer/internal/dto"
 	"goServer/internal/model"
 	"goServer/internal/service"
+	"goServer/pkg/utils"
 
 	"github.com/gofiber/fiber/v3"
 )
@@ -30,12 +34,12 @@ func (h *UserHandler) GetProfile(c fiber.Ctx) error {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
 	}
 
-	user, err := h.userService.GetUserByID(context.Background(), userID)
+	user, err := h.userService.GetUserByID(c.Context(), userID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "user not found"})
 	}
 
-	return c.JSON(userToRes(user))
+	return c.JSON(accountToRes(user))
 }
 
 // UpdateProfile updates current user profile
@@ -51,122 +55,135 @@ func (h *UserHandler) UpdateProfile(c fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
 	}
 
-	user, err := h.userService.UpdateUser(context.Background(), userID, req)
+	user, err := h.userService.UpdateUser(c.Context(), userID, req)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	return c.JSON(userToRes(user))
+	return c.JSON(accountToRes(user))
 }
 
-// DeleteAccount deletes current user account
-func (h *UserHandler) DeleteAccount(c fiber.Ctx) error {
+// ChangeUsername changes the current user's handle
+func (h *UserHandler) ChangeUsername(c fiber.Ctx) error {
 	userID, ok := c.Locals("sub").(string)
 	if !ok || userID == "" {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
 	}
 
-	if err := h.userService.DeleteUser(context.Background(), userID); err != nil {
+	var req dto.ChangeUsernameReq
+
+	if err := c.Bind().Body(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+
+	user, err := h.userService.ChangeUsername(c.Context(), userID, req.Username)
+	switch {
+	case errors.Is(err, utils.ErrUsernameInvalid), errors.Is(err, utils.ErrUsernameReserved), errors.Is(err, service.ErrUsernameUnchanged):
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, service.ErrUsernameTaken):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, service.ErrUsernameCooldown):
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": err.Error()})
+	case err != nil:
+		log.Printf("[user] failed to change username: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to change username"})
 	}
 
-	return c.JSON(fiber.Map{"message": "account deleted successfully"})
+	return c.JSON(accountToRes(user))
 }
 
-// GetUserByUsername retrieves user by username
+// GetUserByUsername retrieves a user's profile with counts and, for a
+// signed-in caller, the follow flags between them
 func (h *UserHandler) GetUserByUsername(c fiber.Ctx) error {
 	username := c.Params("username")
+	viewerID, _ := c.Locals("sub").(string)
 
-	user, err := h.userService.GetUserByUsername(context.Background(), username)
+	user, movedFrom, err := h.userService.ResolveUsername(c.Context(), username)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "user not found"})
 	}
 
-	return c.JSON(userToRes(user))
-}
-
-// GetFollowers retrieves followers of a user
-func (h *UserHandler) GetFollowers(c fiber.Ctx) error {
-	username := c.Params("username")
-
-	followers, err := h.userService.GetFollowers(context.Background(), username)
+	details, err := h.userService.DescribeUsers(c.Context(), viewerID, []model.User{*user})
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to load user"})
 	}
 
-	res := make([]dto.UserRes, len(followers))
-	for i, f := range followers {
-		res[i] = userToRes(&f)
+	res := details[0]
+	if movedFrom != "" {
+		// Old handle: answer with the account but point clients at the current one
+		res.MovedFrom = movedFrom
+		c.Set(fiber.HeaderLink, fmt.Sprintf(`</api/v1/users/%s>; rel="canonical"`, user.Username))
 	}
 
 	return c.JSON(res)
 }
 
-// GetFollowing retrieves users that a user is following
-func (h *UserHandler) GetFollowing(c fiber.Ctx) error {
-	username := c.Params("username")
-
-	following, err := h.userService.GetFollowing(context.Background(), username)
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
-	}
-
-	res := make([]dto.UserRes, len(following))
-	for i, f := range following {
-		res[i] = userToRes(&f)
-	}
+// GetFollowers retrieves a page of a user's followers
+func (h *UserHandler) GetFollowers(c fiber.Ctx) error {
+	viewerID, _ := c.Locals("sub").(string)
+	return h.followList(c, c.Params("username"), viewerID, h.userService.GetFollowers)
+}
 
-	return c.JSON(res)
+// GetFollowing retrieves a page of the users a user is following
+func (h *UserHandler) GetFollowing(c fiber.Ctx) error {
+	viewerID, _ := c.Locals("sub").(string)
+	return h.followList(c, c.Params("username"), viewerID, h.userService.GetFollowing)
 }
 
-// GetMyFollowers retrieves current user's followers
+// GetMyFollowers retrieves a page of the current user's followers
 func (h *UserHandler) GetMyFollowers(c fiber.Ctx) error {
 	userID, ok := c.Locals("sub").(string)
 	if !ok || userID == "" {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
 	}
 
-	user, err := h.userService.GetUserByID(context.Background(), userID)
+	user, err := h.userService.GetUserByID(c.Context(), userID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "user not found"})
 	}
 
-	followers, err := h.userService.GetFollowers(context.Background(), user.Username)
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
-	}
-
-	res := make([]dto.UserRes, len(followers))
-	for i, f := range followers {
-		res[i] = userToRes(&f)
-	}
-
-	return c.JSON(res)
+	return h.followList(c, user.Username, userID, h.userService.GetFollowers)
 }
 
-// GetMyFollowing retrieves users that current user is following
+// GetMyFollowing retrieves a page of the users the current user is following
 func (h *UserHandler) GetMyFollowing(c fiber.Ctx) error {
 	userID, ok := c.Locals("sub").(string)
 	if !ok || userID == "" {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
 	}
 
-	user, err := h.userService.GetUserByID(context.Background(), userID)
+	user, err := h.userService.GetUserByID(c.Context(), userID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "user not found"})
 	}
 
-	following, err := h.userService.GetFollowing(context.Background(), user.Username)
+	return h.followList(c, user.Username, userID, h.userService.GetFollowing)
+}
+
+// followList answers with one page of a follower or following list, taking
+// ?cursor= and ?limit= from the query string
+func (h *UserHandler) followList(c fiber.Ctx, username, viewerID string,
+	list func(context.Context, string, string, string, int) ([]model.User, string, error)) error {
+	limit, _ := strconv.Atoi(c.Query("limit", "20"))
+
+	users, next, err := list(c.Context(), username, viewerID, c.Query("cursor"), limit)
+	if errors.Is(err, service.ErrPrivateAccount) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	}
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	res := make([]dto.UserRes, len(following))
-	for i, f := range following {
-		res[i] = userToRes(&f)
+	items, err := h.userService.DescribeUsers(c.Context(), viewerID, users)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to load users"})
 	}
 
-	return c.JSON(res)
+	return c.JSON(dto.CursorRes{
+		Items:      items,
+		NextCursor: next,
+		HasMore:    next != "",
+	})
 }
 
 // FollowUser follows a user
@@ -178,10 +195,22 @@ func (h *UserHandler) FollowUser(c fiber.Ctx) error {
 
 	followeeID := c.Params("id")
 
-	if err := h.userService.FollowUser(context.Background(), followerID, followeeID); err != nil {
+	requested, err := h.userService.FollowUser(c.Context(), followerID, followeeID)
+	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
 
+	if requested {
+		if err := h.notificationService.NotifyFollowRequest(c.Context(), followeeID, followerID); err != nil {
+			log.Printf("[user] failed to notify follow request: %v", err)
+		}
+		return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"message": "follow request sent", "requested": true})
+	}
+
+	if err := h.notificationService.NotifyFollow(c.Context(), followeeID, followerID); err != nil {
+		log.Printf("[user] failed to notify follow: %v", err)
+	}
+
 	return c.JSON(fiber.Map{"message": "followed successfully"})
 }
 
@@ -194,7 +223,7 @@ func (h *UserHandler) UnfollowUser(c fiber.Ctx) error {
 
 	followeeID := c.Params("id")
 
-	if err := h.userService.UnfollowUser(context.Background(), followerID, followeeID); err != nil {
+	if err := h.userService.UnfollowUser(c.Context(), followerID, followeeID); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
 
@@ -206,12 +235,13 @@ func (h *UserHandler) SearchUsers(c fiber.Ctx) error {
 	query := c.Query("query")
 	limit, _ := strconv.Atoi(c.Query("limit", "20"))
 	offset, _ := strconv.Atoi(c.Query("offset", "0"))
+	viewerID, _ := c.Locals("sub").(string)
 
 	if query == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "query is required"})
 	}
 
-	users, err := h.userService.SearchUsers(context.Background(), query, limit, offset)
+	users, err := h.userService.SearchUsers(c.Context(), query, viewerID, limit, offset)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
@@ -229,47 +259,19 @@ func (h *UserHandler) GetAllUsers(c fiber.Ctx) error {
 	limit, _ := strconv.Atoi(c.Query("limit", "20"))
 	offset, _ := strconv.Atoi(c.Query("offset", "0"))
 
-	users, err := h.userService.GetAllUsers(context.Background(), limit, offset)
+	users, err := h.userService.GetAllUsers(c.Context(), limit, offset)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	res := make([]dto.UserRes, len(users))
+	res := make([]dto.AccountRes, len(users))
 	for i, u := range users {
-		res[i] = userToRes(&u)
+		res[i] = accountToRes(&u)
 	}
 
 	return c.JSON(res)
 }
 
-// AdminDeleteUser deletes a user (admin)
-func (h *UserHandler) AdminDeleteUser(c fiber.Ctx) error {
-	userID := c.Params("id")
-
-	if err := h.userService.DeleteUser(context.Background(), userID); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
-	}
-
-	return c.JSON(fiber.Map{"message": "user deleted successfully"})
-}
-
-// UpdateUserRole updates a user's role (admin)
-func (h *UserHandler) UpdateUserRole(c fiber.Ctx) error {
-	userID := c.Params("id")
-	var req dto.UpdateUserRoleReq
-
-	if err := c.Bind().Body(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
-	}
-
-	user, err := h.userService.UpdateUserRole(context.Background(), userID, req.Role)
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
-	}
-
-	return c.JSON(userToRes(user))
-}
-
 // GetNotifications retrieves user's notifications
 func (h *UserHandler) GetNotifications(c fiber.Ctx) error {
 	userID, ok := c.Locals("sub").(string)
@@ -280,7 +282,7 @@ func (h *UserHandler) GetNotifications(c fiber.Ctx) error {
 	limit, _ := strconv.Atoi(c.Query("limit", "20"))
 	offset, _ := strconv.Atoi(c.Query("offset", "0"))
 
-	notifications, err := h.notificationService.GetNotifications(context.Background(), userID, limit, offset)
+	notifications, err := h.notificationService.GetNotifications(c.Context(), userID, limit, offset)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
@@ -302,7 +304,7 @@ func (h *UserHandler) MarkNotificationAsRead(c fiber.Ctx) error {
 
 	notificationID := c.Params("id")
 
-	notification, err := h.notificationService.MarkAsRead(context.Background(), notificationID, userID)
+	notification, err := h.notificationService.MarkAsRead(c.Context(), notificationID, userID)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
@@ -319,28 +321,37 @@ func (h *UserHandler) DeleteNotification(c fiber.Ctx) error {
 
 	notificationID := c.Params("id")
 
-	if err := h.notificationService.DeleteNotification(context.Background(), notificationID, userID); err != nil {
+	if err := h.notificationService.DeleteNotification(c.Context(), notificationID, userID); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
 
 	return c.JSON(fiber.Map{"message": "notification deleted"})
 }
 
-// GetSystemStats retrieves system statistics (admin)
-func (h *UserHandler) GetSystemStats(c fiber.Ctx) error {
-	// This will be implemented when stats service is created
-	return c.JSON(fiber.Map{"message": "system stats"})
-}
-
 // Helper function to convert User model to UserRes DTO
 func userToRes(u *model.User) dto.UserRes {
 	return dto.UserRes{
+		ID:          u.ID,
+		Username:    u.Username,
+		DisplayName: u.DisplayName,
+		Bio:         u.Bio,
+		AvatarURL:   u.AvatarURL,
+		IsPrivate:   u.IsPrivate,
+		Role:        u.Role,
+		CreatedAt:   u.CreatedAt.String(),
+	}
+}
+
+// accountToRes converts a User to the AccountRes only they and admins see
+func accountToRes(u *model.User) dto.AccountRes {
+	return dto.AccountRes{
 		ID:          u.ID,
 		Email:       u.Email,
 		Username:    u.Username,
 		DisplayName: u.DisplayName,
 		Bio:         u.Bio,
 		AvatarURL:   u.AvatarURL,
+		IsPrivate:   u.IsPrivate,
 		Role:        u.Role,
 		CreatedAt:   u.CreatedAt.String(),
 	}
@@ -348,12 +359,20 @@ func userToRes(u *model.User) dto.UserRes {
 
 // Helper function to convert Notification model to NotificationRes DTO
 func notificationToRes(n *model.Notification) dto.NotificationRes {
-	return dto.NotificationRes{
+	message := n.Message
+	if message == "" {
+		message = n.Type
+	}
+	res := dto.NotificationRes{
 		ID:        n.ID,
 		UserID:    n.UserID,
 		Type:      n.Type,
-		Message:   n.Type,
+		Message:   message,
 		Read:      n.Read,
 		CreatedAt: n.CreatedAt.String(),
 	}
+	if n.PostID != nil {
+		res.PostID = *n.PostID
+	}
+	return res
 }
@@ -0,0 +1,98 @@
+package metrics
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry holds every collector exposed on the metrics endpoint
+var Registry = prometheus.NewRegistry()
+
+var (
+	// HTTP (rate, errors, duration)
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Duration of HTTP requests by route template, method and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestsInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "http_requests_in_flight",
+		Help: "Number of HTTP requests currently being served.",
+	})
+
+	// Database
+	DBQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "db_query_duration_seconds",
+		Help:    "Duration of gorm queries by operation and table.",
+		Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation", "table"})
+
+	DBQueryErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "db_query_errors_total",
+		Help: "Number of failed gorm queries by operation and table.",
+	}, []string{"operation", "table"})
+
+	// Rate limiting
+	RateLimitRejections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "rate_limit_rejections_total",
+		Help: "Number of requests rejected by the rate limiter by action.",
+	}, []string{"action"})
+
+	// Domain events
+	PostsCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "posts_created_total",
+		Help: "Number of posts created.",
+	})
+
+	LikesCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "likes_created_total",
+		Help: "Number of likes created.",
+	})
+
+	FollowsCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "follows_created_total",
+		Help: "Number of follow relationships created.",
+	})
+
+	NotificationsDelivered = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "notifications_delivered_total",
+		Help: "Number of notifications delivered by type.",
+	}, []string{"type"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequestDuration,
+		HTTPRequestsInFlight,
+		DBQueryDuration,
+		DBQueryErrors,
+		RateLimitRejections,
+		PostsCreated,
+		LikesCreated,
+		FollowsCreated,
+		NotificationsDelivered,
+	)
+}
+
+// Handler returns the http handler serving the registry in Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// Serve exposes /metrics on its own listener so it never shares the public port
+func Serve(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+
+	log.Printf("Metrics listening on %s", addr)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		log.Printf("[metrics] server stopped: %v", err)
+	}
+}
@@ -1,45 +1,112 @@
 package middleware
 
 import (
+	"context"
+	"strings"
+
+	"goServer/internal/model"
+
 	"github.com/gofiber/fiber/v3"
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func JWT(secret string) fiber.Handler {
+// TokenValidator confirms that a token's subject may still use it, e.g. that
+// the token wasn't revoked by a password reset or by ending its session
+type TokenValidator interface {
+	ValidateToken(ctx context.Context, userID string, tokenVersion int, sessionID, ip string) error
+}
+
+// AccessTokenAuthenticator resolves a personal access token to its owner,
+// the owner's role and the scopes granted to the token
+type AccessTokenAuthenticator interface {
+	AuthenticateAccessToken(ctx context.Context, token, ip string) (userID, role string, scopes []string, err error)
+}
+
+// JWT authenticates the request with either a session JWT or, when tokens is
+// set, a personal access token. Access tokens are limited to their scopes
+// (see RequireScope); sessions have no "scopes" local and may do anything.
+func JWT(secret string, validator TokenValidator, tokens AccessTokenAuthenticator) fiber.Handler {
 	return func(c fiber.Ctx) error {
-		auth := c.Get("Authorization")
-		if len(auth) < 7 || auth[:7] != "Bearer " {
-			return fiber.ErrUnauthorized
-		}
-		tokenStr := auth[7:]
-
-		tok, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
-			if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fiber.ErrUnauthorized
-			}
-			return []byte(secret), nil
-		})
-		if err != nil || !tok.Valid {
-			return fiber.ErrUnauthorized
+		return authenticate(c, secret, validator, tokens)
+	}
+}
+
+// OptionalJWT authenticates the request like JWT when it carries an
+// Authorization header and lets it through anonymously otherwise, for public
+// routes whose response depends on who is asking (e.g. blocks)
+func OptionalJWT(secret string, validator TokenValidator, tokens AccessTokenAuthenticator) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if c.Get("Authorization") == "" {
+			return c.Next()
 		}
+		return authenticate(c, secret, validator, tokens)
+	}
+}
 
-		claims := tok.Claims.(jwt.MapClaims)
+func authenticate(c fiber.Ctx, secret string, validator TokenValidator, tokens AccessTokenAuthenticator) error {
+	auth := c.Get("Authorization")
+	if len(auth) < 7 || auth[:7] != "Bearer " {
+		return fiber.ErrUnauthorized
+	}
+	tokenStr := auth[7:]
 
-		// Extract and validate claims
-		sub, ok := claims["sub"].(string)
-		if !ok {
+	if tokens != nil && strings.HasPrefix(tokenStr, model.PATPrefix) {
+		sub, role, scopes, err := tokens.AuthenticateAccessToken(c.Context(), tokenStr, c.IP())
+		if err != nil {
 			return fiber.ErrUnauthorized
 		}
 
-		role, ok := claims["role"].(string)
-		if !ok {
-			role = "USER" // Default role if not present
-		}
-
-		// Set locals
 		c.Locals("sub", sub)
 		c.Locals("role", role)
+		c.Locals("mfa", false)
+		c.Locals("scopes", scopes)
 
 		return c.Next()
 	}
+
+	tok, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fiber.ErrUnauthorized
+		}
+		return []byte(secret), nil
+	})
+	if err != nil || !tok.Valid {
+		return fiber.ErrUnauthorized
+	}
+
+	claims := tok.Claims.(jwt.MapClaims)
+
+	// Extract and validate claims
+	sub, ok := claims["sub"].(string)
+	if !ok {
+		return fiber.ErrUnauthorized
+	}
+
+	role, ok := claims["role"].(string)
+	if !ok {
+		role = "USER" // Default role if not present
+	}
+
+	// MFA tokens and other special-purpose tokens can't be used for API access
+	if typ, ok := claims["typ"].(string); ok && typ != "access" {
+		return fiber.ErrUnauthorized
+	}
+
+	mfa, _ := claims["mfa"].(bool)
+
+	tokenVersion, _ := claims["tv"].(float64)
+	sid, _ := claims["sid"].(string)
+	if validator != nil {
+		if err := validator.ValidateToken(c.Context(), sub, int(tokenVersion), sid, c.IP()); err != nil {
+			return fiber.ErrUnauthorized
+		}
+	}
+
+	// Set locals
+	c.Locals("sub", sub)
+	c.Locals("role", role)
+	c.Locals("mfa", mfa)
+	c.Locals("sid", sid)
+
+	return c.Next()
 }
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"goServer/internal/metrics"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+func Metrics() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		start := time.Now()
+		metrics.HTTPRequestsInFlight.Inc()
+		defer metrics.HTTPRequestsInFlight.Dec()
+
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		var fe *fiber.Error
+		if errors.As(err, &fe) {
+			status = fe.Code
+		} else if err != nil {
+			status = fiber.StatusInternalServerError
+		}
+
+		// Use the route template so /posts/:id doesn't explode label cardinality
+		route := "unmatched"
+		if r := c.Route(); r != nil && r.Path != "" && status != fiber.StatusNotFound {
+			route = r.Path
+		}
+
+		metrics.HTTPRequestDuration.
+			WithLabelValues(c.Method(), route, strconv.Itoa(status)).
+			Observe(time.Since(start).Seconds())
+
+		return err
+	}
+}
@@ -1,9 +1,9 @@
 package middleware
 
 import (
-	"context"
 	"time"
 
+	"goServer/internal/metrics"
 	"goServer/internal/service"
 
 	"github.com/gofiber/fiber/v3"
@@ -16,12 +16,13 @@ func RateLimit(rateLimitService *service.RateLimitService, action string, limit
 			return c.Next() // Skip rate limit for unauthenticated requests
 		}
 
-		allowed, err := rateLimitService.CheckLimit(context.Background(), userID.(string), action, limit, window)
+		allowed, err := rateLimitService.CheckLimit(c.Context(), userID.(string), action, limit, window)
 		if err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "rate limit check failed"})
 		}
 
 		if !allowed {
+			metrics.RateLimitRejections.WithLabelValues(action).Inc()
 			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": "rate limit exceeded"})
 		}
 
@@ -2,6 +2,8 @@ package middleware
 
 import "github.com/gofiber/fiber/v3"
 
+// RequireRole allows only users with one of the given roles. Admin access
+// additionally requires a session that was established with 2FA.
 func RequireRole(roles ...string) fiber.Handler {
 	roleMap := make(map[string]bool)
 	for _, role := range roles {
@@ -9,22 +11,27 @@ func RequireRole(roles ...string) fiber.Handler {
 	}
 
 	return func(c fiber.Ctx) error {
-		user := c.Locals("user")
-		if user == nil {
+		userRole, ok := c.Locals("role").(string)
+		if !ok || userRole == "" {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"error": "unauthorized",
 			})
 		}
 
-		data := user.(fiber.Map)
-		userRole := data["role"].(string)
-
 		if !roleMap[userRole] {
 			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 				"error": "forbidden",
 			})
 		}
 
+		if userRole == "ADMIN" {
+			if mfa, _ := c.Locals("mfa").(bool); !mfa {
+				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+					"error": "two-factor authentication required",
+				})
+			}
+		}
+
 		return c.Next()
 	}
 }
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"slices"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// RequireScope allows personal access tokens only if they were granted the
+// scope. Session tokens carry no scopes and are always allowed.
+func RequireScope(scope string) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		scopes, ok := c.Locals("scopes").([]string)
+		if !ok {
+			return c.Next()
+		}
+
+		if !slices.Contains(scopes, scope) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "token is missing the " + scope + " scope",
+			})
+		}
+
+		return c.Next()
+	}
+}
+
+// RequireSession rejects personal access tokens, for account management
+// endpoints that only the user themselves should reach
+func RequireSession() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if _, ok := c.Locals("scopes").([]string); ok {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "this endpoint cannot be used with an access token",
+			})
+		}
+
+		return c.Next()
+	}
+}
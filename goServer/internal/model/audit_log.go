@@ -0,0 +1,82 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	AuditActionDeleteUser     = "DELETE_USER"
+	AuditActionDeletePost     = "DELETE_POST"
+	AuditActionUpdateUserRole = "UPDATE_USER_ROLE"
+	AuditActionRestoreUser    = "RESTORE_USER"
+	AuditActionRestorePost    = "RESTORE_POST"
+
+	AuditTargetUser = "USER"
+	AuditTargetPost = "POST"
+)
+
+var ErrAuditLogImmutable = errors.New("audit log entries are append-only")
+
+// AuditLog records a single moderation action. Rows form a hash chain:
+// each Hash covers the row's content plus the previous row's Hash, so any
+// edit or deletion breaks verification from that point on.
+type AuditLog struct {
+	ID         string          `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+	Seq        int64           `gorm:"uniqueIndex;not null" json:"seq"`
+	ActorID    string          `gorm:"type:uuid;not null;index" json:"actor_id"`
+	Action     string          `gorm:"not null;index" json:"action"`
+	TargetType string          `gorm:"not null;index:idx_audit_target" json:"target_type"`
+	TargetID   string          `gorm:"not null;index:idx_audit_target" json:"target_id"`
+	Reason     string          `json:"reason"`
+	Before     json.RawMessage `gorm:"type:text" json:"before"`
+	After      json.RawMessage `gorm:"type:text" json:"after"`
+	IP         string          `json:"ip"`
+	UserAgent  string          `json:"user_agent"`
+	PrevHash   string          `gorm:"not null" json:"prev_hash"`
+	Hash       string          `gorm:"not null;uniqueIndex" json:"hash"`
+	CreatedAt  time.Time       `gorm:"not null;index" json:"created_at"`
+}
+
+// ComputeHash returns the chain hash for the entry given its PrevHash
+func (a *AuditLog) ComputeHash() string {
+	fields := []string{
+		a.PrevHash,
+		strconv.FormatInt(a.Seq, 10),
+		a.ActorID,
+		a.Action,
+		a.TargetType,
+		a.TargetID,
+		a.Reason,
+		string(a.Before),
+		string(a.After),
+		a.IP,
+		a.UserAgent,
+		a.CreatedAt.UTC().Format(time.RFC3339Nano),
+	}
+	sum := sha256.Sum256([]byte(strings.Join(fields, "\x1f")))
+	return hex.EncodeToString(sum[:])
+}
+
+func (a *AuditLog) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == "" {
+		a.ID = uuid.New().String()
+	}
+	return nil
+}
+
+func (a *AuditLog) BeforeUpdate(tx *gorm.DB) error {
+	return ErrAuditLogImmutable
+}
+
+func (a *AuditLog) BeforeDelete(tx *gorm.DB) error {
+	return ErrAuditLogImmutable
+}
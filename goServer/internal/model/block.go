@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+// Block stops two users from interacting: neither sees the other's content
+// and neither can follow, like, reply to, mention or notify the other
+type Block struct {
+	BlockerID string    `gorm:"type:uuid;not null;primaryKey" json:"blocker_id"`
+	BlockedID string    `gorm:"type:uuid;not null;primaryKey;index" json:"blocked_id"`
+	CreatedAt time.Time `gorm:"autoCreateTime:milli" json:"created_at"`
+
+	// Relations
+	Blocker User `gorm:"foreignKey:BlockerID;constraint:OnDelete:CASCADE"`
+	Blocked User `gorm:"foreignKey:BlockedID;constraint:OnDelete:CASCADE"`
+}
+
+// Mute hides an account from the muter's feed and notifications. The muted
+// user isn't told and can still interact.
+type Mute struct {
+	MuterID   string    `gorm:"type:uuid;not null;primaryKey" json:"muter_id"`
+	MutedID   string    `gorm:"type:uuid;not null;primaryKey;index" json:"muted_id"`
+	CreatedAt time.Time `gorm:"autoCreateTime:milli" json:"created_at"`
+
+	// Relations
+	Muter User `gorm:"foreignKey:MuterID;constraint:OnDelete:CASCADE"`
+	Muted User `gorm:"foreignKey:MutedID;constraint:OnDelete:CASCADE"`
+}
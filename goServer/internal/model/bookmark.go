@@ -0,0 +1,43 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Bookmark saves a post for later. Unlike likes, bookmarks are only ever
+// visible to the user who made them. A bookmark without a collection is
+// unsorted.
+type Bookmark struct {
+	UserID       string    `gorm:"type:uuid;not null;primaryKey" json:"user_id"`
+	PostID       string    `gorm:"type:uuid;not null;primaryKey;index" json:"post_id"`
+	CollectionID *string   `gorm:"type:uuid;index" json:"collection_id"`
+	CreatedAt    time.Time `gorm:"autoCreateTime:milli" json:"created_at"`
+
+	// Relations
+	User       User                `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Post       Post                `gorm:"foreignKey:PostID;constraint:OnDelete:CASCADE"`
+	Collection *BookmarkCollection `gorm:"foreignKey:CollectionID;constraint:OnDelete:SET NULL"`
+}
+
+// BookmarkCollection is a named group of a user's bookmarks. Deleting one
+// leaves its bookmarks unsorted.
+type BookmarkCollection struct {
+	ID        string    `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+	UserID    string    `gorm:"type:uuid;not null;index" json:"user_id"`
+	Name      string    `gorm:"not null" json:"name"` // unique per user regardless of case
+	CreatedAt time.Time `gorm:"autoCreateTime:milli" json:"created_at"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime:milli" json:"updated_at"`
+
+	// Relations
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+}
+
+func (c *BookmarkCollection) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == "" {
+		c.ID = uuid.New().String()
+	}
+	return nil
+}
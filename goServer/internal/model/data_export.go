@@ -0,0 +1,39 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	ExportStatusPending = "PENDING"
+	ExportStatusRunning = "RUNNING"
+	ExportStatusReady   = "READY"
+	ExportStatusFailed  = "FAILED"
+)
+
+// DataExport is a user's request for a copy of their data. A background job
+// builds the archive; once ready it can be downloaded until ExpiresAt.
+type DataExport struct {
+	ID          string     `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+	UserID      string     `gorm:"type:uuid;not null;index" json:"user_id"`
+	Status      string     `gorm:"not null;default:PENDING;index" json:"status"`
+	Size        int64      `gorm:"not null;default:0" json:"size"`
+	Error       string     `json:"error"`
+	CreatedAt   time.Time  `gorm:"autoCreateTime:milli" json:"created_at"`
+	StartedAt   *time.Time `json:"started_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+	ExpiresAt   *time.Time `gorm:"index" json:"expires_at"`
+
+	// Relations
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+}
+
+func (e *DataExport) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == "" {
+		e.ID = uuid.New().String()
+	}
+	return nil
+}
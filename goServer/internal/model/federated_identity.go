@@ -0,0 +1,42 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// FederatedIdentity links an account at an external OIDC provider to a user
+type FederatedIdentity struct {
+	ID          string     `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+	UserID      string     `gorm:"type:uuid;not null;index;uniqueIndex:idx_federated_user_provider" json:"user_id"`
+	Provider    string     `gorm:"not null;uniqueIndex:idx_federated_provider_subject;uniqueIndex:idx_federated_user_provider" json:"provider"`
+	Subject     string     `gorm:"not null;uniqueIndex:idx_federated_provider_subject" json:"-"`
+	Email       string     `json:"email"`
+	CreatedAt   time.Time  `gorm:"autoCreateTime:milli" json:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at"`
+
+	// Relations
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+}
+
+func (f *FederatedIdentity) BeforeCreate(tx *gorm.DB) error {
+	if f.ID == "" {
+		f.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// OIDCAuthRequest holds the state of an authorization request between the
+// redirect to the provider and the callback. It is looked up by the hash of
+// the state parameter and deleted when the callback consumes it.
+type OIDCAuthRequest struct {
+	StateHash    string    `gorm:"primaryKey" json:"-"`
+	Provider     string    `gorm:"not null" json:"provider"`
+	Nonce        string    `gorm:"not null" json:"-"`
+	CodeVerifier string    `gorm:"not null" json:"-"`
+	LinkUserID   *string   `gorm:"type:uuid" json:"link_user_id"` // set when linking to an existing account
+	ExpiresAt    time.Time `gorm:"not null;index" json:"expires_at"`
+	CreatedAt    time.Time `gorm:"autoCreateTime:milli" json:"created_at"`
+}
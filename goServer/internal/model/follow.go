@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+// Follow is a row of the follows join table behind User.Followers and
+// User.Following. CreatedAt orders follower lists.
+type Follow struct {
+	FollowerID string    `gorm:"type:uuid;not null;primaryKey" json:"follower_id"`
+	FolloweeID string    `gorm:"type:uuid;not null;primaryKey;index" json:"followee_id"`
+	CreatedAt  time.Time `gorm:"autoCreateTime:milli;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// FollowRequest is a pending follow of a private account, waiting for the
+// account owner to approve or reject it
+type FollowRequest struct {
+	RequesterID string    `gorm:"type:uuid;not null;primaryKey" json:"requester_id"`
+	TargetID    string    `gorm:"type:uuid;not null;primaryKey;index" json:"target_id"`
+	CreatedAt   time.Time `gorm:"autoCreateTime:milli" json:"created_at"`
+
+	// Relations
+	Requester User `gorm:"foreignKey:RequesterID;constraint:OnDelete:CASCADE"`
+	Target    User `gorm:"foreignKey:TargetID;constraint:OnDelete:CASCADE"`
+}
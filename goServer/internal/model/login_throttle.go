@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+const (
+	LoginThrottleScopeIP       = "IP"
+	LoginThrottleScopeUsername = "USERNAME"
+)
+
+// LoginThrottle counts recent failed logins for an IP address or a username.
+// Usernames are tracked whether or not the account exists, so lockouts
+// don't reveal which usernames are registered.
+type LoginThrottle struct {
+	Scope         string     `gorm:"primaryKey" json:"scope"`
+	Key           string     `gorm:"primaryKey" json:"key"`
+	Failures      int        `gorm:"not null;default:0" json:"failures"`
+	LastFailureAt time.Time  `gorm:"not null;index" json:"last_failure_at"`
+	LockedUntil   *time.Time `json:"locked_until"`
+}
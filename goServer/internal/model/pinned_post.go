@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// PinnedPost is one of a user's own posts shown at the top of their
+// profile timeline
+type PinnedPost struct {
+	UserID    string    `gorm:"type:uuid;not null;primaryKey" json:"user_id"`
+	PostID    string    `gorm:"type:uuid;not null;primaryKey;index" json:"post_id"`
+	CreatedAt time.Time `gorm:"autoCreateTime:milli" json:"created_at"`
+
+	// Relations
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Post Post `gorm:"foreignKey:PostID;constraint:OnDelete:CASCADE"`
+}
@@ -0,0 +1,58 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Poll is a question attached to a post. Votes are accepted until ClosesAt;
+// ResultsSentAt is set once voters and the author have been sent the final
+// results.
+type Poll struct {
+	ID            string     `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+	PostID        string     `gorm:"type:uuid;not null;uniqueIndex" json:"post_id"`
+	ClosesAt      time.Time  `gorm:"not null;index" json:"closes_at"`
+	ResultsSentAt *time.Time `json:"results_sent_at"`
+	CreatedAt     time.Time  `gorm:"autoCreateTime:milli" json:"created_at"`
+
+	// Relations
+	Options []PollOption `gorm:"foreignKey:PollID;constraint:OnDelete:CASCADE"`
+}
+
+func (p *Poll) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == "" {
+		p.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// PollOption is one of a poll's 2 to 4 choices
+type PollOption struct {
+	ID       string `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+	PollID   string `gorm:"type:uuid;not null;index" json:"poll_id"`
+	Position int    `gorm:"not null" json:"position"`
+	Text     string `gorm:"not null" json:"text"`
+}
+
+func (o *PollOption) BeforeCreate(tx *gorm.DB) error {
+	if o.ID == "" {
+		o.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// PollVote is a user's vote. The primary key allows one vote per user per
+// poll.
+type PollVote struct {
+	PollID    string    `gorm:"type:uuid;not null;primaryKey" json:"poll_id"`
+	UserID    string    `gorm:"type:uuid;not null;primaryKey;index" json:"user_id"`
+	OptionID  string    `gorm:"type:uuid;not null;index" json:"option_id"`
+	CreatedAt time.Time `gorm:"autoCreateTime:milli" json:"created_at"`
+
+	// Relations
+	Poll   Poll       `gorm:"foreignKey:PollID;constraint:OnDelete:CASCADE"`
+	User   User       `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Option PollOption `gorm:"foreignKey:OptionID;constraint:OnDelete:CASCADE"`
+}
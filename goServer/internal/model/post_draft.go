@@ -0,0 +1,44 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	DraftStatusDraft     = "DRAFT"
+	DraftStatusScheduled = "SCHEDULED"
+	DraftStatusFailed    = "FAILED" // the scheduler couldn't publish it; see Error
+)
+
+// PostDraft is a post that hasn't been published yet. Scheduled drafts are
+// published by a background job once PublishAt passes; the draft is deleted
+// when its post is created.
+type PostDraft struct {
+	ID           string     `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+	UserID       string     `gorm:"type:uuid;not null;index" json:"user_id"`
+	Text         string     `gorm:"not null" json:"text"`
+	ReplyTo      *string    `gorm:"type:uuid" json:"reply_to"`
+	IsQuote      bool       `gorm:"default:false" json:"is_quote"`
+	QuotedPostID *string    `gorm:"type:uuid" json:"quoted_post_id"`
+	MediaURLs    []string   `gorm:"serializer:json" json:"media_urls"`
+	PollOptions  []string   `gorm:"serializer:json" json:"poll_options"` // empty if the post has no poll
+	PollDuration int        `json:"poll_duration"`                       // minutes, counted from publishing
+	Status       string     `gorm:"not null;default:DRAFT;index:idx_post_drafts_due,priority:1" json:"status"`
+	PublishAt    *time.Time `gorm:"index:idx_post_drafts_due,priority:2" json:"publish_at"`
+	Error        string     `json:"error"`
+	CreatedAt    time.Time  `gorm:"autoCreateTime:milli" json:"created_at"`
+	UpdatedAt    time.Time  `gorm:"autoUpdateTime:milli" json:"updated_at"`
+
+	// Relations
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+}
+
+func (d *PostDraft) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == "" {
+		d.ID = uuid.New().String()
+	}
+	return nil
+}
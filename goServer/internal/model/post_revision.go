@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// PostRevision is a prior version of an edited post. Version 0 is the text
+// as first published.
+type PostRevision struct {
+	ID         string    `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+	PostID     string    `gorm:"type:uuid;not null;uniqueIndex:idx_post_revisions_version" json:"post_id"`
+	Version    int       `gorm:"not null;uniqueIndex:idx_post_revisions_version" json:"version"`
+	Text       string    `gorm:"not null" json:"text"`
+	CharCount  int       `gorm:"not null" json:"char_count"`
+	WrittenAt  time.Time `gorm:"not null" json:"written_at"` // when this version was published
+	ReplacedAt time.Time `gorm:"autoCreateTime:milli" json:"replaced_at"`
+}
+
+// PostHashtag is a hashtag used in a post, stored lowercase
+type PostHashtag struct {
+	PostID    string    `gorm:"type:uuid;not null;primaryKey" json:"post_id"`
+	Tag       string    `gorm:"not null;primaryKey;index" json:"tag"`
+	CreatedAt time.Time `gorm:"autoCreateTime:milli" json:"created_at"`
+}
@@ -0,0 +1,35 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Session is one login of a user on a device. Its ID is embedded in the
+// access token ("sid"), so revoking the session revokes the token.
+type Session struct {
+	ID           string     `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+	UserID       string     `gorm:"type:uuid;not null;index" json:"user_id"`
+	UserAgent    string     `json:"user_agent"`
+	Device       string     `json:"device"`         // readable summary of the user agent, e.g. "Firefox on Linux"
+	DeviceHash   string     `gorm:"index" json:"-"` // identifies the device when deciding whether it is new
+	IP           string     `json:"ip"`
+	TokenVersion int        `gorm:"not null;default:0" json:"-"` // user's token version when the session started
+	MFA          bool       `gorm:"not null;default:false" json:"mfa"`
+	CreatedAt    time.Time  `gorm:"autoCreateTime:milli" json:"created_at"`
+	LastSeenAt   time.Time  `json:"last_seen_at"`
+	ExpiresAt    time.Time  `gorm:"not null;index" json:"expires_at"`
+	RevokedAt    *time.Time `json:"revoked_at"`
+
+	// Relations
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+}
+
+func (s *Session) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == "" {
+		s.ID = uuid.New().String()
+	}
+	return nil
+}
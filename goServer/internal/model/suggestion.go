@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// FollowSuggestion is a precomputed who-to-follow entry for a user, rebuilt
+// periodically by the suggestion job
+type FollowSuggestion struct {
+	UserID      string    `gorm:"type:uuid;not null;primaryKey" json:"user_id"`
+	SuggestedID string    `gorm:"type:uuid;not null;primaryKey;index" json:"suggested_id"`
+	Score       float64   `gorm:"not null;index" json:"score"`
+	MutualCount int       `gorm:"not null;default:0" json:"mutual_count"` // accounts the user follows that follow the suggestion
+	SharedCount int       `gorm:"not null;default:0" json:"shared_count"` // posts both recently liked or reposted
+	ComputedAt  time.Time `gorm:"autoCreateTime:milli" json:"computed_at"`
+
+	// Relations
+	User      User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Suggested User `gorm:"foreignKey:SuggestedID;constraint:OnDelete:CASCADE"`
+}
+
+// SuggestionDismissal keeps an account out of a user's suggestions for good
+type SuggestionDismissal struct {
+	UserID      string    `gorm:"type:uuid;not null;primaryKey" json:"user_id"`
+	DismissedID string    `gorm:"type:uuid;not null;primaryKey" json:"dismissed_id"`
+	CreatedAt   time.Time `gorm:"autoCreateTime:milli" json:"created_at"`
+
+	// Relations
+	User      User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Dismissed User `gorm:"foreignKey:DismissedID;constraint:OnDelete:CASCADE"`
+}
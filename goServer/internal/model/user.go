@@ -16,16 +16,26 @@ const (
 
 // User represents a user account
 type User struct {
-	ID          string    `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
-	Username    string    `gorm:"uniqueIndex;not null" json:"username"`
-	DisplayName string    `json:"display_name"`
-	Email       string    `gorm:"uniqueIndex;not null" json:"email"`
-	Password    string    `gorm:"not null"`
-	Bio         string    `json:"bio"`
-	AvatarURL   string    `json:"avatar_url"`
-	Role        string    `gorm:"default:USER;not null"`
-	CreatedAt   time.Time `gorm:"autoCreateTime:milli" json:"created_at"`
-	UpdatedAt   time.Time `gorm:"autoUpdateTime:milli" json:"updated_at"`
+	ID                  string         `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+	Username            string         `gorm:"uniqueIndex;not null" json:"username"`
+	DisplayName         string         `json:"display_name"`
+	Email               string         `gorm:"uniqueIndex;not null" json:"email"`
+	Password            string         `gorm:"not null" json:"-"`
+	Bio                 string         `json:"bio"`
+	AvatarURL           string         `json:"avatar_url"`
+	IsPrivate           bool           `gorm:"not null;default:false" json:"is_private"` // posts are only visible to approved followers
+	Role                string         `gorm:"default:USER;not null"`
+	EmailVerifiedAt     *time.Time     `json:"email_verified_at"`
+	TokenVersion        int            `gorm:"not null;default:0" json:"-"` // embedded in JWTs; bumping it revokes them all
+	UsernameChangedAt   *time.Time     `json:"username_changed_at"`
+	TOTPSecret          string         `json:"-"` // sealed with MFASecretKey
+	TOTPEnabledAt       *time.Time     `json:"totp_enabled_at"`
+	TOTPLastStep        int64          `gorm:"not null;default:0" json:"-"`        // last accepted TOTP time step, so a code can't be replayed
+	DeletionScheduledAt *time.Time     `gorm:"index" json:"deletion_scheduled_at"` // account is removed at this time unless the user logs in first
+	SuggestionsBuiltAt  *time.Time     `gorm:"index" json:"-"`                     // when the suggestion job last rebuilt this user's suggestions
+	CreatedAt           time.Time      `gorm:"autoCreateTime:milli" json:"created_at"`
+	UpdatedAt           time.Time      `gorm:"autoUpdateTime:milli" json:"updated_at"`
+	DeletedAt           gorm.DeletedAt `gorm:"index" json:"deleted_at"`
 
 	// Relations
 	Rant          []Rant         `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
@@ -48,33 +58,43 @@ type Rant struct {
 }
 
 type Post struct {
-	ID            string    `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
-	UserID        string    `gorm:"type:uuid;not null;index" json:"user_id"`
-	Text          string    `gorm:"not null" json:"text"`
-	CharCount     int       `gorm:"not null" json:"char_count"`
-	ReplyTo       *string   `gorm:"type:uuid;index" json:"reply_to"` // null if not a reply
-	IsQuote       bool      `gorm:"default:false" json:"is_quote"`
-	QuotedTweetID *string   `gorm:"type:uuid;index" json:"quoted_post_id"`
-	CreatedAt     time.Time `gorm:"autoCreateTime:milli" json:"created_at"`
-	UpdatedAt     time.Time `gorm:"autoUpdateTime:milli" json:"updated_at"`
+	ID            string         `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+	UserID        string         `gorm:"type:uuid;not null;index" json:"user_id"`
+	Text          string         `gorm:"not null" json:"text"`
+	CharCount     int            `gorm:"not null" json:"char_count"`
+	ReplyTo       *string        `gorm:"type:uuid;index" json:"reply_to"` // null if not a reply
+	IsQuote       bool           `gorm:"default:false" json:"is_quote"`
+	QuotedTweetID *string        `gorm:"type:uuid;index" json:"quoted_post_id"`
+	EditedAt      *time.Time     `json:"edited_at"`
+	EditCount     int            `gorm:"not null;default:0" json:"edit_count"`
+	CreatedAt     time.Time      `gorm:"autoCreateTime:milli" json:"created_at"`
+	UpdatedAt     time.Time      `gorm:"autoUpdateTime:milli" json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `gorm:"index" json:"deleted_at"`
 
 	// Relations
-	User        User      `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
-	Media       []Media   `gorm:"foreignKey:PostID;constraint:OnDelete:CASCADE"`
-	Likes       []Like    `gorm:"foreignKey:PostID;constraint:OnDelete:CASCADE"`
-	Retweets    []Repost  `gorm:"foreignKey:PostID;constraint:OnDelete:CASCADE"`
-	Mentions    []Mention `gorm:"foreignKey:PostID;constraint:OnDelete:CASCADE"`
-	RepliedPost *Post     `gorm:"foreignKey:ReplyTo;constraint:OnDelete:CASCADE"`
-	QuotedPost  *Post     `gorm:"foreignKey:QuotedTweetID;constraint:OnDelete:CASCADE"`
+	User      User           `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Media     []Media        `gorm:"foreignKey:PostID;constraint:OnDelete:CASCADE"`
+	Likes     []Like         `gorm:"foreignKey:PostID;constraint:OnDelete:CASCADE"`
+	Retweets  []Repost       `gorm:"foreignKey:PostID;constraint:OnDelete:CASCADE"`
+	Mentions  []Mention      `gorm:"foreignKey:PostID;constraint:OnDelete:CASCADE"`
+	Hashtags  []PostHashtag  `gorm:"foreignKey:PostID;constraint:OnDelete:CASCADE"`
+	Revisions []PostRevision `gorm:"foreignKey:PostID;constraint:OnDelete:CASCADE"`
+	Poll      *Poll          `gorm:"foreignKey:PostID;constraint:OnDelete:CASCADE"`
+
+	// Purging a post or its author must not take other users' replies and
+	// quotes with it; they lose the reference instead
+	RepliedPost *Post `gorm:"foreignKey:ReplyTo;constraint:OnDelete:SET NULL"`
+	QuotedPost  *Post `gorm:"foreignKey:QuotedTweetID;constraint:OnDelete:SET NULL"`
 }
 
 type Media struct {
-	ID        string    `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
-	PostID    string    `gorm:"type:uuid;not null;index" json:"post_id"`
-	URL       string    `gorm:"not null" json:"url"`
-	MediaType string    `json:"media_type"` // image/video
-	Position  int       `gorm:"default:0" json:"position"`
-	CreatedAt time.Time `gorm:"autoCreateTime:milli" json:"created_at"`
+	ID        string         `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+	PostID    string         `gorm:"type:uuid;not null;index" json:"post_id"`
+	URL       string         `gorm:"not null" json:"url"`
+	MediaType string         `json:"media_type"` // image/video
+	Position  int            `gorm:"default:0" json:"position"`
+	CreatedAt time.Time      `gorm:"autoCreateTime:milli" json:"created_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at"`
 
 	// Relations
 	Post Post `gorm:"foreignKey:PostID;constraint:OnDelete:CASCADE"`
@@ -112,11 +132,15 @@ type Mention struct {
 }
 
 type Notification struct {
-	ID        string    `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
-	UserID    string    `gorm:"type:uuid;not null;index" json:"user_id"`
-	Type      string    `json:"type"` // e.g., "LIKE", "REPOST", "MENTION"
-	Read      bool      `gorm:"default:false" json:"read"`
-	CreatedAt time.Time `gorm:"autoCreateTime:milli" json:"created_at"`
+	ID        string         `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+	UserID    string         `gorm:"type:uuid;not null;index" json:"user_id"`
+	ActorID   *string        `gorm:"type:uuid;index" json:"actor_id"` // user whose action caused it, if any
+	PostID    *string        `gorm:"type:uuid" json:"post_id"`        // post it is about, if any
+	Type      string         `json:"type"`                            // e.g., "LIKE", "REPOST", "MENTION"
+	Message   string         `json:"message"`                         // optional details, e.g. the device for "NEW_DEVICE_LOGIN"
+	Read      bool           `gorm:"default:false" json:"read"`
+	CreatedAt time.Time      `gorm:"autoCreateTime:milli" json:"created_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at"`
 
 	// Relations
 	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
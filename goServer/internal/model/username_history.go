@@ -0,0 +1,33 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// UsernameHistory records a username change. Until ReleasedAt the old
+// handle redirects to the account and can't be taken by anyone else.
+type UsernameHistory struct {
+	ID          string    `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+	UserID      string    `gorm:"type:uuid;not null;index" json:"user_id"`
+	OldUsername string    `gorm:"not null;index" json:"old_username"`
+	NewUsername string    `gorm:"not null" json:"new_username"`
+	ChangedAt   time.Time `gorm:"not null" json:"changed_at"`
+	ReleasedAt  time.Time `gorm:"not null;index" json:"released_at"`
+
+	// Relations
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+}
+
+func (UsernameHistory) TableName() string {
+	return "username_history"
+}
+
+func (h *UsernameHistory) BeforeCreate(tx *gorm.DB) error {
+	if h.ID == "" {
+		h.ID = uuid.New().String()
+	}
+	return nil
+}
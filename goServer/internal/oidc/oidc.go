@@ -0,0 +1,155 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"goServer/internal/config"
+
+	gooidc "github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+var ErrUnknownProvider = errors.New("unknown identity provider")
+
+// Claims are the ID token claims used to identify and provision users
+type Claims struct {
+	Subject           string `json:"sub"`
+	Email             string `json:"email"`
+	EmailVerified     bool   `json:"email_verified"`
+	Name              string `json:"name"`
+	PreferredUsername string `json:"preferred_username"`
+	Picture           string `json:"picture"`
+}
+
+// Provider is a configured OpenID Connect provider
+type Provider struct {
+	Name     string
+	oauth    oauth2.Config
+	verifier *gooidc.IDTokenVerifier
+}
+
+// AuthCodeURL returns the provider's authorization URL for the authorization
+// code flow with PKCE (S256) and a nonce bound into the ID token
+func (p *Provider) AuthCodeURL(state, nonce, codeVerifier string) string {
+	return p.oauth.AuthCodeURL(state,
+		oauth2.S256ChallengeOption(codeVerifier),
+		gooidc.Nonce(nonce),
+	)
+}
+
+// Exchange redeems an authorization code and verifies the returned ID token
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*Claims, error) {
+	ctx = gooidc.ClientContext(ctx, httpClient)
+
+	tok, err := p.oauth.Exchange(ctx, code, oauth2.VerifierOption(codeVerifier))
+	if err != nil {
+		return nil, fmt.Errorf("code exchange failed: %w", err)
+	}
+
+	rawIDToken, ok := tok.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, errors.New("token response has no id_token")
+	}
+
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id_token: %w", err)
+	}
+	if idToken.Nonce != nonce {
+		return nil, errors.New("id_token nonce mismatch")
+	}
+
+	var claims Claims
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("invalid id_token claims: %w", err)
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("id_token has no subject")
+	}
+	return &claims, nil
+}
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// Registry holds the configured providers. Discovery runs on first use, so
+// a provider that is down at startup doesn't keep the server from booting.
+type Registry struct {
+	mu          sync.Mutex
+	configs     map[string]config.OIDCProviderConfig
+	providers   map[string]*Provider
+	redirectURL func(name string) string
+}
+
+func NewRegistry(cfg config.Config) *Registry {
+	configs := make(map[string]config.OIDCProviderConfig, len(cfg.OIDCProviders))
+	for _, p := range cfg.OIDCProviders {
+		configs[p.Name] = p
+	}
+
+	return &Registry{
+		configs:   configs,
+		providers: make(map[string]*Provider),
+		redirectURL: func(name string) string {
+			return cfg.AppBaseURL + "/api/v1/auth/oidc/" + name + "/callback"
+		},
+	}
+}
+
+// Names lists the configured providers
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.configs))
+	for name := range r.configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Get returns a provider, running discovery the first time it's requested
+func (r *Registry) Get(name string) (*Provider, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if p, ok := r.providers[name]; ok {
+		return p, nil
+	}
+
+	cfg, ok := r.configs[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+
+	// The provider keeps this context for refreshing signing keys, so it
+	// must not be tied to the request that triggered discovery
+	ctx := gooidc.ClientContext(context.Background(), httpClient)
+	discovered, err := gooidc.NewProvider(ctx, cfg.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("oidc discovery for %s failed: %w", name, err)
+	}
+
+	scopes := cfg.Scopes
+	if !slices.Contains(scopes, gooidc.ScopeOpenID) {
+		scopes = append([]string{gooidc.ScopeOpenID}, scopes...)
+	}
+
+	p := &Provider{
+		Name: name,
+		oauth: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			Endpoint:     discovered.Endpoint(),
+			RedirectURL:  r.redirectURL(name),
+			Scopes:       scopes,
+		},
+		verifier: discovered.Verifier(&gooidc.Config{ClientID: cfg.ClientID}),
+	}
+	r.providers[name] = p
+	return p, nil
+}
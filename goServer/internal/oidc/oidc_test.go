@@ -0,0 +1,90 @@
+package oidc_test
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"goServer/internal/config"
+	"goServer/internal/oidc"
+	"goServer/internal/oidc/oidctest"
+)
+
+func newProvider(t *testing.T) (*oidctest.Server, *oidc.Provider) {
+	t.Helper()
+
+	srv := oidctest.NewServer(t, "client")
+	registry := oidc.NewRegistry(config.Config{
+		AppBaseURL: "http://app.test",
+		OIDCProviders: []config.OIDCProviderConfig{
+			{Name: "mock", Issuer: srv.Issuer, ClientID: "client", ClientSecret: "secret"},
+		},
+	})
+	p, err := registry.Get("mock")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	return srv, p
+}
+
+func TestExchange(t *testing.T) {
+	srv, p := newProvider(t)
+
+	authURL := p.AuthCodeURL("state-1", "nonce-1", "verifier-0123456789-0123456789-0123456789")
+	u, _ := url.Parse(authURL)
+	if got := u.Query().Get("redirect_uri"); got != "http://app.test/api/v1/auth/oidc/mock/callback" {
+		t.Errorf("redirect_uri = %q", got)
+	}
+	if got := u.Query().Get("scope"); got != "openid" {
+		t.Errorf("scope = %q, want openid", got)
+	}
+
+	state, code := srv.Authorize(t, authURL, oidctest.Claims{
+		Subject:       "sub-1",
+		Email:         "Alice@Example.com",
+		EmailVerified: true,
+		Name:          "Alice",
+	})
+	if state != "state-1" {
+		t.Errorf("state = %q, want state-1", state)
+	}
+
+	claims, err := p.Exchange(context.Background(), code, "verifier-0123456789-0123456789-0123456789", "nonce-1")
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if claims.Subject != "sub-1" || claims.Email != "Alice@Example.com" || !claims.EmailVerified || claims.Name != "Alice" {
+		t.Errorf("claims = %+v", claims)
+	}
+
+	if _, err := p.Exchange(context.Background(), code, "verifier-0123456789-0123456789-0123456789", "nonce-1"); err == nil {
+		t.Error("Exchange accepted a code twice")
+	}
+}
+
+func TestExchangeRejectsWrongVerifier(t *testing.T) {
+	srv, p := newProvider(t)
+
+	_, code := srv.Authorize(t, p.AuthCodeURL("state", "nonce", "verifier-0123456789-0123456789-0123456789"), oidctest.Claims{Subject: "sub"})
+
+	if _, err := p.Exchange(context.Background(), code, "other-verifier-0123456789-0123456789-012", "nonce"); err == nil {
+		t.Error("Exchange accepted a code with the wrong PKCE verifier")
+	}
+}
+
+func TestExchangeRejectsWrongNonce(t *testing.T) {
+	srv, p := newProvider(t)
+
+	_, code := srv.Authorize(t, p.AuthCodeURL("state", "nonce", "verifier-0123456789-0123456789-0123456789"), oidctest.Claims{Subject: "sub"})
+
+	if _, err := p.Exchange(context.Background(), code, "verifier-0123456789-0123456789-0123456789", "other-nonce"); err == nil {
+		t.Error("Exchange accepted an ID token issued for another nonce")
+	}
+}
+
+func TestGetUnknownProvider(t *testing.T) {
+	registry := oidc.NewRegistry(config.Config{})
+	if _, err := registry.Get("nope"); err != oidc.ErrUnknownProvider {
+		t.Errorf("Get = %v, want ErrUnknownProvider", err)
+	}
+}
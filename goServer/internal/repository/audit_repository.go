@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"goServer/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// auditChainLockKey serializes appends so that two writers never link to the same previous hash
+const auditChainLockKey = 7_301_550_881
+
+type AuditRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditRepository(db *gorm.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+// AuditFilter narrows down audit log queries; zero values are ignored
+type AuditFilter struct {
+	ActorID    string
+	Action     string
+	TargetType string
+	TargetID   string
+	From       time.Time
+	To         time.Time
+}
+
+// WithTx returns a repository bound to the given transaction
+func (r *AuditRepository) WithTx(tx *gorm.DB) *AuditRepository {
+	return &AuditRepository{db: tx}
+}
+
+// Transaction runs fn inside a database transaction
+func (r *AuditRepository) Transaction(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	return r.db.WithContext(ctx).Transaction(fn)
+}
+
+// Append links the entry to the end of the hash chain and inserts it.
+// It must be called inside a transaction so the chain lock is held until commit.
+func (r *AuditRepository) Append(ctx context.Context, a *model.AuditLog) error {
+	db := r.db.WithContext(ctx)
+
+	if err := db.Exec("SELECT pg_advisory_xact_lock(?)", auditChainLockKey).Error; err != nil {
+		return err
+	}
+
+	var last model.AuditLog
+	err := db.Order("seq DESC").First(&last).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		a.Seq = 1
+		a.PrevHash = ""
+	case err != nil:
+		return err
+	default:
+		a.Seq = last.Seq + 1
+		a.PrevHash = last.Hash
+	}
+
+	// Postgres keeps microseconds, so truncate before hashing or verification would never match
+	a.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	a.Hash = a.ComputeHash()
+
+	return db.Create(a).Error
+}
+
+// List returns audit entries matching the filter, newest first, with the total match count
+func (r *AuditRepository) List(ctx context.Context, f AuditFilter, limit, offset int) ([]model.AuditLog, int64, error) {
+	var logs []model.AuditLog
+	var total int64
+
+	q := r.filtered(ctx, f)
+	if err := q.Model(&model.AuditLog{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := r.filtered(ctx, f).
+		Order("seq DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&logs).Error; err != nil {
+		return nil, 0, err
+	}
+	return logs, total, nil
+}
+
+// Each calls fn for every entry matching the filter in chain order, loading rows in batches
+func (r *AuditRepository) Each(ctx context.Context, f AuditFilter, fn func(a *model.AuditLog) error) error {
+	var lastSeq int64
+	for {
+		var batch []model.AuditLog
+		if err := r.filtered(ctx, f).
+			Where("seq > ?", lastSeq).
+			Order("seq ASC").
+			Limit(500).
+			Find(&batch).Error; err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+
+		for i := range batch {
+			if err := fn(&batch[i]); err != nil {
+				return err
+			}
+		}
+		lastSeq = batch[len(batch)-1].Seq
+	}
+}
+
+func (r *AuditRepository) filtered(ctx context.Context, f AuditFilter) *gorm.DB {
+	q := r.db.WithContext(ctx)
+	if f.ActorID != "" {
+		q = q.Where("actor_id = ?", f.ActorID)
+	}
+	if f.Action != "" {
+		q = q.Where("action = ?", f.Action)
+	}
+	if f.TargetType != "" {
+		q = q.Where("target_type = ?", f.TargetType)
+	}
+	if f.TargetID != "" {
+		q = q.Where("target_id = ?", f.TargetID)
+	}
+	if !f.From.IsZero() {
+		q = q.Where("created_at >= ?", f.From)
+	}
+	if !f.To.IsZero() {
+		q = q.Where("created_at < ?", f.To)
+	}
+	return q
+}
@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+
+	"goServer/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// blockedSubquery selects everyone the viewer blocked or was blocked by
+const blockedSubquery = "SELECT blocked_id FROM blocks WHERE blocker_id = @viewer UNION SELECT blocker_id FROM blocks WHERE blocked_id = @viewer"
+
+// mutedSubquery selects everyone the viewer muted
+const mutedSubquery = "SELECT muted_id FROM mutes WHERE muter_id = @viewer"
+
+// excludeBlocked drops rows whose user column points at someone on either
+// side of a block with the viewer. Anonymous viewers see everything.
+func excludeBlocked(db *gorm.DB, column, viewerID string) *gorm.DB {
+	if viewerID == "" {
+		return db
+	}
+	return db.Where(column+" NOT IN ("+blockedSubquery+")", map[string]interface{}{"viewer": viewerID})
+}
+
+// excludeMuted drops rows whose user column points at someone the viewer muted
+func excludeMuted(db *gorm.DB, column, viewerID string) *gorm.DB {
+	if viewerID == "" {
+		return db
+	}
+	return db.Where(column+" NOT IN ("+mutedSubquery+")", map[string]interface{}{"viewer": viewerID})
+}
+
+type BlockRepository struct {
+	db *gorm.DB
+}
+
+func NewBlockRepository(db *gorm.DB) *BlockRepository {
+	return &BlockRepository{db: db}
+}
+
+// Block records a block and removes any follows or pending follow requests
+// between the two users
+func (r *BlockRepository) Block(ctx context.Context, blockerID, blockedID string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&model.Block{BlockerID: blockerID, BlockedID: blockedID}).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM follows WHERE (follower_id = ? AND followee_id = ?) OR (follower_id = ? AND followee_id = ?)",
+			blockerID, blockedID, blockedID, blockerID).Error; err != nil {
+			return err
+		}
+		return tx.Exec("DELETE FROM follow_requests WHERE (requester_id = ? AND target_id = ?) OR (requester_id = ? AND target_id = ?)",
+			blockerID, blockedID, blockedID, blockerID).Error
+	})
+}
+
+// Unblock removes a block, reporting whether there was one
+func (r *BlockRepository) Unblock(ctx context.Context, blockerID, blockedID string) (bool, error) {
+	res := r.db.WithContext(ctx).
+		Where("blocker_id = ? AND blocked_id = ?", blockerID, blockedID).
+		Delete(&model.Block{})
+	return res.RowsAffected > 0, res.Error
+}
+
+// IsBlockedEitherWay reports whether either user has blocked the other
+func (r *BlockRepository) IsBlockedEitherWay(ctx context.Context, userID, otherID string) (bool, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).
+		Model(&model.Block{}).
+		Where("(blocker_id = ? AND blocked_id = ?) OR (blocker_id = ? AND blocked_id = ?)", userID, otherID, otherID, userID).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// IsMuted reports whether muterID muted mutedID
+func (r *BlockRepository) IsMuted(ctx context.Context, muterID, mutedID string) (bool, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).
+		Model(&model.Mute{}).
+		Where("muter_id = ? AND muted_id = ?", muterID, mutedID).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// GetBlocked lists the users a user blocked, most recent first
+func (r *BlockRepository) GetBlocked(ctx context.Context, userID string, limit, offset int) ([]model.User, error) {
+	var users []model.User
+	if err := r.db.WithContext(ctx).
+		Joins("JOIN blocks ON users.id = blocks.blocked_id").
+		Where("blocks.blocker_id = ?", userID).
+		Order("blocks.created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// Mute records a mute
+func (r *BlockRepository) Mute(ctx context.Context, muterID, mutedID string) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&model.Mute{MuterID: muterID, MutedID: mutedID}).Error
+}
+
+// Unmute removes a mute, reporting whether there was one
+func (r *BlockRepository) Unmute(ctx context.Context, muterID, mutedID string) (bool, error) {
+	res := r.db.WithContext(ctx).
+		Where("muter_id = ? AND muted_id = ?", muterID, mutedID).
+		Delete(&model.Mute{})
+	return res.RowsAffected > 0, res.Error
+}
+
+// GetMuted lists the users a user muted, most recent first
+func (r *BlockRepository) GetMuted(ctx context.Context, userID string, limit, offset int) ([]model.User, error) {
+	var users []model.User
+	if err := r.db.WithContext(ctx).
+		Joins("JOIN mutes ON users.id = mutes.muted_id").
+		Where("mutes.muter_id = ?", userID).
+		Order("mutes.created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
@@ -0,0 +1,196 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"goServer/internal/model"
+	"goServer/pkg/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// BookmarkFilter narrows a bookmark listing. With neither field set every
+// bookmark is listed.
+type BookmarkFilter struct {
+	CollectionID string
+	Unsorted     bool // only bookmarks outside any collection
+}
+
+// BookmarkRepository stores users' private bookmarks and their collections
+type BookmarkRepository struct {
+	db *gorm.DB
+}
+
+func NewBookmarkRepository(db *gorm.DB) *BookmarkRepository {
+	return &BookmarkRepository{db: db}
+}
+
+// Add bookmarks a post, moving it to collectionID if it was already
+// bookmarked. The original bookmark time is kept.
+func (r *BookmarkRepository) Add(ctx context.Context, userID, postID string, collectionID *string) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "post_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"collection_id"}),
+		}).
+		Create(&model.Bookmark{UserID: userID, PostID: postID, CollectionID: collectionID}).Error
+}
+
+// Remove deletes a bookmark, reporting whether there was one
+func (r *BookmarkRepository) Remove(ctx context.Context, userID, postID string) (bool, error) {
+	res := r.db.WithContext(ctx).
+		Where("user_id = ? AND post_id = ?", userID, postID).
+		Delete(&model.Bookmark{})
+	return res.RowsAffected > 0, res.Error
+}
+
+// Page gets a page of a user's bookmarks with their posts, most recently
+// bookmarked first, starting after the given cursor. Deleted posts, and
+// posts the user can no longer see because of a block or a private
+// account, are left out.
+func (r *BookmarkRepository) Page(ctx context.Context, userID string, filter BookmarkFilter, after *utils.Cursor, limit int) ([]model.Bookmark, error) {
+	var bookmarks []model.Bookmark
+	db := r.db.WithContext(ctx).
+		Preload("Post.User").
+		Preload("Post.Media").
+		Joins("JOIN posts ON posts.id = bookmarks.post_id AND posts.deleted_at IS NULL").
+		Where("bookmarks.user_id = ?", userID)
+	db = excludeBlocked(db, "posts.user_id", userID)
+	db = excludePrivate(db, "posts.user_id", userID)
+	switch {
+	case filter.CollectionID != "":
+		db = db.Where("bookmarks.collection_id = ?", filter.CollectionID)
+	case filter.Unsorted:
+		db = db.Where("bookmarks.collection_id IS NULL")
+	}
+	if after != nil {
+		db = db.Where("(bookmarks.created_at, bookmarks.post_id) < (?, ?)", after.At, after.ID)
+	}
+	if err := db.
+		Order("bookmarks.created_at DESC, bookmarks.post_id DESC").
+		Limit(limit).
+		Find(&bookmarks).Error; err != nil {
+		return nil, err
+	}
+	return bookmarks, nil
+}
+
+// BookmarkedIDs reports which of postIDs the user has bookmarked
+func (r *BookmarkRepository) BookmarkedIDs(ctx context.Context, userID string, postIDs []string) (map[string]bool, error) {
+	bookmarked := make(map[string]bool)
+	if userID == "" || len(postIDs) == 0 {
+		return bookmarked, nil
+	}
+
+	var found []string
+	if err := r.db.WithContext(ctx).
+		Model(&model.Bookmark{}).
+		Where("user_id = ? AND post_id IN ?", userID, postIDs).
+		Pluck("post_id", &found).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range found {
+		bookmarked[id] = true
+	}
+	return bookmarked, nil
+}
+
+// CreateCollection creates a bookmark collection
+func (r *BookmarkRepository) CreateCollection(ctx context.Context, c *model.BookmarkCollection) error {
+	return r.db.WithContext(ctx).Create(c).Error
+}
+
+// FindCollection finds one of a user's collections by ID
+func (r *BookmarkRepository) FindCollection(ctx context.Context, id, userID string) (*model.BookmarkCollection, error) {
+	var c model.BookmarkCollection
+	if err := r.db.WithContext(ctx).
+		Where("id = ? AND user_id = ?", id, userID).
+		First(&c).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &c, nil
+}
+
+// FindCollectionByName finds one of a user's collections by name, ignoring
+// case
+func (r *BookmarkRepository) FindCollectionByName(ctx context.Context, userID, name string) (*model.BookmarkCollection, error) {
+	var c model.BookmarkCollection
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ? AND lower(name) = lower(?)", userID, name).
+		First(&c).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &c, nil
+}
+
+// ListCollections lists a user's collections by name
+func (r *BookmarkRepository) ListCollections(ctx context.Context, userID string) ([]model.BookmarkCollection, error) {
+	var collections []model.BookmarkCollection
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("lower(name) ASC").
+		Find(&collections).Error; err != nil {
+		return nil, err
+	}
+	return collections, nil
+}
+
+// CollectionCounts counts a user's bookmarks in each of their collections
+func (r *BookmarkRepository) CollectionCounts(ctx context.Context, userID string) (map[string]int64, error) {
+	var rows []struct {
+		CollectionID string
+		Count        int64
+	}
+	if err := r.db.WithContext(ctx).
+		Model(&model.Bookmark{}).
+		Select("collection_id, COUNT(*) AS count").
+		Where("user_id = ? AND collection_id IS NOT NULL", userID).
+		Group("collection_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.CollectionID] = row.Count
+	}
+	return counts, nil
+}
+
+// CountCollections counts a user's collections
+func (r *BookmarkRepository) CountCollections(ctx context.Context, userID string) (int64, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).
+		Model(&model.BookmarkCollection{}).
+		Where("user_id = ?", userID).
+		Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// RenameCollection renames one of a user's collections, reporting whether it
+// exists
+func (r *BookmarkRepository) RenameCollection(ctx context.Context, id, userID, name string) (bool, error) {
+	res := r.db.WithContext(ctx).
+		Model(&model.BookmarkCollection{}).
+		Where("id = ? AND user_id = ?", id, userID).
+		Update("name", name)
+	return res.RowsAffected > 0, res.Error
+}
+
+// DeleteCollection deletes one of a user's collections, leaving its
+// bookmarks unsorted, and reports whether it existed
+func (r *BookmarkRepository) DeleteCollection(ctx context.Context, id, userID string) (bool, error) {
+	res := r.db.WithContext(ctx).
+		Where("id = ? AND user_id = ?", id, userID).
+		Delete(&model.BookmarkCollection{})
+	return res.RowsAffected > 0, res.Error
+}
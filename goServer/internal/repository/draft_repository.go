@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"goServer/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DraftRepository stores unpublished and scheduled posts
+type DraftRepository struct {
+	db *gorm.DB
+}
+
+func NewDraftRepository(db *gorm.DB) *DraftRepository {
+	return &DraftRepository{db: db}
+}
+
+// WithTx returns a repository that runs its queries in tx
+func (r *DraftRepository) WithTx(tx *gorm.DB) *DraftRepository {
+	return &DraftRepository{db: tx}
+}
+
+// Transaction runs fn in a database transaction
+func (r *DraftRepository) Transaction(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	return r.db.WithContext(ctx).Transaction(fn)
+}
+
+// Create creates a new draft
+func (r *DraftRepository) Create(ctx context.Context, d *model.PostDraft) error {
+	return r.db.WithContext(ctx).Create(d).Error
+}
+
+// FindForUser finds one of a user's drafts
+func (r *DraftRepository) FindForUser(ctx context.Context, id, userID string) (*model.PostDraft, error) {
+	var d model.PostDraft
+	if err := r.db.WithContext(ctx).
+		Where("id = ? AND user_id = ?", id, userID).
+		First(&d).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &d, nil
+}
+
+// LockForUser is FindForUser, also locking the draft until the transaction
+// ends so the scheduler can't publish it at the same time
+func (r *DraftRepository) LockForUser(ctx context.Context, id, userID string) (*model.PostDraft, error) {
+	var d model.PostDraft
+	if err := r.db.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND user_id = ?", id, userID).
+		First(&d).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &d, nil
+}
+
+// ListForUser lists a user's drafts: scheduled ones by publish time, then
+// the rest most recently edited first
+func (r *DraftRepository) ListForUser(ctx context.Context, userID string) ([]model.PostDraft, error) {
+	var drafts []model.PostDraft
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("publish_at ASC NULLS LAST, updated_at DESC").
+		Find(&drafts).Error; err != nil {
+		return nil, err
+	}
+	return drafts, nil
+}
+
+// CountForUser counts a user's drafts
+func (r *DraftRepository) CountForUser(ctx context.Context, userID string) (int64, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).
+		Model(&model.PostDraft{}).
+		Where("user_id = ?", userID).
+		Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Update saves a draft's content and schedule, reporting false if it no
+// longer exists (it was published or discarded in the meantime)
+func (r *DraftRepository) Update(ctx context.Context, d *model.PostDraft) (bool, error) {
+	res := r.db.WithContext(ctx).
+		Model(&model.PostDraft{}).
+		Where("id = ? AND user_id = ?", d.ID, d.UserID).
+		Select("text", "reply_to", "is_quote", "quoted_post_id", "media_urls", "poll_options", "poll_duration", "status", "publish_at", "error", "updated_at").
+		Updates(d)
+	return res.RowsAffected > 0, res.Error
+}
+
+// ClaimDue locks the scheduled draft that has been due longest and returns
+// it, or nil if none is due. Call it in a transaction: the lock is held
+// until the transaction ends, and SKIP LOCKED lets several instances poll
+// without publishing the same draft twice.
+func (r *DraftRepository) ClaimDue(ctx context.Context, at time.Time) (*model.PostDraft, error) {
+	var d model.PostDraft
+	if err := r.db.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("status = ? AND publish_at <= ?", model.DraftStatusScheduled, at).
+		Order("publish_at ASC").
+		First(&d).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &d, nil
+}
+
+// MarkFailed records why a scheduled draft couldn't be published
+func (r *DraftRepository) MarkFailed(ctx context.Context, id, reason string) error {
+	return r.db.WithContext(ctx).
+		Model(&model.PostDraft{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status": model.DraftStatusFailed,
+			"error":  reason,
+		}).Error
+}
+
+// Delete deletes one of a user's drafts, reporting whether it existed
+func (r *DraftRepository) Delete(ctx context.Context, id, userID string) (bool, error) {
+	res := r.db.WithContext(ctx).
+		Where("id = ? AND user_id = ?", id, userID).
+		Delete(&model.PostDraft{})
+	return res.RowsAffected > 0, res.Error
+}
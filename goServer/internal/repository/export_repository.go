@@ -0,0 +1,187 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"goServer/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// ExportRepository stores data export jobs and reads everything a user's
+// export contains
+type ExportRepository struct {
+	db *gorm.DB
+}
+
+func NewExportRepository(db *gorm.DB) *ExportRepository {
+	return &ExportRepository{db: db}
+}
+
+// Create creates a new export job
+func (r *ExportRepository) Create(ctx context.Context, e *model.DataExport) error {
+	return r.db.WithContext(ctx).Create(e).Error
+}
+
+// FindByID finds an export job by ID
+func (r *ExportRepository) FindByID(ctx context.Context, id string) (*model.DataExport, error) {
+	var e model.DataExport
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&e).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &e, nil
+}
+
+// ListForUser lists a user's export jobs, newest first
+func (r *ExportRepository) ListForUser(ctx context.Context, userID string) ([]model.DataExport, error) {
+	var exports []model.DataExport
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&exports).Error; err != nil {
+		return nil, err
+	}
+	return exports, nil
+}
+
+// HasInProgress reports whether the user has an export that hasn't finished
+func (r *ExportRepository) HasInProgress(ctx context.Context, userID string) (bool, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).
+		Model(&model.DataExport{}).
+		Where("user_id = ? AND status IN ?", userID, []string{model.ExportStatusPending, model.ExportStatusRunning}).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// ClaimNext marks the oldest pending export as running and returns it.
+// Jobs left running since before staleBefore are assumed abandoned by a
+// crashed worker and picked up again. SKIP LOCKED lets several instances
+// poll without handing out the same job twice.
+func (r *ExportRepository) ClaimNext(ctx context.Context, staleBefore time.Time) (*model.DataExport, error) {
+	var e model.DataExport
+	if err := r.db.WithContext(ctx).Raw(`
+		UPDATE data_exports SET status = ?, started_at = ?
+		WHERE id = (
+			SELECT id FROM data_exports
+			WHERE status = ? OR (status = ? AND started_at < ?)
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`,
+		model.ExportStatusRunning, time.Now(),
+		model.ExportStatusPending, model.ExportStatusRunning, staleBefore,
+	).Scan(&e).Error; err != nil {
+		return nil, err
+	}
+	if e.ID == "" {
+		return nil, nil
+	}
+	return &e, nil
+}
+
+// MarkReady records a finished export
+func (r *ExportRepository) MarkReady(ctx context.Context, id string, size int64, expiresAt time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&model.DataExport{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":       model.ExportStatusReady,
+			"size":         size,
+			"completed_at": time.Now(),
+			"expires_at":   expiresAt,
+		}).Error
+}
+
+// MarkFailed records an export that could not be built
+func (r *ExportRepository) MarkFailed(ctx context.Context, id, reason string) error {
+	return r.db.WithContext(ctx).
+		Model(&model.DataExport{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":       model.ExportStatusFailed,
+			"error":        reason,
+			"completed_at": time.Now(),
+		}).Error
+}
+
+// DeleteExpired deletes exports whose download window ended, and failed
+// ones older than the cutoff
+func (r *ExportRepository) DeleteExpired(ctx context.Context, at time.Time) error {
+	return r.db.WithContext(ctx).
+		Where("expires_at < ? OR (status = ? AND completed_at < ?)", at, model.ExportStatusFailed, at).
+		Delete(&model.DataExport{}).Error
+}
+
+// ExistingIDs returns which of the given export IDs still exist
+func (r *ExportRepository) ExistingIDs(ctx context.Context, ids []string) (map[string]bool, error) {
+	var found []string
+	if err := r.db.WithContext(ctx).
+		Model(&model.DataExport{}).
+		Where("id IN ?", ids).
+		Pluck("id", &found).Error; err != nil {
+		return nil, err
+	}
+	existing := make(map[string]bool, len(found))
+	for _, id := range found {
+		existing[id] = true
+	}
+	return existing, nil
+}
+
+// Posts returns all of a user's posts with their media, oldest first
+func (r *ExportRepository) Posts(ctx context.Context, userID string) ([]model.Post, error) {
+	var posts []model.Post
+	if err := r.db.WithContext(ctx).
+		Preload("Media").
+		Where("user_id = ?", userID).
+		Order("created_at ASC").
+		Find(&posts).Error; err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
+
+// Likes returns the posts a user liked
+func (r *ExportRepository) Likes(ctx context.Context, userID string) ([]model.Like, error) {
+	var likes []model.Like
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at ASC").
+		Find(&likes).Error; err != nil {
+		return nil, err
+	}
+	return likes, nil
+}
+
+// Reposts returns the posts a user reposted
+func (r *ExportRepository) Reposts(ctx context.Context, userID string) ([]model.Repost, error) {
+	var reposts []model.Repost
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at ASC").
+		Find(&reposts).Error; err != nil {
+		return nil, err
+	}
+	return reposts, nil
+}
+
+// Notifications returns all of a user's notifications
+func (r *ExportRepository) Notifications(ctx context.Context, userID string) ([]model.Notification, error) {
+	var notifications []model.Notification
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at ASC").
+		Find(&notifications).Error; err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
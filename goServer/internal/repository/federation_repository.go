@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"goServer/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type FederationRepository struct {
+	db *gorm.DB
+}
+
+func NewFederationRepository(db *gorm.DB) *FederationRepository {
+	return &FederationRepository{db: db}
+}
+
+// WithTx returns a repository bound to the given transaction
+func (r *FederationRepository) WithTx(tx *gorm.DB) *FederationRepository {
+	return &FederationRepository{db: tx}
+}
+
+// Transaction runs fn inside a database transaction
+func (r *FederationRepository) Transaction(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	return r.db.WithContext(ctx).Transaction(fn)
+}
+
+// CreateIdentity links a provider account to a user
+func (r *FederationRepository) CreateIdentity(ctx context.Context, f *model.FederatedIdentity) error {
+	return r.db.WithContext(ctx).Create(f).Error
+}
+
+// FindIdentity finds the link for a provider subject
+func (r *FederationRepository) FindIdentity(ctx context.Context, provider, subject string) (*model.FederatedIdentity, error) {
+	var f model.FederatedIdentity
+	if err := r.db.WithContext(ctx).
+		Where("provider = ? AND subject = ?", provider, subject).
+		First(&f).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &f, nil
+}
+
+// ListForUser lists the provider accounts linked to a user
+func (r *FederationRepository) ListForUser(ctx context.Context, userID string) ([]model.FederatedIdentity, error) {
+	var identities []model.FederatedIdentity
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at ASC").
+		Find(&identities).Error; err != nil {
+		return nil, err
+	}
+	return identities, nil
+}
+
+// DeleteIdentity unlinks a provider from a user, reporting whether a link existed
+func (r *FederationRepository) DeleteIdentity(ctx context.Context, userID, provider string) (bool, error) {
+	res := r.db.WithContext(ctx).
+		Where("user_id = ? AND provider = ?", userID, provider).
+		Delete(&model.FederatedIdentity{})
+	return res.RowsAffected > 0, res.Error
+}
+
+// TouchLogin records a sign-in through a linked identity
+func (r *FederationRepository) TouchLogin(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).
+		Model(&model.FederatedIdentity{}).
+		Where("id = ?", id).
+		Update("last_login_at", time.Now()).Error
+}
+
+// CreateAuthRequest stores the state of a pending authorization request
+func (r *FederationRepository) CreateAuthRequest(ctx context.Context, a *model.OIDCAuthRequest) error {
+	return r.db.WithContext(ctx).Create(a).Error
+}
+
+// ConsumeAuthRequest deletes and returns a live authorization request, so
+// each state value can complete at most one callback
+func (r *FederationRepository) ConsumeAuthRequest(ctx context.Context, stateHash string) (*model.OIDCAuthRequest, error) {
+	var reqs []model.OIDCAuthRequest
+	if err := r.db.WithContext(ctx).
+		Clauses(clause.Returning{}).
+		Where("state_hash = ? AND expires_at > ?", stateHash, time.Now()).
+		Delete(&reqs).Error; err != nil {
+		return nil, err
+	}
+	if len(reqs) == 0 {
+		return nil, nil
+	}
+	return &reqs[0], nil
+}
+
+// DeleteExpiredAuthRequests removes authorization requests that were never completed
+func (r *FederationRepository) DeleteExpiredAuthRequests(ctx context.Context, before time.Time) error {
+	return r.db.WithContext(ctx).
+		Where("expires_at < ?", before).
+		Delete(&model.OIDCAuthRequest{}).Error
+}
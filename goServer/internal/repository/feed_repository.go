@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Feed candidate sources, in order of preference when a post comes from
+// more than one
+const (
+	FeedSourceFollowee = "followee" // written by an account the viewer follows
+	FeedSourceNetwork  = "network"  // liked or reposted by an account the viewer follows
+	FeedSourceTrending = "trending" // drawing the most engagement right now
+)
+
+// FeedCandidate is a post that may appear in a viewer's ranked feed, with
+// the raw signals used to score it
+type FeedCandidate struct {
+	PostID    string
+	AuthorID  string
+	CreatedAt time.Time
+	Source    string
+	Likes     int64
+	Reposts   int64
+	Replies   int64
+	Affinity  int64 // the viewer's recent likes, reposts and replies on the author's posts
+}
+
+// FeedCandidateQuery bounds the candidate search. Nothing after Until is
+// looked at, so repeating a query with the same Until scores the same posts
+// the same way (short of deletions and undone likes).
+type FeedCandidateQuery struct {
+	Until         time.Time // the moment the feed is ranked as of
+	Since         time.Time // oldest post considered
+	TrendingSince time.Time // engagement window for trending posts
+	AffinitySince time.Time // interaction window for author affinity
+	TrendingLimit int
+	SourceLimit   int // newest posts taken from each of the followee and network sources
+}
+
+const feedCandidatesQuery = `
+WITH followees AS (
+	SELECT followee_id FROM follows WHERE follower_id = @viewer
+),
+sourced AS (
+	(SELECT id, 1 AS rank, '` + FeedSourceFollowee + `' AS source
+	FROM posts WHERE user_id IN (SELECT followee_id FROM followees) AND created_at > @since AND created_at <= @until AND deleted_at IS NULL
+	ORDER BY created_at DESC LIMIT @source_limit)
+	UNION ALL
+	(SELECT post_id, 2, '` + FeedSourceNetwork + `' FROM (
+		SELECT post_id, created_at FROM likes WHERE user_id IN (SELECT followee_id FROM followees) AND created_at > @since AND created_at <= @until
+		UNION ALL
+		SELECT post_id, created_at FROM reposts WHERE user_id IN (SELECT followee_id FROM followees) AND created_at > @since AND created_at <= @until
+	) n GROUP BY post_id ORDER BY max(created_at) DESC LIMIT @source_limit)
+	UNION ALL
+	(SELECT post_id, 3, '` + FeedSourceTrending + `' FROM (
+		SELECT post_id FROM likes WHERE created_at > @trending_since AND created_at <= @until
+		UNION ALL
+		SELECT post_id FROM reposts WHERE created_at > @trending_since AND created_at <= @until
+	) e GROUP BY post_id ORDER BY count(*) DESC, post_id LIMIT @trending_limit)
+),
+candidates AS (
+	SELECT DISTINCT ON (id) id, source FROM sourced ORDER BY id, rank
+),
+affinity AS (
+	SELECT ap.user_id AS author_id, count(*) AS interactions
+	FROM (
+		SELECT post_id FROM likes WHERE user_id = @viewer AND created_at > @affinity_since AND created_at <= @until
+		UNION ALL
+		SELECT post_id FROM reposts WHERE user_id = @viewer AND created_at > @affinity_since AND created_at <= @until
+		UNION ALL
+		SELECT reply_to FROM posts WHERE user_id = @viewer AND reply_to IS NOT NULL AND created_at > @affinity_since AND created_at <= @until
+	) i
+	JOIN posts ap ON ap.id = i.post_id
+	GROUP BY ap.user_id
+)
+SELECT p.id AS post_id, p.user_id AS author_id, p.created_at, c.source,
+	(SELECT count(*) FROM likes l WHERE l.post_id = p.id AND l.created_at <= @until) AS likes,
+	(SELECT count(*) FROM reposts r WHERE r.post_id = p.id AND r.created_at <= @until) AS reposts,
+	(SELECT count(*) FROM posts r WHERE r.reply_to = p.id AND r.deleted_at IS NULL AND r.created_at <= @until) AS replies,
+	COALESCE(a.interactions, 0) AS affinity
+FROM candidates c
+JOIN posts p ON p.id = c.id AND p.deleted_at IS NULL AND p.created_at > @since AND p.created_at <= @until
+LEFT JOIN affinity a ON a.author_id = p.user_id
+WHERE p.user_id <> @viewer
+	AND p.user_id NOT IN (` + blockedSubquery + `)
+	AND p.user_id NOT IN (` + mutedSubquery + `)
+	AND p.user_id NOT IN (` + hiddenAuthorsSubquery + `)`
+
+type FeedRepository struct {
+	db *gorm.DB
+}
+
+func NewFeedRepository(db *gorm.DB) *FeedRepository {
+	return &FeedRepository{db: db}
+}
+
+// Candidates gathers posts for a viewer's ranked feed: posts by followees,
+// posts followees liked or reposted, and trending posts. Each source is
+// limited on its own, so a busy followee list can't crowd the others out
+// before scoring. Blocked, muted and private accounts the viewer can't see
+// are left out.
+func (r *FeedRepository) Candidates(ctx context.Context, viewerID string, q FeedCandidateQuery) ([]FeedCandidate, error) {
+	var candidates []FeedCandidate
+	if err := r.db.WithContext(ctx).Raw(feedCandidatesQuery, map[string]interface{}{
+		"viewer":         viewerID,
+		"until":          q.Until,
+		"since":          q.Since,
+		"trending_since": q.TrendingSince,
+		"affinity_since": q.AffinitySince,
+		"trending_limit": q.TrendingLimit,
+		"source_limit":   q.SourceLimit,
+	}).Scan(&candidates).Error; err != nil {
+		return nil, err
+	}
+	return candidates, nil
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+
+	"goServer/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// hiddenAuthorsSubquery selects private accounts whose posts the viewer may
+// not see: everyone private except the viewer and the accounts they follow
+const hiddenAuthorsSubquery = "SELECT id FROM users WHERE is_private AND id <> @viewer AND id NOT IN (SELECT followee_id FROM follows WHERE follower_id = @viewer)"
+
+// excludePrivate drops rows whose user column points at a private account
+// the viewer doesn't follow. Anonymous viewers see no private posts.
+func excludePrivate(db *gorm.DB, column, viewerID string) *gorm.DB {
+	if viewerID == "" {
+		return db.Where(column + " NOT IN (SELECT id FROM users WHERE is_private)")
+	}
+	return db.Where(column+" NOT IN ("+hiddenAuthorsSubquery+")", map[string]interface{}{"viewer": viewerID})
+}
+
+type FollowRequestRepository struct {
+	db *gorm.DB
+}
+
+func NewFollowRequestRepository(db *gorm.DB) *FollowRequestRepository {
+	return &FollowRequestRepository{db: db}
+}
+
+// Create records a follow request, doing nothing if one is already pending
+func (r *FollowRequestRepository) Create(ctx context.Context, requesterID, targetID string) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&model.FollowRequest{RequesterID: requesterID, TargetID: targetID}).Error
+}
+
+// Exists reports whether requesterID has a pending request to follow targetID
+func (r *FollowRequestRepository) Exists(ctx context.Context, requesterID, targetID string) (bool, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).
+		Model(&model.FollowRequest{}).
+		Where("requester_id = ? AND target_id = ?", requesterID, targetID).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// Delete removes a pending request, reporting whether there was one
+func (r *FollowRequestRepository) Delete(ctx context.Context, requesterID, targetID string) (bool, error) {
+	res := r.db.WithContext(ctx).
+		Where("requester_id = ? AND target_id = ?", requesterID, targetID).
+		Delete(&model.FollowRequest{})
+	return res.RowsAffected > 0, res.Error
+}
+
+// Approve turns a pending request into a follow, reporting whether there was
+// a request to approve
+func (r *FollowRequestRepository) Approve(ctx context.Context, requesterID, targetID string) (bool, error) {
+	approved := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("requester_id = ? AND target_id = ?", requesterID, targetID).
+			Delete(&model.FollowRequest{})
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		approved = true
+		return tx.Exec("INSERT INTO follows (follower_id, followee_id) VALUES (?, ?) ON CONFLICT DO NOTHING",
+			requesterID, targetID).Error
+	})
+	return approved, err
+}
+
+// ApproveAll turns every request pending for targetID into a follow, e.g.
+// when the account is made public
+func (r *FollowRequestRepository) ApproveAll(ctx context.Context, targetID string) (int64, error) {
+	var approved int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`INSERT INTO follows (follower_id, followee_id)
+			SELECT requester_id, target_id FROM follow_requests WHERE target_id = ?
+			ON CONFLICT DO NOTHING`, targetID).Error; err != nil {
+			return err
+		}
+		res := tx.Where("target_id = ?", targetID).Delete(&model.FollowRequest{})
+		approved = res.RowsAffected
+		return res.Error
+	})
+	return approved, err
+}
+
+// ListIncoming lists the requests waiting on targetID, oldest first
+func (r *FollowRequestRepository) ListIncoming(ctx context.Context, targetID string, limit, offset int) ([]model.FollowRequest, error) {
+	var requests []model.FollowRequest
+	if err := r.db.WithContext(ctx).
+		Preload("Requester").
+		Where("target_id = ?", targetID).
+		Order("created_at ASC").
+		Limit(limit).
+		Offset(offset).
+		Find(&requests).Error; err != nil {
+		return nil, err
+	}
+	return requests, nil
+}
+
+// ListOutgoing lists the requests requesterID is waiting on, newest first
+func (r *FollowRequestRepository) ListOutgoing(ctx context.Context, requesterID string, limit, offset int) ([]model.FollowRequest, error) {
+	var requests []model.FollowRequest
+	if err := r.db.WithContext(ctx).
+		Preload("Target").
+		Where("requester_id = ?", requesterID).
+		Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&requests).Error; err != nil {
+		return nil, err
+	}
+	return requests, nil
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"goServer/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type LoginThrottleRepository struct {
+	db *gorm.DB
+}
+
+func NewLoginThrottleRepository(db *gorm.DB) *LoginThrottleRepository {
+	return &LoginThrottleRepository{db: db}
+}
+
+// FindLocked returns the IP and username throttles that are locked at the given time
+func (r *LoginThrottleRepository) FindLocked(ctx context.Context, ip, username string, at time.Time) ([]model.LoginThrottle, error) {
+	var throttles []model.LoginThrottle
+	if err := r.db.WithContext(ctx).
+		Where("((scope = ? AND key = ?) OR (scope = ? AND key = ?)) AND locked_until > ?",
+			model.LoginThrottleScopeIP, ip, model.LoginThrottleScopeUsername, username, at).
+		Find(&throttles).Error; err != nil {
+		return nil, err
+	}
+	return throttles, nil
+}
+
+// RecordFailure counts a failed attempt and returns the updated row. The
+// count restarts when the previous failure is older than windowStart.
+func (r *LoginThrottleRepository) RecordFailure(ctx context.Context, scope, key string, windowStart time.Time) (*model.LoginThrottle, error) {
+	t := &model.LoginThrottle{
+		Scope:         scope,
+		Key:           key,
+		Failures:      1,
+		LastFailureAt: time.Now(),
+	}
+
+	err := r.db.WithContext(ctx).
+		Clauses(
+			clause.OnConflict{
+				Columns: []clause.Column{{Name: "scope"}, {Name: "key"}},
+				DoUpdates: clause.Assignments(map[string]interface{}{
+					"failures": gorm.Expr(
+						"CASE WHEN login_throttles.last_failure_at < ? THEN 1 ELSE login_throttles.failures + 1 END",
+						windowStart,
+					),
+					"last_failure_at": t.LastFailureAt,
+				}),
+			},
+			clause.Returning{},
+		).
+		Create(t).Error
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Lock blocks logins for a throttle key until the given time
+func (r *LoginThrottleRepository) Lock(ctx context.Context, scope, key string, until time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&model.LoginThrottle{}).
+		Where("scope = ? AND key = ?", scope, key).
+		Update("locked_until", until).Error
+}
+
+// Reset clears the failure count for a key
+func (r *LoginThrottleRepository) Reset(ctx context.Context, scope, key string) error {
+	return r.db.WithContext(ctx).
+		Where("scope = ? AND key = ?", scope, key).
+		Delete(&model.LoginThrottle{}).Error
+}
+
+// DeleteStale deletes throttles with no recent failures and no active lock
+func (r *LoginThrottleRepository) DeleteStale(ctx context.Context, before time.Time) error {
+	return r.db.WithContext(ctx).
+		Where("last_failure_at < ? AND (locked_until IS NULL OR locked_until < ?)", before, time.Now()).
+		Delete(&model.LoginThrottle{}).Error
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"errors"
+	"time"
 
 	"goServer/internal/model"
 
@@ -46,10 +47,17 @@ func (r *NotificationRepository) Delete(ctx context.Context, id string) error {
 	return r.db.WithContext(ctx).Where("id = ?", id).Delete(&model.Notification{}).Error
 }
 
+// fromVisibleActors leaves out notifications caused by users the recipient
+// blocked, was blocked by, or muted
+func fromVisibleActors(db *gorm.DB, userID string) *gorm.DB {
+	return db.Where("actor_id IS NULL OR actor_id NOT IN ("+blockedSubquery+" UNION "+mutedSubquery+")",
+		map[string]interface{}{"viewer": userID})
+}
+
 // GetByUserID gets all notifications for a user
 func (r *NotificationRepository) GetByUserID(ctx context.Context, userID string, limit, offset int) ([]model.Notification, error) {
 	var notifications []model.Notification
-	if err := r.db.WithContext(ctx).
+	if err := fromVisibleActors(r.db.WithContext(ctx), userID).
 		Where("user_id = ?", userID).
 		Order("created_at DESC").
 		Limit(limit).
@@ -63,7 +71,7 @@ func (r *NotificationRepository) GetByUserID(ctx context.Context, userID string,
 // GetUnreadCount gets count of unread notifications for a user
 func (r *NotificationRepository) GetUnreadCount(ctx context.Context, userID string) (int64, error) {
 	var count int64
-	if err := r.db.WithContext(ctx).
+	if err := fromVisibleActors(r.db.WithContext(ctx), userID).
 		Model(&model.Notification{}).
 		Where("user_id = ? AND read = false", userID).
 		Count(&count).Error; err != nil {
@@ -95,10 +103,19 @@ func (r *NotificationRepository) DeleteByUserID(ctx context.Context, userID stri
 		Delete(&model.Notification{}).Error
 }
 
+// PurgeDeleted hard-deletes notifications soft-deleted before the cutoff
+func (r *NotificationRepository) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	res := r.db.WithContext(ctx).
+		Unscoped().
+		Where("deleted_at < ?", before).
+		Delete(&model.Notification{})
+	return res.RowsAffected, res.Error
+}
+
 // GetByType gets notifications of a specific type for a user
 func (r *NotificationRepository) GetByType(ctx context.Context, userID, notificationType string, limit, offset int) ([]model.Notification, error) {
 	var notifications []model.Notification
-	if err := r.db.WithContext(ctx).
+	if err := fromVisibleActors(r.db.WithContext(ctx), userID).
 		Where("user_id = ? AND type = ?", userID, notificationType).
 		Order("created_at DESC").
 		Limit(limit).
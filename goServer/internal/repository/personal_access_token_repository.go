@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"goServer/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type PersonalAccessTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewPersonalAccessTokenRepository(db *gorm.DB) *PersonalAccessTokenRepository {
+	return &PersonalAccessTokenRepository{db: db}
+}
+
+// Create creates a new token
+func (r *PersonalAccessTokenRepository) Create(ctx context.Context, t *model.PersonalAccessToken) error {
+	return r.db.WithContext(ctx).Create(t).Error
+}
+
+// FindActiveByHash finds an unrevoked, unexpired token by its hash
+func (r *PersonalAccessTokenRepository) FindActiveByHash(ctx context.Context, tokenHash string) (*model.PersonalAccessToken, error) {
+	var t model.PersonalAccessToken
+	if err := r.db.WithContext(ctx).
+		Preload("User").
+		Where("token_hash = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", tokenHash, time.Now()).
+		First(&t).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+// ListForUser lists a user's tokens, newest first, including revoked and expired ones
+func (r *PersonalAccessTokenRepository) ListForUser(ctx context.Context, userID string) ([]model.PersonalAccessToken, error) {
+	var tokens []model.PersonalAccessToken
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// CountActive counts a user's tokens that are still usable
+func (r *PersonalAccessTokenRepository) CountActive(ctx context.Context, userID string) (int64, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).
+		Model(&model.PersonalAccessToken{}).
+		Where("user_id = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", userID, time.Now()).
+		Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Revoke revokes one of a user's tokens, reporting whether it was active
+func (r *PersonalAccessTokenRepository) Revoke(ctx context.Context, userID, id string) (bool, error) {
+	res := r.db.WithContext(ctx).
+		Model(&model.PersonalAccessToken{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", time.Now())
+	return res.RowsAffected > 0, res.Error
+}
+
+// TouchLastUsed records a use of the token. It writes at most once per
+// interval so busy bots don't turn every request into an UPDATE.
+func (r *PersonalAccessTokenRepository) TouchLastUsed(ctx context.Context, id, ip string, interval time.Duration) error {
+	now := time.Now()
+	return r.db.WithContext(ctx).
+		Model(&model.PersonalAccessToken{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", id, now.Add(-interval)).
+		Updates(map[string]interface{}{
+			"last_used_at": now,
+			"last_used_ip": ip,
+		}).Error
+}
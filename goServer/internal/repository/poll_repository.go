@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"goServer/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PollRepository stores polls' votes and reads their results
+type PollRepository struct {
+	db *gorm.DB
+}
+
+func NewPollRepository(db *gorm.DB) *PollRepository {
+	return &PollRepository{db: db}
+}
+
+// WithTx returns a repository that runs its queries in tx
+func (r *PollRepository) WithTx(tx *gorm.DB) *PollRepository {
+	return &PollRepository{db: tx}
+}
+
+// Transaction runs fn in a database transaction
+func (r *PollRepository) Transaction(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	return r.db.WithContext(ctx).Transaction(fn)
+}
+
+// FindByPostID finds the poll attached to a post, with its options in order
+func (r *PollRepository) FindByPostID(ctx context.Context, postID string) (*model.Poll, error) {
+	polls, err := r.FindByPostIDs(ctx, []string{postID})
+	if err != nil || len(polls) == 0 {
+		return nil, err
+	}
+	return &polls[0], nil
+}
+
+// FindByPostIDs finds the polls attached to any of postIDs, with their
+// options in order
+func (r *PollRepository) FindByPostIDs(ctx context.Context, postIDs []string) ([]model.Poll, error) {
+	if len(postIDs) == 0 {
+		return nil, nil
+	}
+
+	var polls []model.Poll
+	if err := r.db.WithContext(ctx).
+		Preload("Options", func(db *gorm.DB) *gorm.DB { return db.Order("position ASC") }).
+		Where("post_id IN ?", postIDs).
+		Find(&polls).Error; err != nil {
+		return nil, err
+	}
+	return polls, nil
+}
+
+// Vote records a user's vote, reporting false if they had already voted in
+// the poll
+func (r *PollRepository) Vote(ctx context.Context, v *model.PollVote) (bool, error) {
+	res := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(v)
+	return res.RowsAffected > 0, res.Error
+}
+
+// VotedOptions returns, for each of pollIDs the user voted in, the option
+// they chose
+func (r *PollRepository) VotedOptions(ctx context.Context, userID string, pollIDs []string) (map[string]string, error) {
+	voted := make(map[string]string)
+	if userID == "" || len(pollIDs) == 0 {
+		return voted, nil
+	}
+
+	var votes []model.PollVote
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ? AND poll_id IN ?", userID, pollIDs).
+		Find(&votes).Error; err != nil {
+		return nil, err
+	}
+	for _, v := range votes {
+		voted[v.PollID] = v.OptionID
+	}
+	return voted, nil
+}
+
+// Counts returns the number of votes for each option of pollIDs. Options
+// nobody voted for are missing.
+func (r *PollRepository) Counts(ctx context.Context, pollIDs []string) (map[string]int64, error) {
+	counts := make(map[string]int64)
+	if len(pollIDs) == 0 {
+		return counts, nil
+	}
+
+	var rows []struct {
+		OptionID string
+		Count    int64
+	}
+	if err := r.db.WithContext(ctx).
+		Model(&model.PollVote{}).
+		Select("option_id, COUNT(*) AS count").
+		Where("poll_id IN ?", pollIDs).
+		Group("option_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		counts[row.OptionID] = row.Count
+	}
+	return counts, nil
+}
+
+// ClaimClosed marks the poll that closed longest ago without its results
+// being sent as sent, and returns it with its options, or nil if there is
+// none. Call it in a transaction: SKIP LOCKED lets several instances poll
+// without sending the same results twice.
+func (r *PollRepository) ClaimClosed(ctx context.Context, at time.Time) (*model.Poll, error) {
+	var poll model.Poll
+	if err := r.db.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("closes_at <= ? AND results_sent_at IS NULL", at).
+		Order("closes_at ASC").
+		First(&poll).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if err := r.db.WithContext(ctx).
+		Model(&model.Poll{}).
+		Where("id = ?", poll.ID).
+		Update("results_sent_at", at).Error; err != nil {
+		return nil, err
+	}
+
+	if err := r.db.WithContext(ctx).
+		Where("poll_id = ?", poll.ID).
+		Order("position ASC").
+		Find(&poll.Options).Error; err != nil {
+		return nil, err
+	}
+	return &poll, nil
+}
+
+// VoterIDs returns the users who voted in a poll
+func (r *PollRepository) VoterIDs(ctx context.Context, pollID string) ([]string, error) {
+	var ids []string
+	if err := r.db.WithContext(ctx).
+		Model(&model.PollVote{}).
+		Where("poll_id = ?", pollID).
+		Pluck("user_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
@@ -3,10 +3,12 @@ package repository
 import (
 	"context"
 	"errors"
+	"time"
 
 	"goServer/internal/model"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type PostRepository struct {
@@ -17,6 +19,16 @@ func NewPostRepository(db *gorm.DB) *PostRepository {
 	return &PostRepository{db: db}
 }
 
+// WithTx returns a repository bound to the given transaction
+func (r *PostRepository) WithTx(tx *gorm.DB) *PostRepository {
+	return &PostRepository{db: tx}
+}
+
+// Transaction runs fn in a database transaction
+func (r *PostRepository) Transaction(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	return r.db.WithContext(ctx).Transaction(fn)
+}
+
 // Create creates a new post
 func (r *PostRepository) Create(ctx context.Context, p *model.Post) error {
 	return r.db.WithContext(ctx).Create(p).Error
@@ -38,23 +50,211 @@ func (r *PostRepository) FindByID(ctx context.Context, id string) (*model.Post,
 	return &p, nil
 }
 
+// FindByIDs finds several posts by ID, in no particular order
+func (r *PostRepository) FindByIDs(ctx context.Context, ids []string) ([]model.Post, error) {
+	var posts []model.Post
+	if len(ids) == 0 {
+		return posts, nil
+	}
+	if err := r.db.WithContext(ctx).
+		Preload("User").
+		Preload("Media").
+		Where("id IN ?", ids).
+		Find(&posts).Error; err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
+
+// FindByIDWithDeleted finds a post by ID including soft-deleted ones
+func (r *PostRepository) FindByIDWithDeleted(ctx context.Context, id string) (*model.Post, error) {
+	var p model.Post
+	if err := r.db.WithContext(ctx).
+		Unscoped().
+		Preload("User").
+		Preload("Media").
+		Where("id = ?", id).
+		First(&p).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &p, nil
+}
+
 // Update updates a post
 func (r *PostRepository) Update(ctx context.Context, p *model.Post) error {
 	return r.db.WithContext(ctx).Model(p).Updates(p).Error
 }
 
-// Delete deletes a post
+// Revise replaces a post's text, keeping the text it had as a revision.
+// It only applies while the post has fewer than maxEdits edits, was created
+// after editableSince and hasn't been edited since it was loaded; otherwise
+// it reports false and changes nothing. On success post is updated in place.
+func (r *PostRepository) Revise(ctx context.Context, post *model.Post, text string, maxEdits int, editableSince time.Time) (bool, error) {
+	now := time.Now()
+	res := r.db.WithContext(ctx).Model(&model.Post{}).
+		Where("id = ? AND edit_count = ? AND edit_count < ? AND created_at > ?", post.ID, post.EditCount, maxEdits, editableSince).
+		Updates(map[string]interface{}{
+			"text":       text,
+			"char_count": len(text),
+			"edited_at":  now,
+			"edit_count": gorm.Expr("edit_count + 1"),
+		})
+	if res.Error != nil || res.RowsAffected == 0 {
+		return false, res.Error
+	}
+
+	writtenAt := post.CreatedAt
+	if post.EditedAt != nil {
+		writtenAt = *post.EditedAt
+	}
+	if err := r.db.WithContext(ctx).Create(&model.PostRevision{
+		PostID:    post.ID,
+		Version:   post.EditCount,
+		Text:      post.Text,
+		CharCount: post.CharCount,
+		WrittenAt: writtenAt,
+	}).Error; err != nil {
+		return false, err
+	}
+
+	post.Text = text
+	post.CharCount = len(text)
+	post.EditedAt = &now
+	post.EditCount++
+	return true, nil
+}
+
+// GetRevisions gets a post's prior versions, oldest first
+func (r *PostRepository) GetRevisions(ctx context.Context, postID string) ([]model.PostRevision, error) {
+	var revisions []model.PostRevision
+	if err := r.db.WithContext(ctx).
+		Where("post_id = ?", postID).
+		Order("version ASC").
+		Find(&revisions).Error; err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+// SetTags replaces a post's mentions and hashtags, returning the users
+// who weren't mentioned in it before
+func (r *PostRepository) SetTags(ctx context.Context, postID string, mentionedIDs, tags []string) ([]string, error) {
+	db := r.db.WithContext(ctx)
+
+	var existing []string
+	if err := db.Model(&model.Mention{}).Where("post_id = ?", postID).
+		Pluck("mentioned_user_id", &existing).Error; err != nil {
+		return nil, err
+	}
+	had := make(map[string]bool, len(existing))
+	for _, id := range existing {
+		had[id] = true
+	}
+
+	stale := db.Where("post_id = ?", postID)
+	if len(mentionedIDs) > 0 {
+		stale = stale.Where("mentioned_user_id NOT IN ?", mentionedIDs)
+	}
+	if err := stale.Delete(&model.Mention{}).Error; err != nil {
+		return nil, err
+	}
+
+	var added []string
+	for _, id := range mentionedIDs {
+		if had[id] {
+			continue
+		}
+		if err := db.Create(&model.Mention{PostID: postID, MentionedUserID: id}).Error; err != nil {
+			return nil, err
+		}
+		added = append(added, id)
+	}
+
+	if err := db.Where("post_id = ?", postID).Delete(&model.PostHashtag{}).Error; err != nil {
+		return nil, err
+	}
+	if len(tags) > 0 {
+		hashtags := make([]model.PostHashtag, len(tags))
+		for i, tag := range tags {
+			hashtags[i] = model.PostHashtag{PostID: postID, Tag: tag}
+		}
+		if err := db.Create(&hashtags).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return added, nil
+}
+
+// Delete soft-deletes a post and its media; likes and replies are kept so threads stay intact
 func (r *PostRepository) Delete(ctx context.Context, id string) error {
-	return r.db.WithContext(ctx).Where("id = ?", id).Delete(&model.Post{}).Error
+	now := time.Now()
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Media{}).
+			Where("post_id = ?", id).
+			Update("deleted_at", now).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("post_id = ?", id).Delete(&model.PinnedPost{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.Post{}).
+			Where("id = ?", id).
+			Update("deleted_at", now).Error
+	})
 }
 
-// GetUserPosts gets all posts from a user
+// Restore undoes a soft delete, bringing back media that was deleted together with the post
+func (r *PostRepository) Restore(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var p model.Post
+		if err := tx.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(&p).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&model.Media{}).
+			Where("post_id = ? AND deleted_at = ?", id, p.DeletedAt.Time).
+			Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Model(&model.Post{}).
+			Where("id = ?", id).
+			Update("deleted_at", nil).Error
+	})
+}
+
+// PurgeDeleted hard-deletes posts and media soft-deleted before the cutoff.
+// Posts that still have live replies or quotes are kept as tombstones so
+// threads and quotes don't break.
+func (r *PostRepository) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	var purged int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().
+			Where("deleted_at < ?", before).
+			Delete(&model.Media{}).Error; err != nil {
+			return err
+		}
+
+		res := tx.Unscoped().
+			Where("deleted_at < ?", before).
+			Where("NOT EXISTS (SELECT 1 FROM posts r WHERE (r.reply_to = posts.id OR r.quoted_tweet_id = posts.id) AND r.deleted_at IS NULL)").
+			Delete(&model.Post{})
+		purged = res.RowsAffected
+		return res.Error
+	})
+	return purged, err
+}
+
+// GetUserPosts gets all posts from a user except the ones they pinned
 func (r *PostRepository) GetUserPosts(ctx context.Context, userID string, limit, offset int) ([]model.Post, error) {
 	var posts []model.Post
 	if err := r.db.WithContext(ctx).
 		Preload("User").
 		Preload("Media").
 		Where("user_id = ?", userID).
+		Where("id NOT IN (SELECT post_id FROM pinned_posts WHERE user_id = ?)", userID).
 		Order("created_at DESC").
 		Limit(limit).
 		Offset(offset).
@@ -64,13 +264,70 @@ func (r *PostRepository) GetUserPosts(ctx context.Context, userID string, limit,
 	return posts, nil
 }
 
-// GetFeed gets posts from users that the current user follows
-func (r *PostRepository) GetFeed(ctx context.Context, userID string, limit, offset int) ([]model.Post, error) {
+// GetPinnedPosts gets the posts a user pinned, most recently pinned first
+func (r *PostRepository) GetPinnedPosts(ctx context.Context, userID string) ([]model.Post, error) {
 	var posts []model.Post
 	if err := r.db.WithContext(ctx).
 		Preload("User").
 		Preload("Media").
-		Where("user_id IN (SELECT followee_id FROM follows WHERE follower_id = ?)", userID).
+		Joins("JOIN pinned_posts ON pinned_posts.post_id = posts.id").
+		Where("pinned_posts.user_id = ?", userID).
+		Order("pinned_posts.created_at DESC").
+		Find(&posts).Error; err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
+
+// Pin pins a post to a user's profile, reporting false if they already
+// have max other posts pinned. Pinning a post again is not an error. The
+// user's row stays locked until it's done so concurrent pins can't go over
+// the limit.
+func (r *PostRepository) Pin(ctx context.Context, userID, postID string, max int) (bool, error) {
+	pinned := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id").
+			Where("id = ?", userID).
+			First(&model.User{}).Error; err != nil {
+			return err
+		}
+
+		var count int64
+		if err := tx.Model(&model.PinnedPost{}).
+			Where("user_id = ? AND post_id <> ?", userID, postID).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count >= int64(max) {
+			return nil
+		}
+
+		pinned = true
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&model.PinnedPost{UserID: userID, PostID: postID}).Error
+	})
+	return pinned, err
+}
+
+// Unpin unpins a post from a user's profile
+func (r *PostRepository) Unpin(ctx context.Context, userID, postID string) error {
+	return r.db.WithContext(ctx).
+		Where("user_id = ? AND post_id = ?", userID, postID).
+		Delete(&model.PinnedPost{}).Error
+}
+
+// GetFeed gets posts from users that the current user follows, leaving out
+// blocked and muted accounts
+func (r *PostRepository) GetFeed(ctx context.Context, userID string, limit, offset int) ([]model.Post, error) {
+	var posts []model.Post
+	db := r.db.WithContext(ctx).
+		Preload("User").
+		Preload("Media").
+		Where("user_id IN (SELECT followee_id FROM follows WHERE follower_id = ?)", userID)
+	db = excludeBlocked(db, "posts.user_id", userID)
+	db = excludeMuted(db, "posts.user_id", userID)
+	if err := db.
 		Order("created_at DESC").
 		Limit(limit).
 		Offset(offset).
@@ -80,6 +337,11 @@ func (r *PostRepository) GetFeed(ctx context.Context, userID string, limit, offs
 	return posts, nil
 }
 
+// CreatePoll creates a poll with its options
+func (r *PostRepository) CreatePoll(ctx context.Context, p *model.Poll) error {
+	return r.db.WithContext(ctx).Create(p).Error
+}
+
 // AddMedia adds media to a post
 func (r *PostRepository) AddMedia(ctx context.Context, m *model.Media) error {
 	return r.db.WithContext(ctx).Create(m).Error
@@ -125,12 +387,15 @@ func (r *PostRepository) GetLikeCount(ctx context.Context, postID string) (int64
 	return count, nil
 }
 
-// GetPostLikes gets all users who liked a post
-func (r *PostRepository) GetPostLikes(ctx context.Context, postID string, limit, offset int) ([]model.User, error) {
+// GetPostLikes gets all users who liked a post, leaving out users on
+// either side of a block with the viewer
+func (r *PostRepository) GetPostLikes(ctx context.Context, postID, viewerID string, limit, offset int) ([]model.User, error) {
 	var users []model.User
-	if err := r.db.WithContext(ctx).
+	db := r.db.WithContext(ctx).
 		Joins("JOIN likes ON users.id = likes.user_id").
-		Where("likes.post_id = ?", postID).
+		Where("likes.post_id = ?", postID)
+	db = excludeBlocked(db, "users.id", viewerID)
+	if err := db.
 		Limit(limit).
 		Offset(offset).
 		Find(&users).Error; err != nil {
@@ -179,12 +444,15 @@ func (r *PostRepository) GetRepostCount(ctx context.Context, postID string) (int
 	return count, nil
 }
 
-// GetPostReposts gets all users who reposted a post
-func (r *PostRepository) GetPostReposts(ctx context.Context, postID string, limit, offset int) ([]model.User, error) {
+// GetPostReposts gets all users who reposted a post, leaving out users on
+// either side of a block with the viewer
+func (r *PostRepository) GetPostReposts(ctx context.Context, postID, viewerID string, limit, offset int) ([]model.User, error) {
 	var users []model.User
-	if err := r.db.WithContext(ctx).
+	db := r.db.WithContext(ctx).
 		Joins("JOIN reposts ON users.id = reposts.user_id").
-		Where("reposts.post_id = ?", postID).
+		Where("reposts.post_id = ?", postID)
+	db = excludeBlocked(db, "users.id", viewerID)
+	if err := db.
 		Limit(limit).
 		Offset(offset).
 		Find(&users).Error; err != nil {
@@ -193,13 +461,21 @@ func (r *PostRepository) GetPostReposts(ctx context.Context, postID string, limi
 	return users, nil
 }
 
-// GetReplies gets all replies to a post
-func (r *PostRepository) GetReplies(ctx context.Context, postID string, limit, offset int) ([]model.Post, error) {
+// GetReplies gets all replies to a post. Deleted replies that still have
+// live replies of their own are included so they can be shown as tombstones.
+// Replies by users on either side of a block with the viewer, and by private
+// accounts the viewer doesn't follow, are left out.
+func (r *PostRepository) GetReplies(ctx context.Context, postID, viewerID string, limit, offset int) ([]model.Post, error) {
 	var posts []model.Post
-	if err := r.db.WithContext(ctx).
+	db := r.db.WithContext(ctx).
+		Unscoped().
 		Preload("User").
 		Preload("Media").
 		Where("reply_to = ?", postID).
+		Where("deleted_at IS NULL OR EXISTS (SELECT 1 FROM posts r WHERE r.reply_to = posts.id AND r.deleted_at IS NULL)")
+	db = excludeBlocked(db, "posts.user_id", viewerID)
+	db = excludePrivate(db, "posts.user_id", viewerID)
+	if err := db.
 		Order("created_at DESC").
 		Limit(limit).
 		Offset(offset).
@@ -221,13 +497,17 @@ func (r *PostRepository) GetReplyCount(ctx context.Context, postID string) (int6
 	return count, nil
 }
 
-// SearchPosts searches for posts by text
-func (r *PostRepository) SearchPosts(ctx context.Context, query string, limit, offset int) ([]model.Post, error) {
+// SearchPosts searches for posts by text, leaving out users on either side
+// of a block with the viewer and private accounts the viewer doesn't follow
+func (r *PostRepository) SearchPosts(ctx context.Context, query, viewerID string, limit, offset int) ([]model.Post, error) {
 	var posts []model.Post
-	if err := r.db.WithContext(ctx).
+	db := r.db.WithContext(ctx).
 		Preload("User").
 		Preload("Media").
-		Where("text ILIKE ?", "%"+query+"%").
+		Where("text ILIKE ?", "%"+query+"%")
+	db = excludeBlocked(db, "posts.user_id", viewerID)
+	db = excludePrivate(db, "posts.user_id", viewerID)
+	if err := db.
 		Order("created_at DESC").
 		Limit(limit).
 		Offset(offset).
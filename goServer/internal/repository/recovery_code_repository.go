@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"goServer/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type RecoveryCodeRepository struct {
+	db *gorm.DB
+}
+
+func NewRecoveryCodeRepository(db *gorm.DB) *RecoveryCodeRepository {
+	return &RecoveryCodeRepository{db: db}
+}
+
+// WithTx returns a repository bound to the given transaction
+func (r *RecoveryCodeRepository) WithTx(tx *gorm.DB) *RecoveryCodeRepository {
+	return &RecoveryCodeRepository{db: tx}
+}
+
+// Transaction runs fn inside a database transaction
+func (r *RecoveryCodeRepository) Transaction(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	return r.db.WithContext(ctx).Transaction(fn)
+}
+
+// Replace deletes a user's recovery codes and stores a new set of hashes
+func (r *RecoveryCodeRepository) Replace(ctx context.Context, userID string, hashes []string) error {
+	if err := r.DeleteForUser(ctx, userID); err != nil {
+		return err
+	}
+
+	codes := make([]model.RecoveryCode, 0, len(hashes))
+	for _, h := range hashes {
+		codes = append(codes, model.RecoveryCode{UserID: userID, CodeHash: h})
+	}
+	return r.db.WithContext(ctx).Create(&codes).Error
+}
+
+// Consume atomically marks an unused code as used. It reports false when
+// the code doesn't exist or was already used.
+func (r *RecoveryCodeRepository) Consume(ctx context.Context, userID, codeHash string) (bool, error) {
+	res := r.db.WithContext(ctx).
+		Model(&model.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	return res.RowsAffected > 0, res.Error
+}
+
+// CountUnused returns how many recovery codes the user has left
+func (r *RecoveryCodeRepository) CountUnused(ctx context.Context, userID string) (int64, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).
+		Model(&model.RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// DeleteForUser removes all of a user's recovery codes
+func (r *RecoveryCodeRepository) DeleteForUser(ctx context.Context, userID string) error {
+	return r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Delete(&model.RecoveryCode{}).Error
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"goServer/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type SessionRepository struct {
+	db *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+// activeSessions limits a query on sessions to ones that can still be used:
+// not revoked, not expired, and not outlived by a password change
+func activeSessions(db *gorm.DB, at time.Time) *gorm.DB {
+	return db.Joins("JOIN users ON users.id = sessions.user_id").
+		Where("sessions.revoked_at IS NULL AND sessions.expires_at > ? AND sessions.token_version = users.token_version AND users.deleted_at IS NULL", at)
+}
+
+// Create creates a new session
+func (r *SessionRepository) Create(ctx context.Context, s *model.Session) error {
+	return r.db.WithContext(ctx).Create(s).Error
+}
+
+// FindActive finds a user's session if it is still usable
+func (r *SessionRepository) FindActive(ctx context.Context, userID, id string) (*model.Session, error) {
+	var s model.Session
+	if err := activeSessions(r.db.WithContext(ctx), time.Now()).
+		Where("sessions.id = ? AND sessions.user_id = ?", id, userID).
+		First(&s).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &s, nil
+}
+
+// ListActive lists a user's usable sessions, most recently seen first
+func (r *SessionRepository) ListActive(ctx context.Context, userID string) ([]model.Session, error) {
+	var sessions []model.Session
+	if err := activeSessions(r.db.WithContext(ctx), time.Now()).
+		Where("sessions.user_id = ?", userID).
+		Order("sessions.last_seen_at DESC").
+		Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// HasDevice reports whether the user has had a session on the device
+// before, and whether they have had any session at all
+func (r *SessionRepository) HasDevice(ctx context.Context, userID, deviceHash string) (known bool, seen bool, err error) {
+	var row struct {
+		Known bool
+		Seen  bool
+	}
+	if err := r.db.WithContext(ctx).
+		Model(&model.Session{}).
+		Select("COALESCE(bool_or(device_hash = ?), false) AS known, count(*) > 0 AS seen", deviceHash).
+		Where("user_id = ?", userID).
+		Scan(&row).Error; err != nil {
+		return false, false, err
+	}
+	return row.Known, row.Seen, nil
+}
+
+// Touch records activity on a session. It writes at most once per interval.
+func (r *SessionRepository) Touch(ctx context.Context, id, ip string, interval time.Duration) error {
+	now := time.Now()
+	return r.db.WithContext(ctx).
+		Model(&model.Session{}).
+		Where("id = ? AND last_seen_at < ?", id, now.Add(-interval)).
+		Updates(map[string]interface{}{
+			"last_seen_at": now,
+			"ip":           ip,
+		}).Error
+}
+
+// Revoke revokes one of a user's sessions, reporting whether it was active
+func (r *SessionRepository) Revoke(ctx context.Context, userID, id string) (bool, error) {
+	res := r.db.WithContext(ctx).
+		Model(&model.Session{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", time.Now())
+	return res.RowsAffected > 0, res.Error
+}
+
+// RevokeAllExcept revokes every session of the user except one
+func (r *SessionRepository) RevokeAllExcept(ctx context.Context, userID, keepID string) (int64, error) {
+	res := r.db.WithContext(ctx).
+		Model(&model.Session{}).
+		Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userID, keepID).
+		Update("revoked_at", time.Now())
+	return res.RowsAffected, res.Error
+}
+
+// DeleteEnded deletes sessions that expired or were revoked before the given time
+func (r *SessionRepository) DeleteEnded(ctx context.Context, before time.Time) error {
+	return r.db.WithContext(ctx).
+		Where("expires_at < ? OR revoked_at < ?", before, before).
+		Delete(&model.Session{}).Error
+}
@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type StatsRepository struct {
+	db *gorm.DB
+}
+
+func NewStatsRepository(db *gorm.DB) *StatsRepository {
+	return &StatsRepository{db: db}
+}
+
+// SystemTotals holds the raw counters behind the admin stats endpoint
+type SystemTotals struct {
+	TotalUsers    int64
+	TotalPosts    int64
+	TotalLikes    int64
+	TotalReposts  int64
+	TotalReplies  int64
+	ActiveUsers   int64
+	PostsLastHour int64
+	PostsLastDay  int64
+	PostsLastWeek int64
+}
+
+// SeriesPoint is one bucket of a time-series breakdown
+type SeriesPoint struct {
+	Bucket   time.Time
+	Posts    int64
+	Likes    int64
+	Reposts  int64
+	NewUsers int64
+}
+
+// UserTotals holds the raw counters behind a user's engagement stats
+type UserTotals struct {
+	TotalPosts      int64
+	LikesReceived   int64
+	RepostsReceived int64
+	RepliesReceived int64
+	TotalFollowers  int64
+	TotalFollowing  int64
+}
+
+// GetSystemTotals computes every system counter in a single round trip
+func (r *StatsRepository) GetSystemTotals(ctx context.Context, activeSince time.Time, now time.Time) (*SystemTotals, error) {
+	var t SystemTotals
+	if err := r.db.WithContext(ctx).Raw(`
+		SELECT
+			(SELECT COUNT(*) FROM users WHERE deleted_at IS NULL) AS total_users,
+			(SELECT COUNT(*) FROM posts WHERE deleted_at IS NULL) AS total_posts,
+			(SELECT COUNT(*) FROM likes l JOIN posts p ON p.id = l.post_id WHERE p.deleted_at IS NULL) AS total_likes,
+			(SELECT COUNT(*) FROM reposts rp JOIN posts p ON p.id = rp.post_id WHERE p.deleted_at IS NULL) AS total_reposts,
+			(SELECT COUNT(*) FROM posts WHERE reply_to IS NOT NULL AND deleted_at IS NULL) AS total_replies,
+			(SELECT COUNT(DISTINCT user_id) FROM (
+				SELECT user_id FROM posts WHERE created_at >= @active AND deleted_at IS NULL
+				UNION SELECT user_id FROM likes WHERE created_at >= @active
+				UNION SELECT user_id FROM reposts WHERE created_at >= @active
+			) active) AS active_users,
+			(SELECT COUNT(*) FROM posts WHERE created_at >= @hour AND deleted_at IS NULL) AS posts_last_hour,
+			(SELECT COUNT(*) FROM posts WHERE created_at >= @day AND deleted_at IS NULL) AS posts_last_day,
+			(SELECT COUNT(*) FROM posts WHERE created_at >= @week AND deleted_at IS NULL) AS posts_last_week
+	`, map[string]interface{}{
+		"active": activeSince,
+		"hour":   now.Add(-time.Hour),
+		"day":    now.Add(-24 * time.Hour),
+		"week":   now.Add(-7 * 24 * time.Hour),
+	}).Scan(&t).Error; err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// GetSeries buckets posts, likes, reposts and sign-ups by the given
+// Postgres date_trunc unit ("hour", "day" or "week") since the given time
+func (r *StatsRepository) GetSeries(ctx context.Context, unit string, since time.Time) ([]SeriesPoint, error) {
+	var points []SeriesPoint
+	if err := r.db.WithContext(ctx).Raw(`
+		SELECT bucket,
+			SUM(posts) AS posts,
+			SUM(likes) AS likes,
+			SUM(reposts) AS reposts,
+			SUM(new_users) AS new_users
+		FROM (
+			SELECT date_trunc(@unit, created_at) AS bucket, COUNT(*) AS posts, 0 AS likes, 0 AS reposts, 0 AS new_users
+				FROM posts WHERE created_at >= @since AND deleted_at IS NULL GROUP BY 1
+			UNION ALL
+			SELECT date_trunc(@unit, l.created_at), 0, COUNT(*), 0, 0
+				FROM likes l JOIN posts p ON p.id = l.post_id
+				WHERE l.created_at >= @since AND p.deleted_at IS NULL GROUP BY 1
+			UNION ALL
+			SELECT date_trunc(@unit, rp.created_at), 0, 0, COUNT(*), 0
+				FROM reposts rp JOIN posts p ON p.id = rp.post_id
+				WHERE rp.created_at >= @since AND p.deleted_at IS NULL GROUP BY 1
+			UNION ALL
+			SELECT date_trunc(@unit, created_at), 0, 0, 0, COUNT(*)
+				FROM users WHERE created_at >= @since AND deleted_at IS NULL GROUP BY 1
+		) buckets
+		GROUP BY bucket
+		ORDER BY bucket
+	`, map[string]interface{}{
+		"unit":  unit,
+		"since": since,
+	}).Scan(&points).Error; err != nil {
+		return nil, err
+	}
+	return points, nil
+}
+
+// GetUserTotals computes a user's engagement counters in a single round trip
+func (r *StatsRepository) GetUserTotals(ctx context.Context, userID string) (*UserTotals, error) {
+	var t UserTotals
+	if err := r.db.WithContext(ctx).Raw(`
+		SELECT
+			(SELECT COUNT(*) FROM posts WHERE user_id = @user AND deleted_at IS NULL) AS total_posts,
+			(SELECT COUNT(*) FROM likes l JOIN posts p ON p.id = l.post_id WHERE p.user_id = @user AND p.deleted_at IS NULL) AS likes_received,
+			(SELECT COUNT(*) FROM reposts rp JOIN posts p ON p.id = rp.post_id WHERE p.user_id = @user AND p.deleted_at IS NULL) AS reposts_received,
+			(SELECT COUNT(*) FROM posts rep JOIN posts p ON p.id = rep.reply_to WHERE p.user_id = @user AND rep.user_id <> @user AND rep.deleted_at IS NULL AND p.deleted_at IS NULL) AS replies_received,
+			(SELECT COUNT(*) FROM follows WHERE followee_id = @user) AS total_followers,
+			(SELECT COUNT(*) FROM follows WHERE follower_id = @user) AS total_following
+	`, map[string]interface{}{
+		"user": userID,
+	}).Scan(&t).Error; err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// GetUserSeries buckets a user's posts and the likes/reposts they received
+func (r *StatsRepository) GetUserSeries(ctx context.Context, userID, unit string, since time.Time) ([]SeriesPoint, error) {
+	var points []SeriesPoint
+	if err := r.db.WithContext(ctx).Raw(`
+		SELECT bucket,
+			SUM(posts) AS posts,
+			SUM(likes) AS likes,
+			SUM(reposts) AS reposts
+		FROM (
+			SELECT date_trunc(@unit, created_at) AS bucket, COUNT(*) AS posts, 0 AS likes, 0 AS reposts
+				FROM posts WHERE user_id = @user AND created_at >= @since AND deleted_at IS NULL GROUP BY 1
+			UNION ALL
+			SELECT date_trunc(@unit, l.created_at), 0, COUNT(*), 0
+				FROM likes l JOIN posts p ON p.id = l.post_id
+				WHERE p.user_id = @user AND l.created_at >= @since AND p.deleted_at IS NULL GROUP BY 1
+			UNION ALL
+			SELECT date_trunc(@unit, rp.created_at), 0, 0, COUNT(*)
+				FROM reposts rp JOIN posts p ON p.id = rp.post_id
+				WHERE p.user_id = @user AND rp.created_at >= @since AND p.deleted_at IS NULL GROUP BY 1
+		) buckets
+		GROUP BY bucket
+		ORDER BY bucket
+	`, map[string]interface{}{
+		"user":  userID,
+		"unit":  unit,
+		"since": since,
+	}).Scan(&points).Error; err != nil {
+		return nil, err
+	}
+	return points, nil
+}
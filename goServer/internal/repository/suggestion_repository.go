@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"goServer/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SuggestionCandidate is an account that could be suggested to a user,
+// with the raw signals behind it
+type SuggestionCandidate struct {
+	SuggestedID string
+	MutualCount int
+	SharedCount int
+	Followers   int64
+}
+
+// unsuggestableSubquery selects accounts that must never be suggested to
+// @user: themselves, accounts they follow or asked to follow, accounts on
+// either side of a block and accounts they dismissed
+const unsuggestableSubquery = `SELECT CAST(@user AS uuid)
+	UNION SELECT followee_id FROM follows WHERE follower_id = @user
+	UNION SELECT target_id FROM follow_requests WHERE requester_id = @user
+	UNION SELECT dismissed_id FROM suggestion_dismissals WHERE user_id = @user
+	UNION SELECT blocked_id FROM blocks WHERE blocker_id = @user
+	UNION SELECT blocker_id FROM blocks WHERE blocked_id = @user`
+
+// candidatesQuery gathers friends-of-friends, accounts that recently liked
+// or reposted the same posts as the user, and the most followed accounts
+// (so users with an empty graph still get suggestions)
+const candidatesQuery = `
+WITH fof AS (
+	SELECT f2.followee_id AS candidate, count(*) AS mutuals
+	FROM follows f1
+	JOIN follows f2 ON f2.follower_id = f1.followee_id
+	WHERE f1.follower_id = @user
+	GROUP BY f2.followee_id
+),
+engaged AS (
+	SELECT post_id FROM likes WHERE user_id = @user AND created_at > @since
+	UNION SELECT post_id FROM reposts WHERE user_id = @user AND created_at > @since
+),
+overlap AS (
+	SELECT e.user_id AS candidate, count(DISTINCT e.post_id) AS shared
+	FROM (
+		SELECT user_id, post_id FROM likes WHERE created_at > @since AND post_id IN (SELECT post_id FROM engaged)
+		UNION ALL
+		SELECT user_id, post_id FROM reposts WHERE created_at > @since AND post_id IN (SELECT post_id FROM engaged)
+	) e
+	GROUP BY e.user_id
+),
+popular AS (
+	SELECT followee_id AS candidate FROM follows GROUP BY followee_id ORDER BY count(*) DESC LIMIT @popular
+),
+candidates AS (
+	SELECT candidate FROM fof UNION SELECT candidate FROM overlap UNION SELECT candidate FROM popular
+)
+SELECT c.candidate AS suggested_id,
+	COALESCE(fof.mutuals, 0) AS mutual_count,
+	COALESCE(overlap.shared, 0) AS shared_count,
+	(SELECT count(*) FROM follows WHERE followee_id = c.candidate) AS followers
+FROM candidates c
+JOIN users u ON u.id = c.candidate AND u.deleted_at IS NULL AND u.deletion_scheduled_at IS NULL
+LEFT JOIN fof ON fof.candidate = c.candidate
+LEFT JOIN overlap ON overlap.candidate = c.candidate
+WHERE c.candidate NOT IN (` + unsuggestableSubquery + `)
+ORDER BY mutual_count DESC, shared_count DESC, followers DESC
+LIMIT @limit`
+
+type SuggestionRepository struct {
+	db *gorm.DB
+}
+
+func NewSuggestionRepository(db *gorm.DB) *SuggestionRepository {
+	return &SuggestionRepository{db: db}
+}
+
+// suggestionRebuildLockKey lets one instance at a time run the rebuild job
+const suggestionRebuildLockKey = 7_301_550_882
+
+// WithRebuildLock runs fn while holding the rebuild job's advisory lock. It
+// reports false without calling fn when another instance holds it.
+func (r *SuggestionRepository) WithRebuildLock(ctx context.Context, fn func() error) (bool, error) {
+	locked := false
+	// Session locks belong to a connection, so take and release it on one
+	err := r.db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		if err := conn.Raw("SELECT pg_try_advisory_lock(?)", suggestionRebuildLockKey).Scan(&locked).Error; err != nil {
+			return err
+		}
+		if !locked {
+			return nil
+		}
+		// Unlock even if ctx was cancelled, or the pooled connection keeps the lock
+		defer conn.WithContext(context.WithoutCancel(ctx)).Exec("SELECT pg_advisory_unlock(?)", suggestionRebuildLockKey)
+		return fn()
+	})
+	return locked, err
+}
+
+// StaleUserIDsAfter pages through the IDs of live accounts whose suggestions
+// weren't rebuilt since builtBefore, in ID order
+func (r *SuggestionRepository) StaleUserIDsAfter(ctx context.Context, afterID string, builtBefore time.Time, limit int) ([]string, error) {
+	var ids []string
+	db := r.db.WithContext(ctx).
+		Model(&model.User{}).
+		Where("suggestions_built_at IS NULL OR suggestions_built_at < ?", builtBefore)
+	if afterID != "" {
+		db = db.Where("id > ?", afterID)
+	}
+	if err := db.Order("id").Limit(limit).Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// Candidates finds up to limit accounts worth suggesting to a user, using
+// engagement since the given time and the popular most followed accounts
+func (r *SuggestionRepository) Candidates(ctx context.Context, userID string, since time.Time, popular, limit int) ([]SuggestionCandidate, error) {
+	var candidates []SuggestionCandidate
+	if err := r.db.WithContext(ctx).Raw(candidatesQuery, map[string]interface{}{
+		"user":    userID,
+		"since":   since,
+		"popular": popular,
+		"limit":   limit,
+	}).Scan(&candidates).Error; err != nil {
+		return nil, err
+	}
+	return candidates, nil
+}
+
+// Replace swaps a user's stored suggestions for a freshly computed set and
+// records when they were built
+func (r *SuggestionRepository) Replace(ctx context.Context, userID string, suggestions []model.FollowSuggestion) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&model.FollowSuggestion{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.User{}).Where("id = ?", userID).UpdateColumn("suggestions_built_at", time.Now()).Error; err != nil {
+			return err
+		}
+		if len(suggestions) == 0 {
+			return nil
+		}
+		return tx.Create(&suggestions).Error
+	})
+}
+
+// List gets a user's best suggestions. Accounts followed, blocked or
+// dismissed since the suggestions were computed are filtered out here.
+func (r *SuggestionRepository) List(ctx context.Context, userID string, limit int) ([]model.FollowSuggestion, error) {
+	var suggestions []model.FollowSuggestion
+	if err := r.db.WithContext(ctx).
+		InnerJoins("Suggested").
+		Where("follow_suggestions.user_id = ?", userID).
+		Where("follow_suggestions.suggested_id NOT IN ("+unsuggestableSubquery+")", map[string]interface{}{"user": userID}).
+		Order("follow_suggestions.score DESC").
+		Limit(limit).
+		Find(&suggestions).Error; err != nil {
+		return nil, err
+	}
+	return suggestions, nil
+}
+
+// Dismiss stops an account from being suggested to a user again
+func (r *SuggestionRepository) Dismiss(ctx context.Context, userID, dismissedID string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&model.SuggestionDismissal{UserID: userID, DismissedID: dismissedID}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ? AND suggested_id = ?", userID, dismissedID).
+			Delete(&model.FollowSuggestion{}).Error
+	})
+}
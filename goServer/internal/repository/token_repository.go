@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"goServer/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TokenRepository struct {
+	db *gorm.DB
+}
+
+func NewTokenRepository(db *gorm.DB) *TokenRepository {
+	return &TokenRepository{db: db}
+}
+
+// WithTx returns a repository bound to the given transaction
+func (r *TokenRepository) WithTx(tx *gorm.DB) *TokenRepository {
+	return &TokenRepository{db: tx}
+}
+
+// Transaction runs fn inside a database transaction
+func (r *TokenRepository) Transaction(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	return r.db.WithContext(ctx).Transaction(fn)
+}
+
+// Create creates a new token
+func (r *TokenRepository) Create(ctx context.Context, t *model.UserToken) error {
+	return r.db.WithContext(ctx).Create(t).Error
+}
+
+// Consume atomically marks a live token as used and returns it.
+// It returns nil when the token is unknown, expired or already used.
+func (r *TokenRepository) Consume(ctx context.Context, tokenHash, purpose string) (*model.UserToken, error) {
+	var tokens []model.UserToken
+	now := time.Now()
+
+	res := r.db.WithContext(ctx).
+		Model(&tokens).
+		Clauses(clause.Returning{}).
+		Where("token_hash = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?", tokenHash, purpose, now).
+		Update("used_at", now)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+	return &tokens[0], nil
+}
+
+// InvalidateForUser marks every outstanding token of a purpose as used
+func (r *TokenRepository) InvalidateForUser(ctx context.Context, userID, purpose string) error {
+	return r.db.WithContext(ctx).
+		Model(&model.UserToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+		Update("used_at", time.Now()).Error
+}
+
+// DeleteExpired deletes tokens that expired before the given time
+func (r *TokenRepository) DeleteExpired(ctx context.Context, before time.Time) error {
+	return r.db.WithContext(ctx).
+		Where("expires_at < ?", before).
+		Delete(&model.UserToken{}).Error
+}
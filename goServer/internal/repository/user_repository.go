@@ -3,8 +3,10 @@ package repository
 import (
 	"context"
 	"errors"
+	"time"
 
 	"goServer/internal/model"
+	"goServer/pkg/utils"
 
 	"gorm.io/gorm"
 )
@@ -17,15 +19,20 @@ func NewUserRepository(db *gorm.DB) *UserRepository {
 	return &UserRepository{db: db}
 }
 
+// WithTx returns a repository bound to the given transaction
+func (r *UserRepository) WithTx(tx *gorm.DB) *UserRepository {
+	return &UserRepository{db: tx}
+}
+
 // Create creates a new user
 func (r *UserRepository) Create(ctx context.Context, u *model.User) error {
 	return r.db.WithContext(ctx).Create(u).Error
 }
 
-// FindByEmail finds a user by email
+// FindByEmail finds a user by email, ignoring case
 func (r *UserRepository) FindByEmail(ctx context.Context, email string) (*model.User, error) {
 	var u model.User
-	if err := r.db.WithContext(ctx).Where("email = ?", email).First(&u).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("lower(email) = ?", utils.NormalizeEmail(email)).First(&u).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil
 		}
@@ -34,10 +41,10 @@ func (r *UserRepository) FindByEmail(ctx context.Context, email string) (*model.
 	return &u, nil
 }
 
-// FindByUsername finds a user by username
+// FindByUsername finds a user by username, ignoring case
 func (r *UserRepository) FindByUsername(ctx context.Context, username string) (*model.User, error) {
 	var u model.User
-	if err := r.db.WithContext(ctx).Where("username = ?", username).First(&u).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("lower(username) = ?", utils.NormalizeUsername(username)).First(&u).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil
 		}
@@ -46,6 +53,21 @@ func (r *UserRepository) FindByUsername(ctx context.Context, username string) (*
 	return &u, nil
 }
 
+// FindByUsernames finds the users with any of the given usernames,
+// ignoring case. Unknown names are skipped.
+func (r *UserRepository) FindByUsernames(ctx context.Context, usernames []string) ([]model.User, error) {
+	var users []model.User
+	if len(usernames) == 0 {
+		return users, nil
+	}
+	if err := r.db.WithContext(ctx).
+		Where("lower(username) IN ?", usernames).
+		Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
 // FindByID finds a user by ID
 func (r *UserRepository) FindByID(ctx context.Context, id string) (*model.User, error) {
 	var u model.User
@@ -63,6 +85,95 @@ func (r *UserRepository) Update(ctx context.Context, u *model.User) error {
 	return r.db.WithContext(ctx).Model(u).Updates(u).Error
 }
 
+// MarkEmailVerified records that the user proved ownership of their email
+func (r *UserRepository) MarkEmailVerified(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).
+		Model(&model.User{}).
+		Where("id = ?", id).
+		Update("email_verified_at", time.Now()).Error
+}
+
+// UpdatePassword stores a new password hash and bumps the token version,
+// which invalidates every token issued before the change
+func (r *UserRepository) UpdatePassword(ctx context.Context, id, passwordHash string) error {
+	return r.db.WithContext(ctx).
+		Model(&model.User{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"password":      passwordHash,
+			"token_version": gorm.Expr("token_version + 1"),
+		}).Error
+}
+
+// UpdateUsername changes a user's handle and stamps the change time
+func (r *UserRepository) UpdateUsername(ctx context.Context, id, username string) error {
+	return r.db.WithContext(ctx).
+		Model(&model.User{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"username":            username,
+			"username_changed_at": time.Now(),
+		}).Error
+}
+
+// SetPasswordHash replaces the stored hash of an unchanged password, e.g.
+// when upgrading it to newer hashing parameters. Tokens stay valid.
+func (r *UserRepository) SetPasswordHash(ctx context.Context, id, passwordHash string) error {
+	return r.db.WithContext(ctx).
+		Model(&model.User{}).
+		Where("id = ?", id).
+		Update("password", passwordHash).Error
+}
+
+// SetTOTPSecret stores a pending TOTP secret; it has no effect on login
+// until EnableTOTP confirms it
+func (r *UserRepository) SetTOTPSecret(ctx context.Context, id, secret string) error {
+	return r.db.WithContext(ctx).
+		Model(&model.User{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"totp_secret":     secret,
+			"totp_enabled_at": nil,
+			"totp_last_step":  0,
+		}).Error
+}
+
+// EnableTOTP turns on two-factor authentication with the pending secret
+func (r *UserRepository) EnableTOTP(ctx context.Context, id string, step int64) error {
+	return r.db.WithContext(ctx).
+		Model(&model.User{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"totp_enabled_at": time.Now(),
+			"totp_last_step":  step,
+		}).Error
+}
+
+// DisableTOTP removes the TOTP secret, turns two-factor authentication off
+// and bumps the token version, logging the user out everywhere
+func (r *UserRepository) DisableTOTP(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).
+		Model(&model.User{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"totp_secret":     "",
+			"totp_enabled_at": nil,
+			"totp_last_step":  0,
+			"token_version":   gorm.Expr("token_version + 1"),
+		}).Error
+}
+
+// AdvanceTOTPStep records step as the last accepted TOTP step. It reports
+// false when a code for that step (or a later one) was already accepted,
+// which makes concurrent replays of the same code fail.
+func (r *UserRepository) AdvanceTOTPStep(ctx context.Context, id string, step int64) (bool, error) {
+	res := r.db.WithContext(ctx).
+		Model(&model.User{}).
+		Where("id = ? AND totp_last_step < ?", id, step).
+		Update("totp_last_step", step)
+	return res.RowsAffected > 0, res.Error
+}
+
 // UpdateProfile updates user profile fields
 func (r *UserRepository) UpdateProfile(ctx context.Context, id string, displayName, bio, avatarURL string) (*model.User, error) {
 	u := &model.User{}
@@ -76,9 +187,113 @@ func (r *UserRepository) UpdateProfile(ctx context.Context, id string, displayNa
 	return u, nil
 }
 
-// Delete deletes a user
+// SetPrivate switches whether the account's posts are limited to approved followers
+func (r *UserRepository) SetPrivate(ctx context.Context, id string, private bool) error {
+	return r.db.WithContext(ctx).Model(&model.User{}).
+		Where("id = ?", id).
+		Update("is_private", private).Error
+}
+
+// FindByIDWithDeleted finds a user by ID including soft-deleted ones
+func (r *UserRepository) FindByIDWithDeleted(ctx context.Context, id string) (*model.User, error) {
+	var u model.User
+	if err := r.db.WithContext(ctx).Unscoped().Where("id = ?", id).First(&u).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &u, nil
+}
+
+// Delete soft-deletes a user along with their posts and media, all stamped
+// with the same time so Restore can bring back exactly what was removed
 func (r *UserRepository) Delete(ctx context.Context, id string) error {
-	return r.db.WithContext(ctx).Where("id = ?", id).Delete(&model.User{}).Error
+	now := time.Now()
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Media{}).
+			Where("post_id IN (SELECT id FROM posts WHERE user_id = ? AND deleted_at IS NULL)", id).
+			Update("deleted_at", now).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.Post{}).
+			Where("user_id = ?", id).
+			Update("deleted_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.User{}).
+			Where("id = ?", id).
+			Update("deleted_at", now).Error
+	})
+}
+
+// ScheduleDeletion marks the account for deletion at the given time and
+// bumps the token version, logging the user out everywhere
+func (r *UserRepository) ScheduleDeletion(ctx context.Context, id string, at time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&model.User{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"deletion_scheduled_at": at,
+			"token_version":         gorm.Expr("token_version + 1"),
+		}).Error
+}
+
+// CancelDeletion clears a scheduled deletion, reporting whether one was pending
+func (r *UserRepository) CancelDeletion(ctx context.Context, id string) (bool, error) {
+	res := r.db.WithContext(ctx).
+		Model(&model.User{}).
+		Where("id = ? AND deletion_scheduled_at IS NOT NULL", id).
+		Update("deletion_scheduled_at", nil)
+	return res.RowsAffected > 0, res.Error
+}
+
+// DeleteScheduled hard-deletes accounts whose deletion grace period ended;
+// the database cascades remove everything they owned. As with PurgeDeleted,
+// other users' replies to and quotes of their posts are kept, detached from
+// the deleted post.
+func (r *UserRepository) DeleteScheduled(ctx context.Context, at time.Time) (int64, error) {
+	res := r.db.WithContext(ctx).
+		Unscoped().
+		Where("deletion_scheduled_at <= ?", at).
+		Delete(&model.User{})
+	return res.RowsAffected, res.Error
+}
+
+// Restore undoes a soft delete of a user and the content removed with it
+func (r *UserRepository) Restore(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var u model.User
+		if err := tx.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(&u).Error; err != nil {
+			return err
+		}
+		deletedAt := u.DeletedAt.Time
+
+		if err := tx.Unscoped().Model(&model.Media{}).
+			Where("post_id IN (SELECT id FROM posts WHERE user_id = ?) AND deleted_at = ?", id, deletedAt).
+			Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&model.Post{}).
+			Where("user_id = ? AND deleted_at = ?", id, deletedAt).
+			Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Model(&model.User{}).
+			Where("id = ?", id).
+			Update("deleted_at", nil).Error
+	})
+}
+
+// PurgeDeleted hard-deletes users soft-deleted before the cutoff; the
+// database cascades remove everything they owned. Other users' replies to
+// and quotes of their posts are kept, detached from the purged post.
+func (r *UserRepository) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	res := r.db.WithContext(ctx).
+		Unscoped().
+		Where("deleted_at < ?", before).
+		Delete(&model.User{})
+	return res.RowsAffected, res.Error
 }
 
 // FollowUser creates a follow relationship
@@ -121,6 +336,132 @@ func (r *UserRepository) GetFollowing(ctx context.Context, userID string) ([]mod
 	return following, nil
 }
 
+// FollowEdge is one entry of a follower or following list
+type FollowEdge struct {
+	User       model.User
+	FollowedAt time.Time
+}
+
+// GetFollowersPage gets up to limit followers of a user, newest follow
+// first, starting after the cursor
+func (r *UserRepository) GetFollowersPage(ctx context.Context, userID string, after *utils.Cursor, limit int) ([]FollowEdge, error) {
+	return r.followPage(ctx, "followee_id", "follower_id", userID, after, limit)
+}
+
+// GetFollowingPage gets up to limit users a user follows, newest follow
+// first, starting after the cursor
+func (r *UserRepository) GetFollowingPage(ctx context.Context, userID string, after *utils.Cursor, limit int) ([]FollowEdge, error) {
+	return r.followPage(ctx, "follower_id", "followee_id", userID, after, limit)
+}
+
+// followPage pages through follows matching userID on column, returning the
+// users on the other side
+func (r *UserRepository) followPage(ctx context.Context, column, other, userID string, after *utils.Cursor, limit int) ([]FollowEdge, error) {
+	var follows []model.Follow
+	db := r.db.WithContext(ctx).
+		Model(&model.Follow{}).
+		Joins("JOIN users ON users.id = follows."+other+" AND users.deleted_at IS NULL").
+		Where("follows."+column+" = ?", userID)
+	if after != nil {
+		db = db.Where("(follows.created_at, follows."+other+") < (?, ?)", after.At, after.ID)
+	}
+	if err := db.
+		Order("follows.created_at DESC, follows." + other + " DESC").
+		Limit(limit).
+		Find(&follows).Error; err != nil {
+		return nil, err
+	}
+	if len(follows) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]string, len(follows))
+	for i, f := range follows {
+		if other == "followee_id" {
+			ids[i] = f.FolloweeID
+		} else {
+			ids[i] = f.FollowerID
+		}
+	}
+
+	var users []model.User
+	if err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[string]model.User, len(users))
+	for _, u := range users {
+		byID[u.ID] = u
+	}
+
+	edges := make([]FollowEdge, 0, len(follows))
+	for i, f := range follows {
+		if u, ok := byID[ids[i]]; ok {
+			edges = append(edges, FollowEdge{User: u, FollowedAt: f.CreatedAt})
+		}
+	}
+	return edges, nil
+}
+
+// FollowFlags reports, for each of ids, whether the viewer follows them and
+// whether they follow the viewer
+func (r *UserRepository) FollowFlags(ctx context.Context, viewerID string, ids []string) (following, followedBy map[string]bool, err error) {
+	following = make(map[string]bool)
+	followedBy = make(map[string]bool)
+	if viewerID == "" || len(ids) == 0 {
+		return following, followedBy, nil
+	}
+
+	var follows []model.Follow
+	if err := r.db.WithContext(ctx).
+		Where("(follower_id = ? AND followee_id IN ?) OR (followee_id = ? AND follower_id IN ?)", viewerID, ids, viewerID, ids).
+		Find(&follows).Error; err != nil {
+		return nil, nil, err
+	}
+	for _, f := range follows {
+		if f.FollowerID == viewerID {
+			following[f.FolloweeID] = true
+		} else {
+			followedBy[f.FollowerID] = true
+		}
+	}
+	return following, followedBy, nil
+}
+
+// UserCounts holds the follower, following and post counts of a user
+type UserCounts struct {
+	UserID    string
+	Followers int64
+	Following int64
+	Posts     int64
+}
+
+// GetCounts gets the counts for several users in one query. Follows by
+// deleted accounts and deleted posts aren't counted.
+func (r *UserRepository) GetCounts(ctx context.Context, ids []string) (map[string]UserCounts, error) {
+	counts := make(map[string]UserCounts, len(ids))
+	if len(ids) == 0 {
+		return counts, nil
+	}
+
+	var rows []UserCounts
+	if err := r.db.WithContext(ctx).Raw(`
+		SELECT u.id AS user_id,
+			(SELECT count(*) FROM follows f JOIN users x ON x.id = f.follower_id AND x.deleted_at IS NULL
+				WHERE f.followee_id = u.id) AS followers,
+			(SELECT count(*) FROM follows f JOIN users x ON x.id = f.followee_id AND x.deleted_at IS NULL
+				WHERE f.follower_id = u.id) AS following,
+			(SELECT count(*) FROM posts p WHERE p.user_id = u.id AND p.deleted_at IS NULL) AS posts
+		FROM users u
+		WHERE u.id IN ?`, ids).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		counts[row.UserID] = row
+	}
+	return counts, nil
+}
+
 // IsFollowing checks if followerID is following followeeID
 func (r *UserRepository) IsFollowing(ctx context.Context, followerID, followeeID string) (bool, error) {
 	var count int64
@@ -160,11 +501,14 @@ func (r *UserRepository) GetFollowingCount(ctx context.Context, userID string) (
 	return count, nil
 }
 
-// SearchUsers searches for users by username or display name
-func (r *UserRepository) SearchUsers(ctx context.Context, query string, limit, offset int) ([]model.User, error) {
+// SearchUsers searches for users by username or display name, leaving out
+// users on either side of a block with the viewer
+func (r *UserRepository) SearchUsers(ctx context.Context, query, viewerID string, limit, offset int) ([]model.User, error) {
 	var users []model.User
-	if err := r.db.WithContext(ctx).
-		Where("username ILIKE ? OR display_name ILIKE ?", "%"+query+"%", "%"+query+"%").
+	db := r.db.WithContext(ctx).
+		Where("username ILIKE ? OR display_name ILIKE ?", "%"+query+"%", "%"+query+"%")
+	db = excludeBlocked(db, "users.id", viewerID)
+	if err := db.
 		Limit(limit).
 		Offset(offset).
 		Find(&users).Error; err != nil {
@@ -185,22 +529,24 @@ func (r *UserRepository) GetAllUsers(ctx context.Context, limit, offset int) ([]
 	return users, nil
 }
 
-// ExistsEmail checks if email exists
+// ExistsEmail checks if email exists, ignoring case and including
+// soft-deleted accounts
 func (r *UserRepository) ExistsEmail(ctx context.Context, email string) (bool, error) {
 	var count int64
-	if err := r.db.WithContext(ctx).Model(&model.User{}).
-		Where("email = ?", email).
+	if err := r.db.WithContext(ctx).Unscoped().Model(&model.User{}).
+		Where("lower(email) = ?", utils.NormalizeEmail(email)).
 		Count(&count).Error; err != nil {
 		return false, err
 	}
 	return count > 0, nil
 }
 
-// ExistsUsername checks if username exists
+// ExistsUsername checks if username exists, ignoring case and including
+// soft-deleted accounts
 func (r *UserRepository) ExistsUsername(ctx context.Context, username string) (bool, error) {
 	var count int64
-	if err := r.db.WithContext(ctx).Model(&model.User{}).
-		Where("username = ?", username).
+	if err := r.db.WithContext(ctx).Unscoped().Model(&model.User{}).
+		Where("lower(username) = ?", utils.NormalizeUsername(username)).
 		Count(&count).Error; err != nil {
 		return false, err
 	}
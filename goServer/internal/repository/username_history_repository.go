@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"goServer/internal/model"
+	"goServer/pkg/utils"
+
+	"gorm.io/gorm"
+)
+
+type UsernameHistoryRepository struct {
+	db *gorm.DB
+}
+
+func NewUsernameHistoryRepository(db *gorm.DB) *UsernameHistoryRepository {
+	return &UsernameHistoryRepository{db: db}
+}
+
+// WithTx returns a repository bound to the given transaction
+func (r *UsernameHistoryRepository) WithTx(tx *gorm.DB) *UsernameHistoryRepository {
+	return &UsernameHistoryRepository{db: tx}
+}
+
+// Transaction runs fn inside a database transaction
+func (r *UsernameHistoryRepository) Transaction(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	return r.db.WithContext(ctx).Transaction(fn)
+}
+
+// Create records a username change
+func (r *UsernameHistoryRepository) Create(ctx context.Context, h *model.UsernameHistory) error {
+	return r.db.WithContext(ctx).Create(h).Error
+}
+
+// FindActive returns the most recent unreleased change away from username
+func (r *UsernameHistoryRepository) FindActive(ctx context.Context, username string, at time.Time) (*model.UsernameHistory, error) {
+	var h model.UsernameHistory
+	if err := r.db.WithContext(ctx).
+		Where("lower(old_username) = ? AND released_at > ?", utils.NormalizeUsername(username), at).
+		Order("changed_at DESC").
+		First(&h).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &h, nil
+}
+
+// IsHeld reports whether username is an old handle still reserved for an
+// account other than exceptUserID
+func (r *UsernameHistoryRepository) IsHeld(ctx context.Context, username, exceptUserID string, at time.Time) (bool, error) {
+	var count int64
+	q := r.db.WithContext(ctx).
+		Model(&model.UsernameHistory{}).
+		Where("lower(old_username) = ? AND released_at > ?", utils.NormalizeUsername(username), at)
+	if exceptUserID != "" {
+		q = q.Where("user_id <> ?", exceptUserID)
+	}
+	if err := q.Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// Release ends the hold on a user's old handle, e.g. when they take it back
+func (r *UsernameHistoryRepository) Release(ctx context.Context, userID, username string, at time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&model.UsernameHistory{}).
+		Where("user_id = ? AND lower(old_username) = ? AND released_at > ?", userID, utils.NormalizeUsername(username), at).
+		Update("released_at", at).Error
+}
@@ -1,116 +1,299 @@
 package router
 
 import (
+	"context"
+	"log"
 	"time"
 
 	"goServer/internal/config"
 	"goServer/internal/handler"
+	"goServer/internal/mailer"
 	"goServer/internal/middleware"
+	"goServer/internal/model"
+	"goServer/internal/oidc"
 	"goServer/internal/repository"
 	"goServer/internal/service"
+	"goServer/pkg/utils"
 
 	"github.com/gofiber/fiber/v3"
 	"gorm.io/gorm"
 )
 
-func SetupRoutes(app *fiber.App, db *gorm.DB, cfg config.Config) {
+func SetupRoutes(app *fiber.App, db *gorm.DB, cfg config.Config, mail mailer.Mailer) error {
 	// Dependency Injection - Repositories
 	userRepo := repository.NewUserRepository(db)
 	postRepo := repository.NewPostRepository(db)
 	rateLimitRepo := repository.NewRateLimitRepository(db)
 	notificationRepo := repository.NewNotificationRepository(db)
+	statsRepo := repository.NewStatsRepository(db)
+	auditRepo := repository.NewAuditRepository(db)
+	tokenRepo := repository.NewTokenRepository(db)
+	recoveryCodeRepo := repository.NewRecoveryCodeRepository(db)
+	loginThrottleRepo := repository.NewLoginThrottleRepository(db)
+	usernameHistoryRepo := repository.NewUsernameHistoryRepository(db)
+	federationRepo := repository.NewFederationRepository(db)
+	accessTokenRepo := repository.NewPersonalAccessTokenRepository(db)
+	sessionRepo := repository.NewSessionRepository(db)
+	exportRepo := repository.NewExportRepository(db)
+	blockRepo := repository.NewBlockRepository(db)
+	followRequestRepo := repository.NewFollowRequestRepository(db)
+	suggestionRepo := repository.NewSuggestionRepository(db)
+	feedRepo := repository.NewFeedRepository(db)
+	draftRepo := repository.NewDraftRepository(db)
+	bookmarkRepo := repository.NewBookmarkRepository(db)
+	pollRepo := repository.NewPollRepository(db)
 
 	// Dependency Injection - Services
-	userSvc := service.NewUserService(*userRepo)
-	postSvc := service.NewPostService(*postRepo, *userRepo)
+	hasher := utils.NewArgon2idHasher(utils.ArgonConfig{
+		Time:    uint32(cfg.Argon2Time),
+		Memory:  uint32(cfg.Argon2Memory),
+		Threads: uint8(cfg.Argon2Threads),
+	})
+	var blocklist []string
+	if cfg.PasswordBlocklistFile != "" {
+		list, err := utils.LoadPasswordBlocklist(cfg.PasswordBlocklistFile)
+		if err != nil {
+			log.Printf("[router] failed to load password blocklist: %v", err)
+		}
+		blocklist = list
+	}
+	passwordPolicy := utils.NewPasswordPolicy(cfg.PasswordMinLength, cfg.PasswordMaxLength, blocklist)
+
+	userSvc, err := service.NewUserService(*userRepo, *usernameHistoryRepo, *blockRepo, *followRequestRepo, hasher, passwordPolicy, cfg)
+	if err != nil {
+		return err
+	}
+	postSvc := service.NewPostService(*postRepo, *userRepo, *blockRepo, cfg)
 	rateLimitSvc := service.NewRateLimitService(*rateLimitRepo)
-	notificationSvc := service.NewNotificationService(*notificationRepo, *userRepo)
+	notificationSvc := service.NewNotificationService(*notificationRepo, *userRepo, *blockRepo)
+	statsSvc := service.NewStatsService(*statsRepo, *userRepo, *blockRepo, cfg.StatsMaxAge)
+	adminSvc := service.NewAdminService(*auditRepo, *userRepo, *postRepo)
+	purgeSvc := service.NewPurgeService(*userRepo, *postRepo, *notificationRepo, *tokenRepo, *loginThrottleRepo, *federationRepo, *sessionRepo, cfg.SoftDeleteRetention, cfg.SessionHistoryRetention)
+	accountSvc := service.NewAccountService(*userRepo, *tokenRepo, mail, hasher, passwordPolicy, cfg)
+	mfaSvc := service.NewMFAService(*userRepo, *recoveryCodeRepo, hasher, cfg)
+	loginGuardSvc := service.NewLoginGuardService(*loginThrottleRepo, *userRepo, mail, cfg)
+	federationSvc := service.NewFederationService(*federationRepo, *userRepo, *usernameHistoryRepo, oidc.NewRegistry(cfg), cfg)
+	accessTokenSvc := service.NewAccessTokenService(*accessTokenRepo, *userRepo, cfg)
+	sessionSvc := service.NewSessionService(*sessionRepo, *userRepo, cfg)
+	exportSvc := service.NewExportService(*exportRepo, *userRepo, mail, cfg)
+	blockSvc := service.NewBlockService(*blockRepo, *userRepo)
+	followRequestSvc := service.NewFollowRequestService(*followRequestRepo)
+	suggestionSvc := service.NewSuggestionService(*suggestionRepo, *userRepo, cfg)
+	feedSvc := service.NewFeedService(*feedRepo, *postRepo, cfg)
+	draftSvc := service.NewDraftService(*draftRepo, *postRepo, *userRepo, *blockRepo, cfg)
+	bookmarkSvc := service.NewBookmarkService(*bookmarkRepo, *postRepo, *userRepo, *blockRepo)
+	pollSvc := service.NewPollService(*pollRepo, *postRepo, *userRepo, *blockRepo)
 
 	// Dependency Injection - Handlers
-	authHandler := handler.NewAuthHandler(userSvc, cfg)
+	authHandler := handler.NewAuthHandler(userSvc, accountSvc, mfaSvc, loginGuardSvc, sessionSvc, notificationSvc, cfg)
+	oidcHandler := handler.NewOIDCHandler(federationSvc, authHandler)
 	userHandler := handler.NewUserHandler(userSvc, notificationSvc)
-	postHandler := handler.NewPostHandler(postSvc)
+	postHandler := handler.NewPostHandler(postSvc, feedSvc, notificationSvc, bookmarkSvc, pollSvc)
+	statsHandler := handler.NewStatsHandler(statsSvc)
+	adminHandler := handler.NewAdminHandler(adminSvc)
+	accessTokenHandler := handler.NewAccessTokenHandler(accessTokenSvc)
+	sessionHandler := handler.NewSessionHandler(sessionSvc)
+	exportHandler := handler.NewExportHandler(exportSvc)
+	blockHandler := handler.NewBlockHandler(blockSvc, userSvc)
+	followRequestHandler := handler.NewFollowRequestHandler(followRequestSvc, userSvc, notificationSvc)
+	suggestionHandler := handler.NewSuggestionHandler(suggestionSvc, userSvc)
+	draftHandler := handler.NewDraftHandler(draftSvc, notificationSvc, pollSvc)
+	bookmarkHandler := handler.NewBookmarkHandler(bookmarkSvc, postSvc, pollSvc)
+	pollHandler := handler.NewPollHandler(pollSvc)
+
+	// Background Jobs
+	go statsSvc.Run(context.Background(), cfg.StatsRefreshInterval)
+	go purgeSvc.Run(context.Background(), cfg.PurgeInterval)
+	go exportSvc.Run(context.Background(), cfg.ExportPollInterval)
+	go suggestionSvc.Run(context.Background(), cfg.SuggestionInterval)
+	go draftSvc.Run(context.Background(), cfg.ScheduledPostInterval, notificationSvc.NotifyMention)
+	go pollSvc.Run(context.Background(), cfg.PollResultsInterval, notificationSvc.NotifyPollResults)
 
 	api := app.Group("/api")
 	v1 := api.Group("/v1")
 
 	// ============ PUBLIC ROUTES ============
+	// Signed-in callers are identified where it changes the answer, e.g. to
+	// hide content across blocks
+	optional := middleware.OptionalJWT(cfg.JWTSecret, sessionSvc, accessTokenSvc)
+
 	// Authentication
 	v1.Post("/auth/register", authHandler.Register)
 	v1.Post("/auth/login", authHandler.Login)
+	v1.Get("/auth/verify", authHandler.VerifyEmail)
+	v1.Post("/auth/verify", authHandler.VerifyEmail)
+	v1.Post("/auth/forgot", authHandler.ForgotPassword)
+	v1.Post("/auth/reset", authHandler.ResetPassword)
+	v1.Get("/auth/oidc/providers", oidcHandler.GetProviders)
+	v1.Post("/auth/oidc/signup", oidcHandler.Signup)
+	v1.Get("/auth/oidc/:provider", oidcHandler.Login)
+	v1.Get("/auth/oidc/:provider/callback", oidcHandler.Callback)
+
+	// Data export downloads (authorized by the signed link)
+	v1.Get("/exports/:id/download", exportHandler.Download)
 
 	// Public Search (MUST BE BEFORE :username route)
-	v1.Get("/users/search", userHandler.SearchUsers)
+	v1.Get("/users/search", optional, userHandler.SearchUsers)
 
-	// Public User Info (SPECIFIC ROUTES BEFORE WILDCARD)
-	v1.Get("/users/:username/followers", userHandler.GetFollowers)
-	v1.Get("/users/:username/following", userHandler.GetFollowing)
-	v1.Get("/users/:username", userHandler.GetUserByUsername)
+	// Public User Info (SPECIFIC ROUTES BEFORE WILDCARD). Usernames are at
+	// least 3 characters, so the minLen constraint keeps these from
+	// swallowing the protected /users/me routes registered below.
+	v1.Get("/users/:username<minLen(3)>/followers", optional, userHandler.GetFollowers)
+	v1.Get("/users/:username<minLen(3)>/following", optional, userHandler.GetFollowing)
+	v1.Get("/users/:username<minLen(3)>/stats", optional, statsHandler.GetUserStats)
+	v1.Get("/users/:username<minLen(3)>", optional, userHandler.GetUserByUsername)
 
 	// Public Posts
-	v1.Get("/posts/:id/likes", postHandler.GetPostLikes)
-	v1.Get("/posts/:id/reposts", postHandler.GetPostReposts)
-	v1.Get("/posts/:id/replies", postHandler.GetReplies)
-	v1.Get("/posts/:id", postHandler.GetPost)
+	v1.Get("/posts/:id/likes", optional, postHandler.GetPostLikes)
+	v1.Get("/posts/:id/reposts", optional, postHandler.GetPostReposts)
+	v1.Get("/posts/:id/replies", optional, postHandler.GetReplies)
+	v1.Get("/posts/:id/history", optional, postHandler.GetPostHistory)
+	v1.Get("/posts/:id", optional, postHandler.GetPost)
 
-	// ============ PROTECTED ROUTES (Requires JWT) ============
-	protected := v1.Group("/", middleware.JWT(cfg.JWTSecret))
+	// ============ PROTECTED ROUTES (Requires JWT or access token) ============
+	// Personal access tokens only reach routes whose scope they were granted;
+	// account management is limited to interactive sessions.
+	protected := v1.Group("/", middleware.JWT(cfg.JWTSecret, sessionSvc, accessTokenSvc))
+	session := middleware.RequireSession()
+	scope := middleware.RequireScope
+
+	// Account
+	protected.Post("/auth/logout", session, sessionHandler.Logout)
+	protected.Post("/auth/verify/resend", session, authHandler.ResendVerification)
+	protected.Get("/auth/2fa", session, authHandler.GetMFAStatus)
+	protected.Post("/auth/2fa/enroll", session, authHandler.EnrollMFA)
+	protected.Post("/auth/2fa/confirm", session, authHandler.ConfirmMFA)
+	protected.Post("/auth/2fa/disable", session, authHandler.DisableMFA)
+	protected.Post("/auth/2fa/recovery-codes", session, authHandler.RegenerateRecoveryCodes)
+	protected.Get("/auth/scopes", accessTokenHandler.GetScopes)
 
 	// User Profile Management
-	protected.Get("/users/me", userHandler.GetProfile)
-	protected.Put("/users/me", userHandler.UpdateProfile)
-	protected.Delete("/users/me", userHandler.DeleteAccount)
+	protected.Get("/users/me", scope(model.ScopeUsersRead), userHandler.GetProfile)
+	protected.Put("/users/me", scope(model.ScopeUsersWrite), userHandler.UpdateProfile)
+	protected.Put("/users/me/username", session, userHandler.ChangeUsername)
+	protected.Get("/users/me/identities", session, oidcHandler.GetIdentities)
+	protected.Post("/users/me/identities/:provider", session, oidcHandler.LinkIdentity)
+	protected.Delete("/users/me/identities/:provider", session, oidcHandler.UnlinkIdentity)
+	protected.Get("/users/me/tokens", session, accessTokenHandler.GetTokens)
+	protected.Post("/users/me/tokens", session, accessTokenHandler.CreateToken)
+	protected.Delete("/users/me/tokens/:id", session, accessTokenHandler.RevokeToken)
+	protected.Get("/users/me/sessions", session, sessionHandler.GetSessions)
+	protected.Delete("/users/me/sessions", session, sessionHandler.RevokeOtherSessions)
+	protected.Delete("/users/me/sessions/:id", session, sessionHandler.RevokeSession)
+	protected.Get("/users/me/exports", session, exportHandler.GetExports)
+	protected.Post("/users/me/exports",
+		session,
+		middleware.RateLimit(rateLimitSvc, "data_export", 3, 24*time.Hour),
+		exportHandler.RequestExport)
+	protected.Delete("/users/me", session, authHandler.DeleteAccount)
+
+	protected.Get("/users/me/blocks", scope(model.ScopeUsersRead), blockHandler.GetBlocked)
+	protected.Post("/users/me/blocks/:id", scope(model.ScopeUsersWrite), blockHandler.BlockUser)
+	protected.Delete("/users/me/blocks/:id", scope(model.ScopeUsersWrite), blockHandler.UnblockUser)
+	protected.Get("/users/me/mutes", scope(model.ScopeUsersRead), blockHandler.GetMuted)
+	protected.Post("/users/me/mutes/:id", scope(model.ScopeUsersWrite), blockHandler.MuteUser)
+	protected.Delete("/users/me/mutes/:id", scope(model.ScopeUsersWrite), blockHandler.UnmuteUser)
+
+	protected.Get("/users/me/follow-requests", scope(model.ScopeUsersRead), followRequestHandler.GetIncoming)
+	protected.Get("/users/me/follow-requests/outgoing", scope(model.ScopeUsersRead), followRequestHandler.GetOutgoing)
+	protected.Post("/users/me/follow-requests/:id/approve", scope(model.ScopeUsersWrite), followRequestHandler.Approve)
+	protected.Post("/users/me/follow-requests/:id/reject", scope(model.ScopeUsersWrite), followRequestHandler.Reject)
+
+	protected.Get("/users/me/suggestions", scope(model.ScopeUsersRead), suggestionHandler.GetSuggestions)
+	protected.Delete("/users/me/suggestions/:id", scope(model.ScopeUsersWrite), suggestionHandler.DismissSuggestion)
 
-	protected.Get("/users/me/followers", userHandler.GetMyFollowers)
-	protected.Get("/users/me/following", userHandler.GetMyFollowing)
+	protected.Get("/users/me/drafts", scope(model.ScopePostsRead), draftHandler.GetDrafts)
+	protected.Post("/users/me/drafts",
+		scope(model.ScopePostsWrite),
+		middleware.RateLimit(rateLimitSvc, "create_draft", 30, 15*time.Minute),
+		draftHandler.CreateDraft)
+	protected.Put("/users/me/drafts/:id", scope(model.ScopePostsWrite), draftHandler.UpdateDraft)
+	protected.Put("/users/me/drafts/:id/schedule", scope(model.ScopePostsWrite), draftHandler.ScheduleDraft)
+	protected.Post("/users/me/drafts/:id/publish",
+		scope(model.ScopePostsWrite),
+		middleware.RateLimit(rateLimitSvc, "create_post", 10, 15*time.Minute),
+		draftHandler.PublishDraft)
+	protected.Delete("/users/me/drafts/:id", scope(model.ScopePostsWrite), draftHandler.DeleteDraft)
+
+	protected.Get("/users/me/bookmarks", scope(model.ScopePostsRead), bookmarkHandler.GetBookmarks)
+	protected.Get("/users/me/bookmarks/collections", scope(model.ScopePostsRead), bookmarkHandler.GetCollections)
+	protected.Post("/users/me/bookmarks/collections", scope(model.ScopePostsWrite), bookmarkHandler.CreateCollection)
+	protected.Put("/users/me/bookmarks/collections/:id", scope(model.ScopePostsWrite), bookmarkHandler.RenameCollection)
+	protected.Delete("/users/me/bookmarks/collections/:id", scope(model.ScopePostsWrite), bookmarkHandler.DeleteCollection)
+
+	protected.Get("/users/me/followers", scope(model.ScopeUsersRead), userHandler.GetMyFollowers)
+	protected.Get("/users/me/following", scope(model.ScopeUsersRead), userHandler.GetMyFollowing)
 
 	// User Relationships
-	protected.Post("/users/:id/follow", userHandler.FollowUser)
-	protected.Post("/users/:id/unfollow", userHandler.UnfollowUser)
+	protected.Post("/users/:id/follow", scope(model.ScopeUsersWrite), userHandler.FollowUser)
+	protected.Post("/users/:id/unfollow", scope(model.ScopeUsersWrite), userHandler.UnfollowUser)
 
 	// Posts - Create & Manage
 	protected.Post("/posts",
+		scope(model.ScopePostsWrite),
 		middleware.RateLimit(rateLimitSvc, "create_post", 10, 15*time.Minute),
 		postHandler.CreatePost)
 
-	protected.Get("/posts/feed", postHandler.GetFeed)
-	protected.Get("/posts/timeline/:username", postHandler.GetUserTimeline)
+	protected.Get("/posts/feed", scope(model.ScopePostsRead), postHandler.GetFeed)
+	protected.Get("/posts/timeline/:username", scope(model.ScopePostsRead), postHandler.GetUserTimeline)
 
 	// Generic post routes
-	protected.Put("/posts/:id", postHandler.UpdatePost)
-	protected.Delete("/posts/:id", postHandler.DeletePost)
+	protected.Put("/posts/:id", scope(model.ScopePostsWrite), postHandler.UpdatePost)
+	protected.Delete("/posts/:id", scope(model.ScopePostsWrite), postHandler.DeletePost)
 
 	// Posts - Interactions
 	protected.Post("/posts/:id/like",
+		scope(model.ScopePostsWrite),
 		middleware.RateLimit(rateLimitSvc, "like_post", 50, 1*time.Minute),
 		postHandler.LikePost)
-	protected.Delete("/posts/:id/unlike", postHandler.UnlikePost)
+	protected.Delete("/posts/:id/unlike", scope(model.ScopePostsWrite), postHandler.UnlikePost)
 
 	protected.Post("/posts/:id/repost",
+		scope(model.ScopePostsWrite),
 		middleware.RateLimit(rateLimitSvc, "repost_post", 30, 1*time.Minute),
 		postHandler.RepostPost)
-	protected.Delete("/posts/:id/unrepost", postHandler.UndoRepost)
+	protected.Delete("/posts/:id/unrepost", scope(model.ScopePostsWrite), postHandler.UndoRepost)
+
+	protected.Post("/posts/:id/poll/vote",
+		scope(model.ScopePostsWrite),
+		middleware.RateLimit(rateLimitSvc, "poll_vote", 30, 1*time.Minute),
+		pollHandler.Vote)
+
+	protected.Post("/posts/:id/pin", scope(model.ScopePostsWrite), postHandler.PinPost)
+	protected.Delete("/posts/:id/pin", scope(model.ScopePostsWrite), postHandler.UnpinPost)
+
+	protected.Post("/posts/:id/bookmark", scope(model.ScopePostsWrite), bookmarkHandler.AddBookmark)
+	protected.Delete("/posts/:id/bookmark", scope(model.ScopePostsWrite), bookmarkHandler.RemoveBookmark)
 
 	// Notifications
 	protected.Get("/notifications",
+		scope(model.ScopeNotificationsRead),
 		middleware.RateLimit(rateLimitSvc, "get_notifications", 100, 1*time.Minute),
 		userHandler.GetNotifications)
-	protected.Put("/notifications/:id/read", userHandler.MarkNotificationAsRead)
-	protected.Delete("/notifications/:id", userHandler.DeleteNotification)
+	protected.Put("/notifications/:id/read", scope(model.ScopeNotificationsWrite), userHandler.MarkNotificationAsRead)
+	protected.Delete("/notifications/:id", scope(model.ScopeNotificationsWrite), userHandler.DeleteNotification)
 
 	// Search
-	protected.Get("/search/posts", postHandler.SearchPosts)
-	protected.Get("/search/users", userHandler.SearchUsers)
+	protected.Get("/search/posts", scope(model.ScopePostsRead), postHandler.SearchPosts)
+	protected.Get("/search/users", scope(model.ScopeUsersRead), userHandler.SearchUsers)
 
 	// ============ ADMIN ROUTES (Requires Admin Role) ============
-	admin := protected.Group("/admin", middleware.RequireRole("ADMIN"))
+	admin := protected.Group("/admin", session, middleware.RequireRole("ADMIN"))
 
 	admin.Get("/users", userHandler.GetAllUsers)
-	admin.Delete("/users/:id", userHandler.AdminDeleteUser)
-	admin.Put("/users/:id/role", userHandler.UpdateUserRole)
+	admin.Delete("/users/:id", adminHandler.AdminDeleteUser)
+	admin.Put("/users/:id/role", adminHandler.UpdateUserRole)
+	admin.Post("/users/:id/restore", adminHandler.RestoreUser)
 
 	admin.Get("/posts", postHandler.GetAllPosts)
-	admin.Delete("/posts/:id", postHandler.AdminDeletePost)
+	admin.Delete("/posts/:id", adminHandler.AdminDeletePost)
+	admin.Post("/posts/:id/restore", adminHandler.RestorePost)
+
+	admin.Get("/stats", statsHandler.GetSystemStats)
+
+	admin.Get("/audit", adminHandler.GetAuditLogs)
+	admin.Get("/audit/export", adminHandler.ExportAuditLogs)
+	admin.Get("/audit/verify", adminHandler.VerifyAuditLogs)
 
-	admin.Get("/stats", userHandler.GetSystemStats)
+	return nil
 }
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"goServer/internal/config"
+	"goServer/internal/model"
+	"goServer/internal/repository"
+	"goServer/internal/tracing"
+	"goServer/pkg/utils"
+)
+
+// lastUsedInterval bounds how often a token's last-used time is written
+const lastUsedInterval = time.Minute
+
+var ErrInvalidAccessToken = errors.New("invalid or revoked access token")
+
+// AccessTokenService manages personal access tokens, which let scripts and
+// bots use the API without a password or an interactive session
+type AccessTokenService struct {
+	tokenRepo repository.PersonalAccessTokenRepository
+	userRepo  repository.UserRepository
+	cfg       config.Config
+}
+
+func NewAccessTokenService(tr repository.PersonalAccessTokenRepository, ur repository.UserRepository, cfg config.Config) *AccessTokenService {
+	return &AccessTokenService{
+		tokenRepo: tr,
+		userRepo:  ur,
+		cfg:       cfg,
+	}
+}
+
+// Create issues a new token. The plaintext token is returned here and
+// never again; only its hash is kept.
+func (s *AccessTokenService) Create(ctx context.Context, userID, name string, scopes []string, expiresInDays int) (*model.PersonalAccessToken, string, error) {
+	ctx, span := tracing.Start(ctx, "AccessTokenService.Create")
+	defer span.End()
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", errors.New("token name is required")
+	}
+
+	scopes, err := normalizeScopes(scopes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if expiresInDays < 0 {
+		return nil, "", errors.New("expires_in_days cannot be negative")
+	}
+	var expiresAt *time.Time
+	if expiresInDays > 0 {
+		ttl := time.Duration(expiresInDays) * 24 * time.Hour
+		if s.cfg.AccessTokenMaxTTL > 0 && ttl > s.cfg.AccessTokenMaxTTL {
+			return nil, "", fmt.Errorf("tokens can be valid for at most %d days", int(s.cfg.AccessTokenMaxTTL.Hours()/24))
+		}
+		t := time.Now().Add(ttl)
+		expiresAt = &t
+	} else if s.cfg.AccessTokenMaxTTL > 0 {
+		return nil, "", fmt.Errorf("tokens must expire within %d days", int(s.cfg.AccessTokenMaxTTL.Hours()/24))
+	}
+
+	count, err := s.tokenRepo.CountActive(ctx, userID)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to count tokens: %w", err)
+	}
+	if s.cfg.AccessTokenMaxPerUser > 0 && count >= int64(s.cfg.AccessTokenMaxPerUser) {
+		return nil, "", fmt.Errorf("you can have at most %d active tokens", s.cfg.AccessTokenMaxPerUser)
+	}
+
+	secret, err := utils.RandomToken(32)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	plain := model.PATPrefix + secret
+
+	token := &model.PersonalAccessToken{
+		UserID:    userID,
+		Name:      name,
+		TokenHash: utils.HashToken(plain),
+		Prefix:    plain[:len(model.PATPrefix)+6],
+		Scopes:    strings.Join(scopes, " "),
+		ExpiresAt: expiresAt,
+	}
+	if err := s.tokenRepo.Create(ctx, token); err != nil {
+		return nil, "", fmt.Errorf("failed to create token: %w", err)
+	}
+
+	return token, plain, nil
+}
+
+// List returns the user's tokens, including expired and revoked ones
+func (s *AccessTokenService) List(ctx context.Context, userID string) ([]model.PersonalAccessToken, error) {
+	ctx, span := tracing.Start(ctx, "AccessTokenService.List")
+	defer span.End()
+
+	return s.tokenRepo.ListForUser(ctx, userID)
+}
+
+// Revoke revokes one of the user's tokens
+func (s *AccessTokenService) Revoke(ctx context.Context, userID, id string) error {
+	ctx, span := tracing.Start(ctx, "AccessTokenService.Revoke")
+	defer span.End()
+
+	revoked, err := s.tokenRepo.Revoke(ctx, userID, id)
+	if err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+	if !revoked {
+		return errors.New("token not found")
+	}
+	return nil
+}
+
+// AuthenticateAccessToken resolves a token presented as a bearer credential
+// to its owner and granted scopes. It satisfies middleware.AccessTokenAuthenticator.
+func (s *AccessTokenService) AuthenticateAccessToken(ctx context.Context, token, ip string) (string, string, []string, error) {
+	ctx, span := tracing.Start(ctx, "AccessTokenService.AuthenticateAccessToken")
+	defer span.End()
+
+	pat, err := s.tokenRepo.FindActiveByHash(ctx, utils.HashToken(token))
+	if err != nil {
+		return "", "", nil, fmt.Errorf("failed to find token: %w", err)
+	}
+	// Tokens stop working while the account is scheduled for deletion
+	if pat == nil || pat.User.ID == "" || pat.User.DeletionScheduledAt != nil {
+		return "", "", nil, ErrInvalidAccessToken
+	}
+
+	if err := s.tokenRepo.TouchLastUsed(ctx, pat.ID, ip, lastUsedInterval); err != nil {
+		return "", "", nil, fmt.Errorf("failed to record token use: %w", err)
+	}
+
+	return pat.UserID, pat.User.Role, pat.ScopeList(), nil
+}
+
+// normalizeScopes validates requested scopes and removes duplicates.
+// Write access to a resource implies read access to it.
+func normalizeScopes(requested []string) ([]string, error) {
+	known := make(map[string]bool, len(model.Scopes))
+	for _, s := range model.Scopes {
+		known[s] = true
+	}
+
+	granted := make(map[string]bool)
+	for _, s := range requested {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if !known[s] {
+			return nil, fmt.Errorf("unknown scope: %s", s)
+		}
+		granted[s] = true
+		if resource, ok := strings.CutSuffix(s, ":write"); ok {
+			granted[resource+":read"] = true
+		}
+	}
+	if len(granted) == 0 {
+		return nil, errors.New("at least one scope is required")
+	}
+
+	scopes := make([]string, 0, len(granted))
+	for _, s := range model.Scopes {
+		if granted[s] {
+			scopes = append(scopes, s)
+		}
+	}
+	return scopes, nil
+}
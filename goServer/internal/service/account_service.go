@@ -0,0 +1,275 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"goServer/internal/config"
+	"goServer/internal/mailer"
+	"goServer/internal/model"
+	"goServer/internal/repository"
+	"goServer/internal/tracing"
+	"goServer/pkg/utils"
+
+	"gorm.io/gorm"
+)
+
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// AccountService handles email verification and password recovery
+type AccountService struct {
+	userRepo  repository.UserRepository
+	tokenRepo repository.TokenRepository
+	mailer    mailer.Mailer
+	hasher    utils.PasswordHasher
+	policy    *utils.PasswordPolicy
+	cfg       config.Config
+}
+
+func NewAccountService(ur repository.UserRepository, tr repository.TokenRepository, m mailer.Mailer, hasher utils.PasswordHasher, policy *utils.PasswordPolicy, cfg config.Config) *AccountService {
+	return &AccountService{
+		userRepo:  ur,
+		tokenRepo: tr,
+		mailer:    m,
+		hasher:    hasher,
+		policy:    policy,
+		cfg:       cfg,
+	}
+}
+
+// SendVerificationEmail issues a fresh verification token and mails it,
+// invalidating any token sent earlier
+func (s *AccountService) SendVerificationEmail(ctx context.Context, userID string) error {
+	ctx, span := tracing.Start(ctx, "AccountService.SendVerificationEmail")
+	defer span.End()
+
+	if userID == "" {
+		return errors.New("user id is required")
+	}
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to find user: %w", err)
+	}
+	if user == nil {
+		return errors.New("user not found")
+	}
+	if user.EmailVerifiedAt != nil {
+		return errors.New("email already verified")
+	}
+
+	token, err := s.issue(ctx, user.ID, model.TokenPurposeEmailVerify, s.cfg.EmailVerifyTTL)
+	if err != nil {
+		return err
+	}
+
+	link := s.cfg.AppBaseURL + "/api/v1/auth/verify?token=" + url.QueryEscape(token)
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\r\n\r\nConfirm your email address by opening the link below:\r\n\r\n%s\r\n\r\n"+
+			"The link expires in %s. If you didn't create an account, ignore this email.\r\n",
+			user.Username, link, s.cfg.EmailVerifyTTL),
+	})
+}
+
+// VerifyEmail consumes a verification token and marks the email as verified
+func (s *AccountService) VerifyEmail(ctx context.Context, token string) error {
+	ctx, span := tracing.Start(ctx, "AccountService.VerifyEmail")
+	defer span.End()
+
+	if !utils.VerifySignedToken(s.cfg.JWTSecret, model.TokenPurposeEmailVerify, token) {
+		return ErrInvalidToken
+	}
+
+	return s.tokenRepo.Transaction(ctx, func(tx *gorm.DB) error {
+		t, err := s.tokenRepo.WithTx(tx).Consume(ctx, utils.HashToken(token), model.TokenPurposeEmailVerify)
+		if err != nil {
+			return fmt.Errorf("failed to consume token: %w", err)
+		}
+		if t == nil {
+			return ErrInvalidToken
+		}
+
+		if err := s.userRepo.WithTx(tx).MarkEmailVerified(ctx, t.UserID); err != nil {
+			return fmt.Errorf("failed to verify email: %w", err)
+		}
+		return nil
+	})
+}
+
+// ForgotPassword mails a reset link if the email belongs to an account.
+// It reports success either way so the endpoint can't be used to probe for
+// accounts; the link is issued and sent in the background so the response
+// time doesn't give it away either.
+func (s *AccountService) ForgotPassword(ctx context.Context, email string) error {
+	ctx, span := tracing.Start(ctx, "AccountService.ForgotPassword")
+	defer span.End()
+
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return errors.New("email is required")
+	}
+
+	user, err := s.userRepo.FindByEmail(ctx, email)
+	if err != nil {
+		return fmt.Errorf("failed to find user: %w", err)
+	}
+	if user == nil {
+		return nil
+	}
+
+	go s.sendPasswordReset(context.WithoutCancel(ctx), user)
+	return nil
+}
+
+func (s *AccountService) sendPasswordReset(ctx context.Context, user *model.User) {
+	token, err := s.issue(ctx, user.ID, model.TokenPurposePasswordReset, s.cfg.PasswordResetTTL)
+	if err != nil {
+		log.Printf("[account] failed to issue reset token for user %s: %v", user.ID, err)
+		return
+	}
+
+	link := s.cfg.AppBaseURL + "/reset-password?token=" + url.QueryEscape(token)
+	if err := s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\r\n\r\nSomeone asked to reset the password for your account. "+
+			"Use the link below to choose a new one:\r\n\r\n%s\r\n\r\n"+
+			"The link expires in %s and can only be used once. If this wasn't you, ignore this email.\r\n",
+			user.Username, link, s.cfg.PasswordResetTTL),
+	}); err != nil {
+		log.Printf("[account] failed to send reset email to user %s: %v", user.ID, err)
+	}
+}
+
+// ResetPassword consumes a reset token, sets the new password and revokes
+// every token issued to the user before the reset
+func (s *AccountService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	ctx, span := tracing.Start(ctx, "AccountService.ResetPassword")
+	defer span.End()
+
+	if !utils.VerifySignedToken(s.cfg.JWTSecret, model.TokenPurposePasswordReset, token) {
+		return ErrInvalidToken
+	}
+
+	return s.tokenRepo.Transaction(ctx, func(tx *gorm.DB) error {
+		tokenRepo := s.tokenRepo.WithTx(tx)
+
+		t, err := tokenRepo.Consume(ctx, utils.HashToken(token), model.TokenPurposePasswordReset)
+		if err != nil {
+			return fmt.Errorf("failed to consume token: %w", err)
+		}
+		if t == nil {
+			return ErrInvalidToken
+		}
+
+		// The policy needs the account's username and email, so it runs
+		// once the token is known to be good; a rejected password rolls
+		// the consumption back and the link stays usable
+		user, err := s.userRepo.WithTx(tx).FindByID(ctx, t.UserID)
+		if err != nil {
+			return fmt.Errorf("failed to find user: %w", err)
+		}
+		if user == nil {
+			return ErrInvalidToken
+		}
+		if err := s.policy.Validate(newPassword, user.Username, user.Email); err != nil {
+			return err
+		}
+
+		hashedPassword, err := s.hasher.Hash(newPassword)
+		if err != nil {
+			return fmt.Errorf("failed to hash password: %w", err)
+		}
+
+		if err := s.userRepo.WithTx(tx).UpdatePassword(ctx, t.UserID, hashedPassword); err != nil {
+			return fmt.Errorf("failed to update password: %w", err)
+		}
+
+		// Any other reset links that were sent are now stale
+		if err := tokenRepo.InvalidateForUser(ctx, t.UserID, model.TokenPurposePasswordReset); err != nil {
+			return fmt.Errorf("failed to invalidate tokens: %w", err)
+		}
+		return nil
+	})
+}
+
+// ScheduleDeletion schedules the account for deletion after the grace
+// period and logs the user out everywhere. Logging in again before then
+// cancels it.
+func (s *AccountService) ScheduleDeletion(ctx context.Context, userID string) (time.Time, error) {
+	ctx, span := tracing.Start(ctx, "AccountService.ScheduleDeletion")
+	defer span.End()
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to find user: %w", err)
+	}
+	if user == nil {
+		return time.Time{}, errors.New("user not found")
+	}
+
+	at := time.Now().Add(s.cfg.AccountDeletionGrace)
+	if user.DeletionScheduledAt != nil {
+		at = *user.DeletionScheduledAt
+	}
+	if err := s.userRepo.ScheduleDeletion(ctx, userID, at); err != nil {
+		return time.Time{}, fmt.Errorf("failed to schedule deletion: %w", err)
+	}
+
+	if err := s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Your account is scheduled for deletion",
+		Body: fmt.Sprintf("Hi %s,\r\n\r\nYour account and everything in it will be permanently deleted on %s.\r\n\r\n"+
+			"Changed your mind? Just log in before then and the deletion is cancelled.\r\n",
+			user.Username, at.Format(time.RFC1123)),
+	}); err != nil {
+		log.Printf("[account] failed to send deletion email to user %s: %v", user.ID, err)
+	}
+
+	return at, nil
+}
+
+// CancelDeletion cancels a scheduled deletion, reporting whether one was pending
+func (s *AccountService) CancelDeletion(ctx context.Context, userID string) (bool, error) {
+	ctx, span := tracing.Start(ctx, "AccountService.CancelDeletion")
+	defer span.End()
+
+	cancelled, err := s.userRepo.CancelDeletion(ctx, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to cancel deletion: %w", err)
+	}
+	return cancelled, nil
+}
+
+// issue creates a new single-use token for the user, replacing older ones of the same purpose
+func (s *AccountService) issue(ctx context.Context, userID, purpose string, ttl time.Duration) (string, error) {
+	token, hash, err := utils.GenerateSignedToken(s.cfg.JWTSecret, purpose)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	err = s.tokenRepo.Transaction(ctx, func(tx *gorm.DB) error {
+		tokenRepo := s.tokenRepo.WithTx(tx)
+		if err := tokenRepo.InvalidateForUser(ctx, userID, purpose); err != nil {
+			return err
+		}
+		return tokenRepo.Create(ctx, &model.UserToken{
+			UserID:    userID,
+			Purpose:   purpose,
+			TokenHash: hash,
+			ExpiresAt: time.Now().Add(ttl),
+		})
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to store token: %w", err)
+	}
+
+	return token, nil
+}
@@ -0,0 +1,380 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"goServer/internal/dto"
+	"goServer/internal/model"
+	"goServer/internal/repository"
+	"goServer/internal/tracing"
+
+	"gorm.io/gorm"
+)
+
+// AuditActor identifies who performed a moderation action and from where
+type AuditActor struct {
+	ID        string
+	IP        string
+	UserAgent string
+}
+
+// AuditVerification is the result of walking the audit hash chain
+type AuditVerification struct {
+	Valid    bool   `json:"valid"`
+	Checked  int64  `json:"checked"`
+	BrokenAt *int64 `json:"broken_at,omitempty"`
+	Problem  string `json:"problem,omitempty"`
+}
+
+type AdminService struct {
+	auditRepo repository.AuditRepository
+	userRepo  repository.UserRepository
+	postRepo  repository.PostRepository
+}
+
+func NewAdminService(ar repository.AuditRepository, ur repository.UserRepository, pr repository.PostRepository) *AdminService {
+	return &AdminService{auditRepo: ar, userRepo: ur, postRepo: pr}
+}
+
+// DeleteUser deletes a user and records the action in the audit log
+func (s *AdminService) DeleteUser(ctx context.Context, actor AuditActor, userID, reason string) error {
+	ctx, span := tracing.Start(ctx, "AdminService.DeleteUser")
+	defer span.End()
+
+	if userID == "" || reason == "" {
+		return errors.New("user id and reason are required")
+	}
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to find user: %w", err)
+	}
+	if user == nil {
+		return errors.New("user not found")
+	}
+
+	entry := &model.AuditLog{
+		Action:     model.AuditActionDeleteUser,
+		TargetType: model.AuditTargetUser,
+		TargetID:   userID,
+		Reason:     reason,
+		Before:     userSnapshot(user),
+	}
+
+	return s.audited(ctx, actor, entry, func(tx *gorm.DB) error {
+		if err := s.userRepo.WithTx(tx).Delete(ctx, userID); err != nil {
+			return fmt.Errorf("failed to delete user: %w", err)
+		}
+		return nil
+	})
+}
+
+// DeletePost deletes any post and records the action in the audit log
+func (s *AdminService) DeletePost(ctx context.Context, actor AuditActor, postID, reason string) error {
+	ctx, span := tracing.Start(ctx, "AdminService.DeletePost")
+	defer span.End()
+
+	if postID == "" || reason == "" {
+		return errors.New("post id and reason are required")
+	}
+
+	post, err := s.postRepo.FindByID(ctx, postID)
+	if err != nil {
+		return fmt.Errorf("failed to find post: %w", err)
+	}
+	if post == nil {
+		return errors.New("post not found")
+	}
+
+	entry := &model.AuditLog{
+		Action:     model.AuditActionDeletePost,
+		TargetType: model.AuditTargetPost,
+		TargetID:   postID,
+		Reason:     reason,
+		Before:     postSnapshot(post),
+	}
+
+	return s.audited(ctx, actor, entry, func(tx *gorm.DB) error {
+		if err := s.postRepo.WithTx(tx).Delete(ctx, postID); err != nil {
+			return fmt.Errorf("failed to delete post: %w", err)
+		}
+		return nil
+	})
+}
+
+// UpdateUserRole changes a user's role and records the action in the audit log
+func (s *AdminService) UpdateUserRole(ctx context.Context, actor AuditActor, userID, role, reason string) (*model.User, error) {
+	ctx, span := tracing.Start(ctx, "AdminService.UpdateUserRole")
+	defer span.End()
+
+	if userID == "" || role == "" {
+		return nil, errors.New("user id and role are required")
+	}
+
+	if role != string(model.RoleUser) && role != string(model.RoleAdmin) {
+		return nil, errors.New("invalid role")
+	}
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+
+	entry := &model.AuditLog{
+		Action:     model.AuditActionUpdateUserRole,
+		TargetType: model.AuditTargetUser,
+		TargetID:   userID,
+		Reason:     reason,
+		Before:     userSnapshot(user),
+	}
+
+	user.Role = role
+	entry.After = userSnapshot(user)
+
+	if err := s.audited(ctx, actor, entry, func(tx *gorm.DB) error {
+		if err := s.userRepo.WithTx(tx).Update(ctx, user); err != nil {
+			return fmt.Errorf("failed to update user role: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// RestoreUser undoes a soft delete of a user and the content removed with them
+func (s *AdminService) RestoreUser(ctx context.Context, actor AuditActor, userID, reason string) (*model.User, error) {
+	ctx, span := tracing.Start(ctx, "AdminService.RestoreUser")
+	defer span.End()
+
+	if userID == "" {
+		return nil, errors.New("user id is required")
+	}
+
+	user, err := s.userRepo.FindByIDWithDeleted(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+	if !user.DeletedAt.Valid {
+		return nil, errors.New("user is not deleted")
+	}
+
+	entry := &model.AuditLog{
+		Action:     model.AuditActionRestoreUser,
+		TargetType: model.AuditTargetUser,
+		TargetID:   userID,
+		Reason:     reason,
+		After:      userSnapshot(user),
+	}
+
+	if err := s.audited(ctx, actor, entry, func(tx *gorm.DB) error {
+		if err := s.userRepo.WithTx(tx).Restore(ctx, userID); err != nil {
+			return fmt.Errorf("failed to restore user: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	user.DeletedAt = gorm.DeletedAt{}
+	return user, nil
+}
+
+// RestorePost undoes a soft delete of a post
+func (s *AdminService) RestorePost(ctx context.Context, actor AuditActor, postID, reason string) (*model.Post, error) {
+	ctx, span := tracing.Start(ctx, "AdminService.RestorePost")
+	defer span.End()
+
+	if postID == "" {
+		return nil, errors.New("post id is required")
+	}
+
+	post, err := s.postRepo.FindByIDWithDeleted(ctx, postID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find post: %w", err)
+	}
+	if post == nil {
+		return nil, errors.New("post not found")
+	}
+	if !post.DeletedAt.Valid {
+		return nil, errors.New("post is not deleted")
+	}
+
+	entry := &model.AuditLog{
+		Action:     model.AuditActionRestorePost,
+		TargetType: model.AuditTargetPost,
+		TargetID:   postID,
+		Reason:     reason,
+		After:      postSnapshot(post),
+	}
+
+	if err := s.audited(ctx, actor, entry, func(tx *gorm.DB) error {
+		if err := s.postRepo.WithTx(tx).Restore(ctx, postID); err != nil {
+			return fmt.Errorf("failed to restore post: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	restored, err := s.postRepo.FindByID(ctx, postID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find post: %w", err)
+	}
+	if restored == nil {
+		return nil, errors.New("post not found")
+	}
+
+	return restored, nil
+}
+
+// ListAuditLogs returns audit entries matching the filter, newest first
+func (s *AdminService) ListAuditLogs(ctx context.Context, req dto.AuditFilterReq) ([]model.AuditLog, int64, error) {
+	filter, err := auditFilter(req)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	limit, offset := req.Limit, req.Offset
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	logs, total, err := s.auditRepo.List(ctx, filter, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list audit logs: %w", err)
+	}
+
+	return logs, total, nil
+}
+
+// ExportAuditLogs streams every audit entry matching the filter in chain order
+func (s *AdminService) ExportAuditLogs(ctx context.Context, req dto.AuditFilterReq, fn func(a *model.AuditLog) error) error {
+	filter, err := auditFilter(req)
+	if err != nil {
+		return err
+	}
+
+	if err := s.auditRepo.Each(ctx, filter, fn); err != nil {
+		return fmt.Errorf("failed to export audit logs: %w", err)
+	}
+	return nil
+}
+
+// VerifyAuditChain recomputes every hash in the chain and reports the first broken link
+func (s *AdminService) VerifyAuditChain(ctx context.Context) (*AuditVerification, error) {
+	ctx, span := tracing.Start(ctx, "AdminService.VerifyAuditChain")
+	defer span.End()
+
+	res := &AuditVerification{Valid: true}
+	prevHash := ""
+	var prevSeq int64
+
+	errBroken := errors.New("chain broken")
+	err := s.auditRepo.Each(ctx, repository.AuditFilter{}, func(a *model.AuditLog) error {
+		res.Checked++
+
+		switch {
+		case a.Seq != prevSeq+1:
+			res.Problem = "missing entry before this sequence number"
+		case a.PrevHash != prevHash:
+			res.Problem = "previous hash does not match"
+		case a.Hash != a.ComputeHash():
+			res.Problem = "entry content does not match its hash"
+		default:
+			prevHash = a.Hash
+			prevSeq = a.Seq
+			return nil
+		}
+
+		seq := a.Seq
+		res.Valid = false
+		res.BrokenAt = &seq
+		return errBroken
+	})
+	if err != nil && !errors.Is(err, errBroken) {
+		return nil, fmt.Errorf("failed to verify audit chain: %w", err)
+	}
+
+	return res, nil
+}
+
+// audited runs action and appends the audit entry in the same transaction
+func (s *AdminService) audited(ctx context.Context, actor AuditActor, entry *model.AuditLog, action func(tx *gorm.DB) error) error {
+	entry.ActorID = actor.ID
+	entry.IP = actor.IP
+	entry.UserAgent = actor.UserAgent
+
+	return s.auditRepo.Transaction(ctx, func(tx *gorm.DB) error {
+		if err := action(tx); err != nil {
+			return err
+		}
+		if err := s.auditRepo.WithTx(tx).Append(ctx, entry); err != nil {
+			return fmt.Errorf("failed to write audit log: %w", err)
+		}
+		return nil
+	})
+}
+
+func auditFilter(req dto.AuditFilterReq) (repository.AuditFilter, error) {
+	filter := repository.AuditFilter{
+		ActorID:    req.ActorID,
+		Action:     req.Action,
+		TargetType: req.TargetType,
+		TargetID:   req.TargetID,
+	}
+
+	if req.From != "" {
+		from, err := time.Parse(time.RFC3339, req.From)
+		if err != nil {
+			return filter, errors.New("from must be an RFC3339 timestamp")
+		}
+		filter.From = from
+	}
+	if req.To != "" {
+		to, err := time.Parse(time.RFC3339, req.To)
+		if err != nil {
+			return filter, errors.New("to must be an RFC3339 timestamp")
+		}
+		filter.To = to
+	}
+
+	return filter, nil
+}
+
+func userSnapshot(u *model.User) json.RawMessage {
+	b, _ := json.Marshal(map[string]interface{}{
+		"id":           u.ID,
+		"username":     u.Username,
+		"email":        u.Email,
+		"display_name": u.DisplayName,
+		"role":         u.Role,
+	})
+	return b
+}
+
+func postSnapshot(p *model.Post) json.RawMessage {
+	b, _ := json.Marshal(map[string]interface{}{
+		"id":             p.ID,
+		"user_id":        p.UserID,
+		"text":           p.Text,
+		"reply_to":       p.ReplyTo,
+		"quoted_post_id": p.QuotedTweetID,
+		"created_at":     p.CreatedAt,
+	})
+	return b
+}
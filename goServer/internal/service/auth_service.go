@@ -1,20 +0,0 @@
-package service
-
-import (
-	"goServer/internal/model"
-
-	"golang.org/x/crypto/bcrypt"
-	"gorm.io/gorm"
-)
-
-func Register(db *gorm.DB, user *model.User) error {
-	hashed, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
-	if err != nil {
-		return err
-	}
-
-	user.Password = string(hashed)
-	user.Role = "USER" // DEFAULT ROLE HERE
-
-	return db.Create(user).Error
-}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"goServer/internal/model"
+	"goServer/internal/repository"
+	"goServer/internal/tracing"
+)
+
+var ErrBlocked = errors.New("you can't interact with this user")
+
+// BlockService manages the accounts a user has blocked or muted
+type BlockService struct {
+	blockRepo repository.BlockRepository
+	userRepo  repository.UserRepository
+}
+
+func NewBlockService(br repository.BlockRepository, ur repository.UserRepository) *BlockService {
+	return &BlockService{
+		blockRepo: br,
+		userRepo:  ur,
+	}
+}
+
+// Block blocks a user, removing follows in both directions
+func (s *BlockService) Block(ctx context.Context, userID, targetID string) error {
+	ctx, span := tracing.Start(ctx, "BlockService.Block")
+	defer span.End()
+
+	if err := s.checkTarget(ctx, userID, targetID); err != nil {
+		return err
+	}
+
+	if err := s.blockRepo.Block(ctx, userID, targetID); err != nil {
+		return fmt.Errorf("failed to block user: %w", err)
+	}
+	return nil
+}
+
+// Unblock removes a block
+func (s *BlockService) Unblock(ctx context.Context, userID, targetID string) error {
+	ctx, span := tracing.Start(ctx, "BlockService.Unblock")
+	defer span.End()
+
+	removed, err := s.blockRepo.Unblock(ctx, userID, targetID)
+	if err != nil {
+		return fmt.Errorf("failed to unblock user: %w", err)
+	}
+	if !removed {
+		return errors.New("user is not blocked")
+	}
+	return nil
+}
+
+// GetBlocked lists the users a user blocked
+func (s *BlockService) GetBlocked(ctx context.Context, userID string, limit, offset int) ([]model.User, error) {
+	ctx, span := tracing.Start(ctx, "BlockService.GetBlocked")
+	defer span.End()
+
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	return s.blockRepo.GetBlocked(ctx, userID, limit, offset)
+}
+
+// Mute hides a user from the muter's feed and notifications
+func (s *BlockService) Mute(ctx context.Context, userID, targetID string) error {
+	ctx, span := tracing.Start(ctx, "BlockService.Mute")
+	defer span.End()
+
+	if err := s.checkTarget(ctx, userID, targetID); err != nil {
+		return err
+	}
+
+	if err := s.blockRepo.Mute(ctx, userID, targetID); err != nil {
+		return fmt.Errorf("failed to mute user: %w", err)
+	}
+	return nil
+}
+
+// Unmute removes a mute
+func (s *BlockService) Unmute(ctx context.Context, userID, targetID string) error {
+	ctx, span := tracing.Start(ctx, "BlockService.Unmute")
+	defer span.End()
+
+	removed, err := s.blockRepo.Unmute(ctx, userID, targetID)
+	if err != nil {
+		return fmt.Errorf("failed to unmute user: %w", err)
+	}
+	if !removed {
+		return errors.New("user is not muted")
+	}
+	return nil
+}
+
+// GetMuted lists the users a user muted
+func (s *BlockService) GetMuted(ctx context.Context, userID string, limit, offset int) ([]model.User, error) {
+	ctx, span := tracing.Start(ctx, "BlockService.GetMuted")
+	defer span.End()
+
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	return s.blockRepo.GetMuted(ctx, userID, limit, offset)
+}
+
+func (s *BlockService) checkTarget(ctx context.Context, userID, targetID string) error {
+	if userID == "" || targetID == "" {
+		return errors.New("user id is required")
+	}
+	if userID == targetID {
+		return errors.New("you can't block or mute yourself")
+	}
+
+	target, err := s.userRepo.FindByID(ctx, targetID)
+	if err != nil {
+		return fmt.Errorf("failed to find user: %w", err)
+	}
+	if target == nil {
+		return errors.New("user not found")
+	}
+	return nil
+}
+
+// checkNotBlocked returns ErrBlocked if either user has blocked the other.
+// An empty userID (an anonymous viewer) is never blocked.
+func checkNotBlocked(ctx context.Context, blockRepo repository.BlockRepository, userID, otherID string) error {
+	if userID == "" || otherID == "" || userID == otherID {
+		return nil
+	}
+
+	blocked, err := blockRepo.IsBlockedEitherWay(ctx, userID, otherID)
+	if err != nil {
+		return fmt.Errorf("failed to check blocks: %w", err)
+	}
+	if blocked {
+		return ErrBlocked
+	}
+	return nil
+}
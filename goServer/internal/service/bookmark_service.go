@@ -0,0 +1,244 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"goServer/internal/model"
+	"goServer/internal/repository"
+	"goServer/internal/tracing"
+	"goServer/pkg/utils"
+)
+
+// maxBookmarkCollections is how many collections a user may create
+const maxBookmarkCollections = 100
+
+var (
+	ErrCollectionNotFound    = errors.New("collection not found")
+	ErrCollectionExists      = errors.New("a collection with this name already exists")
+	ErrCollectionLimit       = errors.New("too many collections")
+	ErrInvalidCollectionName = errors.New("collection name must be 1 to 50 characters")
+)
+
+// BookmarkService manages users' private bookmarks and bookmark collections
+type BookmarkService struct {
+	bookmarkRepo repository.BookmarkRepository
+	postRepo     repository.PostRepository
+	userRepo     repository.UserRepository
+	blockRepo    repository.BlockRepository
+}
+
+func NewBookmarkService(bmr repository.BookmarkRepository, pr repository.PostRepository, ur repository.UserRepository, br repository.BlockRepository) *BookmarkService {
+	return &BookmarkService{bookmarkRepo: bmr, postRepo: pr, userRepo: ur, blockRepo: br}
+}
+
+// Add bookmarks a post the user can see, in collectionID if it isn't empty.
+// Bookmarking an already bookmarked post moves it to that collection.
+func (s *BookmarkService) Add(ctx context.Context, userID, postID, collectionID string) error {
+	ctx, span := tracing.Start(ctx, "BookmarkService.Add")
+	defer span.End()
+
+	post, err := s.postRepo.FindByID(ctx, postID)
+	if err != nil {
+		return fmt.Errorf("failed to find post: %w", err)
+	}
+	if post == nil {
+		return errors.New("post not found")
+	}
+	if err := checkNotBlocked(ctx, s.blockRepo, userID, post.UserID); err != nil {
+		return errors.New("post not found")
+	}
+	if err := checkCanSeePosts(ctx, s.userRepo, userID, &post.User); err != nil {
+		return errors.New("post not found")
+	}
+
+	var collection *string
+	if collectionID != "" {
+		if err := s.checkCollection(ctx, collectionID, userID); err != nil {
+			return err
+		}
+		collection = &collectionID
+	}
+
+	if err := s.bookmarkRepo.Add(ctx, userID, postID, collection); err != nil {
+		return fmt.Errorf("failed to bookmark post: %w", err)
+	}
+	return nil
+}
+
+// Remove deletes a bookmark. Removing a post that isn't bookmarked is not
+// an error.
+func (s *BookmarkService) Remove(ctx context.Context, userID, postID string) error {
+	ctx, span := tracing.Start(ctx, "BookmarkService.Remove")
+	defer span.End()
+
+	if _, err := s.bookmarkRepo.Remove(ctx, userID, postID); err != nil {
+		return fmt.Errorf("failed to remove bookmark: %w", err)
+	}
+	return nil
+}
+
+// List retrieves a page of the user's bookmarks, most recently bookmarked
+// first, and the cursor for the next page ("" on the last page)
+func (s *BookmarkService) List(ctx context.Context, userID string, filter repository.BookmarkFilter, cursor string, limit int) ([]model.Bookmark, string, error) {
+	ctx, span := tracing.Start(ctx, "BookmarkService.List")
+	defer span.End()
+
+	after, err := utils.DecodeCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if filter.CollectionID != "" {
+		if err := s.checkCollection(ctx, filter.CollectionID, userID); err != nil {
+			return nil, "", err
+		}
+	}
+
+	// Fetch one extra row to learn whether there is another page
+	bookmarks, err := s.bookmarkRepo.Page(ctx, userID, filter, after, limit+1)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get bookmarks: %w", err)
+	}
+
+	next := ""
+	if len(bookmarks) > limit {
+		bookmarks = bookmarks[:limit]
+		last := bookmarks[limit-1]
+		next = utils.Cursor{At: last.CreatedAt, ID: last.PostID}.Encode()
+	}
+	return bookmarks, next, nil
+}
+
+// BookmarkedIDs reports which of postIDs the user has bookmarked
+func (s *BookmarkService) BookmarkedIDs(ctx context.Context, userID string, postIDs []string) (map[string]bool, error) {
+	ctx, span := tracing.Start(ctx, "BookmarkService.BookmarkedIDs")
+	defer span.End()
+
+	return s.bookmarkRepo.BookmarkedIDs(ctx, userID, postIDs)
+}
+
+// CreateCollection creates a named bookmark collection
+func (s *BookmarkService) CreateCollection(ctx context.Context, userID, name string) (*model.BookmarkCollection, error) {
+	ctx, span := tracing.Start(ctx, "BookmarkService.CreateCollection")
+	defer span.End()
+
+	name, err := s.checkCollectionName(ctx, userID, name)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := s.bookmarkRepo.CountCollections(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count collections: %w", err)
+	}
+	if count >= maxBookmarkCollections {
+		return nil, ErrCollectionLimit
+	}
+
+	collection := &model.BookmarkCollection{UserID: userID, Name: name}
+	if err := s.bookmarkRepo.CreateCollection(ctx, collection); err != nil {
+		return nil, fmt.Errorf("failed to create collection: %w", err)
+	}
+	return collection, nil
+}
+
+// ListCollections lists the user's collections by name, with how many
+// bookmarks each holds
+func (s *BookmarkService) ListCollections(ctx context.Context, userID string) ([]model.BookmarkCollection, map[string]int64, error) {
+	ctx, span := tracing.Start(ctx, "BookmarkService.ListCollections")
+	defer span.End()
+
+	collections, err := s.bookmarkRepo.ListCollections(ctx, userID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list collections: %w", err)
+	}
+	counts, err := s.bookmarkRepo.CollectionCounts(ctx, userID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to count bookmarks: %w", err)
+	}
+	return collections, counts, nil
+}
+
+// RenameCollection renames one of the user's collections
+func (s *BookmarkService) RenameCollection(ctx context.Context, id, userID, name string) (*model.BookmarkCollection, error) {
+	ctx, span := tracing.Start(ctx, "BookmarkService.RenameCollection")
+	defer span.End()
+
+	collection, err := s.bookmarkRepo.FindCollection(ctx, id, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find collection: %w", err)
+	}
+	if collection == nil {
+		return nil, ErrCollectionNotFound
+	}
+
+	// Changing only the case of the name is allowed
+	name = strings.TrimSpace(name)
+	if !strings.EqualFold(name, collection.Name) {
+		if _, err := s.checkCollectionName(ctx, userID, name); err != nil {
+			return nil, err
+		}
+	}
+
+	renamed, err := s.bookmarkRepo.RenameCollection(ctx, id, userID, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rename collection: %w", err)
+	}
+	if !renamed {
+		return nil, ErrCollectionNotFound
+	}
+	collection.Name = name
+	return collection, nil
+}
+
+// DeleteCollection deletes one of the user's collections. Its bookmarks are
+// kept, unsorted.
+func (s *BookmarkService) DeleteCollection(ctx context.Context, id, userID string) error {
+	ctx, span := tracing.Start(ctx, "BookmarkService.DeleteCollection")
+	defer span.End()
+
+	deleted, err := s.bookmarkRepo.DeleteCollection(ctx, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete collection: %w", err)
+	}
+	if !deleted {
+		return ErrCollectionNotFound
+	}
+	return nil
+}
+
+// checkCollection returns ErrCollectionNotFound unless the collection
+// belongs to the user
+func (s *BookmarkService) checkCollection(ctx context.Context, id, userID string) error {
+	collection, err := s.bookmarkRepo.FindCollection(ctx, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to find collection: %w", err)
+	}
+	if collection == nil {
+		return ErrCollectionNotFound
+	}
+	return nil
+}
+
+// checkCollectionName trims a new collection name and checks it is valid
+// and not already used by another of the user's collections
+func (s *BookmarkService) checkCollectionName(ctx context.Context, userID, name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len([]rune(name)) > 50 {
+		return "", ErrInvalidCollectionName
+	}
+
+	existing, err := s.bookmarkRepo.FindCollectionByName(ctx, userID, name)
+	if err != nil {
+		return "", fmt.Errorf("failed to check collection name: %w", err)
+	}
+	if existing != nil {
+		return "", ErrCollectionExists
+	}
+	return name, nil
+}
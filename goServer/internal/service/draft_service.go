@@ -0,0 +1,314 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"goServer/internal/config"
+	"goServer/internal/dto"
+	"goServer/internal/model"
+	"goServer/internal/repository"
+	"goServer/internal/tracing"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrDraftNotFound   = errors.New("draft not found")
+	ErrDraftLimit      = errors.New("too many drafts; publish or discard some first")
+	ErrInvalidSchedule = errors.New("publish time must be in the future")
+)
+
+// MentionNotifier delivers a mention notification;
+// NotificationService.NotifyMention is one
+type MentionNotifier func(ctx context.Context, mentionedUserID, mentionerID string) error
+
+// DraftService keeps posts that aren't published yet. Scheduled drafts are
+// published by Run once their time comes.
+type DraftService struct {
+	draftRepo repository.DraftRepository
+	postRepo  repository.PostRepository
+	userRepo  repository.UserRepository
+	blockRepo repository.BlockRepository
+	cfg       config.Config
+}
+
+func NewDraftService(dr repository.DraftRepository, pr repository.PostRepository, ur repository.UserRepository, br repository.BlockRepository, cfg config.Config) *DraftService {
+	return &DraftService{draftRepo: dr, postRepo: pr, userRepo: ur, blockRepo: br, cfg: cfg}
+}
+
+// Create saves a new draft, scheduled if req.PublishAt is set
+func (s *DraftService) Create(ctx context.Context, userID string, req dto.CreateDraftReq) (*model.PostDraft, error) {
+	ctx, span := tracing.Start(ctx, "DraftService.Create")
+	defer span.End()
+
+	if err := checkNewPost(ctx, s.postRepo, s.userRepo, s.blockRepo, userID, req.CreatePostReq); err != nil {
+		return nil, err
+	}
+	if req.PublishAt != nil && !req.PublishAt.After(time.Now()) {
+		return nil, ErrInvalidSchedule
+	}
+
+	count, err := s.draftRepo.CountForUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count drafts: %w", err)
+	}
+	if count >= int64(s.cfg.PostMaxDrafts) {
+		return nil, ErrDraftLimit
+	}
+
+	draft := &model.PostDraft{UserID: userID}
+	setDraftContent(draft, req.CreatePostReq)
+	setDraftSchedule(draft, req.PublishAt)
+
+	if err := s.draftRepo.Create(ctx, draft); err != nil {
+		return nil, fmt.Errorf("failed to create draft: %w", err)
+	}
+	return draft, nil
+}
+
+// List returns the user's drafts, scheduled ones first by publish time
+func (s *DraftService) List(ctx context.Context, userID string) ([]model.PostDraft, error) {
+	ctx, span := tracing.Start(ctx, "DraftService.List")
+	defer span.End()
+
+	drafts, err := s.draftRepo.ListForUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list drafts: %w", err)
+	}
+	return drafts, nil
+}
+
+// Update replaces a draft's content, keeping its schedule. A draft the
+// scheduler failed to publish goes back to being an unscheduled draft.
+func (s *DraftService) Update(ctx context.Context, id, userID string, req dto.CreatePostReq) (*model.PostDraft, error) {
+	ctx, span := tracing.Start(ctx, "DraftService.Update")
+	defer span.End()
+
+	if err := checkNewPost(ctx, s.postRepo, s.userRepo, s.blockRepo, userID, req); err != nil {
+		return nil, err
+	}
+
+	return s.modify(ctx, id, userID, func(d *model.PostDraft) {
+		setDraftContent(d, req)
+		if d.Status == model.DraftStatusFailed {
+			setDraftSchedule(d, nil)
+		}
+	})
+}
+
+// Schedule sets when a draft is published. A nil publishAt unschedules it.
+func (s *DraftService) Schedule(ctx context.Context, id, userID string, publishAt *time.Time) (*model.PostDraft, error) {
+	ctx, span := tracing.Start(ctx, "DraftService.Schedule")
+	defer span.End()
+
+	if publishAt != nil && !publishAt.After(time.Now()) {
+		return nil, ErrInvalidSchedule
+	}
+
+	return s.modify(ctx, id, userID, func(d *model.PostDraft) {
+		setDraftSchedule(d, publishAt)
+	})
+}
+
+// Delete discards a draft, cancelling it if it was scheduled
+func (s *DraftService) Delete(ctx context.Context, id, userID string) error {
+	ctx, span := tracing.Start(ctx, "DraftService.Delete")
+	defer span.End()
+
+	deleted, err := s.draftRepo.Delete(ctx, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete draft: %w", err)
+	}
+	if !deleted {
+		return ErrDraftNotFound
+	}
+	return nil
+}
+
+// Publish publishes a draft now. Like CreatePost, it also returns the users
+// mentioned in the post, who should be notified.
+func (s *DraftService) Publish(ctx context.Context, id, userID string) (*model.Post, []string, error) {
+	ctx, span := tracing.Start(ctx, "DraftService.Publish")
+	defer span.End()
+
+	var post *model.Post
+	var mentioned []string
+	err := s.draftRepo.Transaction(ctx, func(tx *gorm.DB) error {
+		draft, err := s.draftRepo.WithTx(tx).LockForUser(ctx, id, userID)
+		if err != nil {
+			return fmt.Errorf("failed to find draft: %w", err)
+		}
+		if draft == nil {
+			return ErrDraftNotFound
+		}
+
+		req := draftPostReq(draft)
+		if err := checkNewPost(ctx, s.postRepo, s.userRepo, s.blockRepo, userID, req); err != nil {
+			return err
+		}
+		post, mentioned, err = s.publish(ctx, tx, draft, req)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return post, mentioned, nil
+}
+
+// Run publishes due scheduled posts every interval until ctx is cancelled,
+// passing the users mentioned in each to notify
+func (s *DraftService) Run(ctx context.Context, interval time.Duration, notify MentionNotifier) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			post, mentioned, processed, err := s.publishNext(ctx)
+			if err != nil {
+				log.Printf("[drafts] %v", err)
+				break
+			}
+			if !processed {
+				break
+			}
+			for _, id := range mentioned {
+				if err := notify(ctx, id, post.UserID); err != nil {
+					log.Printf("[drafts] failed to notify mention: %v", err)
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// publishNext publishes the scheduled draft that has been due longest,
+// reporting whether there was one. A draft that can no longer be published,
+// e.g. because the post it replies to was deleted, or whose publishing
+// fails is marked failed so it doesn't hold up the drafts due after it. The
+// returned post is nil in that case.
+func (s *DraftService) publishNext(ctx context.Context) (*model.Post, []string, bool, error) {
+	ctx, span := tracing.Start(ctx, "DraftService.publishNext")
+	defer span.End()
+
+	var post *model.Post
+	var mentioned []string
+	processed := false
+	err := s.draftRepo.Transaction(ctx, func(tx *gorm.DB) error {
+		drafts := s.draftRepo.WithTx(tx)
+		draft, err := drafts.ClaimDue(ctx, time.Now())
+		if err != nil {
+			return fmt.Errorf("failed to claim scheduled post: %w", err)
+		}
+		if draft == nil {
+			return nil
+		}
+		processed = true
+
+		req := draftPostReq(draft)
+		if err := checkNewPost(ctx, s.postRepo, s.userRepo, s.blockRepo, draft.UserID, req); err != nil {
+			log.Printf("[drafts] scheduled post %s can't be published: %v", draft.ID, err)
+			return drafts.MarkFailed(ctx, draft.ID, err.Error())
+		}
+
+		// Publish under a savepoint so a failure only undoes the post
+		// and the draft can still be marked failed
+		err = tx.Transaction(func(tx *gorm.DB) error {
+			post, mentioned, err = s.publish(ctx, tx, draft, req)
+			return err
+		})
+		if err != nil {
+			log.Printf("[drafts] failed to publish scheduled post %s: %v", draft.ID, err)
+			post, mentioned = nil, nil
+			return drafts.MarkFailed(ctx, draft.ID, "publishing failed; try publishing it again")
+		}
+		return nil
+	})
+	return post, mentioned, processed, err
+}
+
+// publish creates the post for a locked draft and deletes the draft, both
+// in tx
+func (s *DraftService) publish(ctx context.Context, tx *gorm.DB, draft *model.PostDraft, req dto.CreatePostReq) (*model.Post, []string, error) {
+	post, mentioned, err := publishPost(ctx, *s.postRepo.WithTx(tx), s.userRepo, s.blockRepo, draft.UserID, req)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := s.draftRepo.WithTx(tx).Delete(ctx, draft.ID, draft.UserID); err != nil {
+		return nil, nil, fmt.Errorf("failed to delete draft: %w", err)
+	}
+	return post, mentioned, nil
+}
+
+// modify applies change to a draft and saves it, holding the draft's lock so
+// it can't be published halfway through
+func (s *DraftService) modify(ctx context.Context, id, userID string, change func(d *model.PostDraft)) (*model.PostDraft, error) {
+	var draft *model.PostDraft
+	err := s.draftRepo.Transaction(ctx, func(tx *gorm.DB) error {
+		drafts := s.draftRepo.WithTx(tx)
+
+		var err error
+		draft, err = drafts.LockForUser(ctx, id, userID)
+		if err != nil {
+			return fmt.Errorf("failed to find draft: %w", err)
+		}
+		if draft == nil {
+			return ErrDraftNotFound
+		}
+
+		change(draft)
+		if _, err := drafts.Update(ctx, draft); err != nil {
+			return fmt.Errorf("failed to update draft: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return draft, nil
+}
+
+func setDraftContent(d *model.PostDraft, req dto.CreatePostReq) {
+	d.Text = req.Text
+	d.ReplyTo = req.ReplyTo
+	d.IsQuote = req.IsQuote
+	d.QuotedPostID = req.QuotedPostID
+	d.MediaURLs = req.MediaURLs
+	d.PollOptions = nil
+	d.PollDuration = 0
+	if req.Poll != nil {
+		d.PollOptions = req.Poll.Options
+		d.PollDuration = req.Poll.DurationMinutes
+	}
+}
+
+func setDraftSchedule(d *model.PostDraft, publishAt *time.Time) {
+	d.PublishAt = publishAt
+	d.Status = model.DraftStatusDraft
+	if publishAt != nil {
+		d.Status = model.DraftStatusScheduled
+	}
+	d.Error = ""
+}
+
+func draftPostReq(d *model.PostDraft) dto.CreatePostReq {
+	req := dto.CreatePostReq{
+		Text:         d.Text,
+		ReplyTo:      d.ReplyTo,
+		IsQuote:      d.IsQuote,
+		QuotedPostID: d.QuotedPostID,
+		MediaURLs:    d.MediaURLs,
+	}
+	if len(d.PollOptions) > 0 {
+		req.Poll = &dto.PollReq{Options: d.PollOptions, DurationMinutes: d.PollDuration}
+	}
+	return req
+}
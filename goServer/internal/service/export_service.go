@@ -0,0 +1,500 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"goServer/internal/config"
+	"goServer/internal/mailer"
+	"goServer/internal/model"
+	"goServer/internal/repository"
+	"goServer/internal/tracing"
+	"goServer/pkg/utils"
+)
+
+const (
+	exportLinkPurpose = "data-export"
+	// exportStaleAfter is how long a running export may go without finishing
+	// before another worker assumes it crashed and starts over
+	exportStaleAfter = time.Hour
+)
+
+var (
+	ErrExportInProgress = errors.New("an export is already being prepared")
+	ErrExportNotFound   = errors.New("export not found or link expired")
+)
+
+// ExportService builds downloadable archives of everything a user has
+// stored with us. Requests are queued and processed by Run in the background.
+type ExportService struct {
+	exportRepo repository.ExportRepository
+	userRepo   repository.UserRepository
+	mailer     mailer.Mailer
+	client     *http.Client
+	cfg        config.Config
+}
+
+func NewExportService(er repository.ExportRepository, ur repository.UserRepository, m mailer.Mailer, cfg config.Config) *ExportService {
+	return &ExportService{
+		exportRepo: er,
+		userRepo:   ur,
+		mailer:     m,
+		client:     utils.NewPublicHTTPClient(30 * time.Second),
+		cfg:        cfg,
+	}
+}
+
+// Request queues a new export for the user
+func (s *ExportService) Request(ctx context.Context, userID string) (*model.DataExport, error) {
+	ctx, span := tracing.Start(ctx, "ExportService.Request")
+	defer span.End()
+
+	busy, err := s.exportRepo.HasInProgress(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check exports: %w", err)
+	}
+	if busy {
+		return nil, ErrExportInProgress
+	}
+
+	export := &model.DataExport{
+		UserID: userID,
+		Status: model.ExportStatusPending,
+	}
+	if err := s.exportRepo.Create(ctx, export); err != nil {
+		return nil, fmt.Errorf("failed to create export: %w", err)
+	}
+	return export, nil
+}
+
+// List returns the user's exports
+func (s *ExportService) List(ctx context.Context, userID string) ([]model.DataExport, error) {
+	ctx, span := tracing.Start(ctx, "ExportService.List")
+	defer span.End()
+
+	return s.exportRepo.ListForUser(ctx, userID)
+}
+
+// DownloadURL returns a freshly signed link to a ready export, valid for
+// ExportLinkTTL or until the export expires, whichever is sooner
+func (s *ExportService) DownloadURL(e *model.DataExport) string {
+	if e.Status != model.ExportStatusReady || e.ExpiresAt == nil {
+		return ""
+	}
+
+	expires := time.Now().Add(s.cfg.ExportLinkTTL)
+	if e.ExpiresAt.Before(expires) {
+		expires = *e.ExpiresAt
+	}
+
+	return fmt.Sprintf("%s/api/v1/exports/%s/download?expires=%d&sig=%s",
+		s.cfg.AppBaseURL, e.ID, expires.Unix(),
+		url.QueryEscape(utils.SignExpiring(s.cfg.JWTSecret, exportLinkPurpose, e.ID, expires)))
+}
+
+// Open checks a signed download link and returns the export and the path
+// of its archive
+func (s *ExportService) Open(ctx context.Context, id string, expires int64, sig string) (*model.DataExport, string, error) {
+	ctx, span := tracing.Start(ctx, "ExportService.Open")
+	defer span.End()
+
+	if !utils.VerifyExpiring(s.cfg.JWTSecret, exportLinkPurpose, id, expires, sig) {
+		return nil, "", ErrExportNotFound
+	}
+
+	export, err := s.exportRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to find export: %w", err)
+	}
+	if export == nil || export.Status != model.ExportStatusReady ||
+		export.ExpiresAt == nil || export.ExpiresAt.Before(time.Now()) {
+		return nil, "", ErrExportNotFound
+	}
+
+	return export, s.archivePath(export.ID), nil
+}
+
+// Run processes queued exports every interval until ctx is cancelled
+func (s *ExportService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.cleanup(ctx); err != nil {
+			log.Printf("[export] cleanup: %v", err)
+		}
+		for {
+			processed, err := s.processNext(ctx)
+			if err != nil {
+				log.Printf("[export] %v", err)
+			}
+			if !processed {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// processNext builds the next queued export, reporting whether there was one
+func (s *ExportService) processNext(ctx context.Context) (bool, error) {
+	ctx, span := tracing.Start(ctx, "ExportService.processNext")
+	defer span.End()
+
+	export, err := s.exportRepo.ClaimNext(ctx, time.Now().Add(-exportStaleAfter))
+	if err != nil {
+		return false, fmt.Errorf("failed to claim export: %w", err)
+	}
+	if export == nil {
+		return false, nil
+	}
+
+	user, err := s.userRepo.FindByID(ctx, export.UserID)
+	if err != nil {
+		return true, fmt.Errorf("failed to find user: %w", err)
+	}
+	if user == nil {
+		return true, s.exportRepo.MarkFailed(ctx, export.ID, "user not found")
+	}
+
+	size, err := s.build(ctx, export, user)
+	if err != nil {
+		if markErr := s.exportRepo.MarkFailed(ctx, export.ID, "export could not be created"); markErr != nil {
+			log.Printf("[export] failed to mark export %s failed: %v", export.ID, markErr)
+		}
+		return true, fmt.Errorf("failed to build export %s: %w", export.ID, err)
+	}
+
+	expiresAt := time.Now().Add(s.cfg.ExportRetention)
+	if err := s.exportRepo.MarkReady(ctx, export.ID, size, expiresAt); err != nil {
+		return true, fmt.Errorf("failed to mark export ready: %w", err)
+	}
+	export.Status = model.ExportStatusReady
+	export.ExpiresAt = &expiresAt
+
+	if err := s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Your data export is ready",
+		Body: fmt.Sprintf("Hi %s,\r\n\r\nThe copy of your data you asked for is ready. Download it here:\r\n\r\n%s\r\n\r\n"+
+			"The link expires in %s. You can get a new link from your account settings until %s.\r\n",
+			user.Username, s.DownloadURL(export), s.cfg.ExportLinkTTL, expiresAt.Format(time.RFC1123)),
+	}); err != nil {
+		log.Printf("[export] failed to send ready email to user %s: %v", user.ID, err)
+	}
+
+	return true, nil
+}
+
+type exportProfile struct {
+	ID              string     `json:"id"`
+	Username        string     `json:"username"`
+	DisplayName     string     `json:"display_name"`
+	Email           string     `json:"email"`
+	Bio             string     `json:"bio"`
+	AvatarURL       string     `json:"avatar_url"`
+	Role            string     `json:"role"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	TwoFactor       bool       `json:"two_factor_enabled"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+type exportMedia struct {
+	ID        string `json:"id"`
+	URL       string `json:"url"`
+	MediaType string `json:"media_type"`
+	File      string `json:"file,omitempty"`  // path inside the archive
+	Error     string `json:"error,omitempty"` // why the file couldn't be included
+}
+
+type exportPost struct {
+	ID           string        `json:"id"`
+	Text         string        `json:"text"`
+	ReplyTo      *string       `json:"reply_to"`
+	QuotedPostID *string       `json:"quoted_post_id"`
+	Media        []exportMedia `json:"media"`
+	CreatedAt    time.Time     `json:"created_at"`
+	UpdatedAt    time.Time     `json:"updated_at"`
+}
+
+type exportInteraction struct {
+	PostID    string    `json:"post_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type exportFollow struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+}
+
+type exportNotification struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	Message   string    `json:"message"`
+	Read      bool      `json:"read"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// build writes the export archive and returns its size
+func (s *ExportService) build(ctx context.Context, export *model.DataExport, user *model.User) (int64, error) {
+	if err := os.MkdirAll(s.cfg.ExportDir, 0o700); err != nil {
+		return 0, err
+	}
+
+	dest := s.archivePath(export.ID)
+	tmp := dest + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp)
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	if err := s.writeArchive(ctx, zw, user); err != nil {
+		return 0, err
+	}
+	if err := zw.Close(); err != nil {
+		return 0, err
+	}
+	if err := f.Close(); err != nil {
+		return 0, err
+	}
+
+	if err := os.Rename(tmp, dest); err != nil {
+		return 0, err
+	}
+	info, err := os.Stat(dest)
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+func (s *ExportService) writeArchive(ctx context.Context, zw *zip.Writer, user *model.User) error {
+	if err := writeJSON(zw, "profile.json", exportProfile{
+		ID:              user.ID,
+		Username:        user.Username,
+		DisplayName:     user.DisplayName,
+		Email:           user.Email,
+		Bio:             user.Bio,
+		AvatarURL:       user.AvatarURL,
+		Role:            user.Role,
+		EmailVerifiedAt: user.EmailVerifiedAt,
+		TwoFactor:       user.TOTPEnabledAt != nil,
+		CreatedAt:       user.CreatedAt,
+	}); err != nil {
+		return err
+	}
+
+	posts, err := s.exportRepo.Posts(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to load posts: %w", err)
+	}
+	postsOut := make([]exportPost, len(posts))
+	for i, p := range posts {
+		media := make([]exportMedia, len(p.Media))
+		for j, m := range p.Media {
+			media[j] = exportMedia{ID: m.ID, URL: m.URL, MediaType: m.MediaType}
+			file, err := s.writeMedia(ctx, zw, m)
+			if err != nil {
+				media[j].Error = err.Error()
+			}
+			media[j].File = file
+		}
+		postsOut[i] = exportPost{
+			ID:           p.ID,
+			Text:         p.Text,
+			ReplyTo:      p.ReplyTo,
+			QuotedPostID: p.QuotedTweetID,
+			Media:        media,
+			CreatedAt:    p.CreatedAt,
+			UpdatedAt:    p.UpdatedAt,
+		}
+	}
+	if err := writeJSON(zw, "posts.json", postsOut); err != nil {
+		return err
+	}
+
+	likes, err := s.exportRepo.Likes(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to load likes: %w", err)
+	}
+	likesOut := make([]exportInteraction, len(likes))
+	for i, l := range likes {
+		likesOut[i] = exportInteraction{PostID: l.PostID, CreatedAt: l.CreatedAt}
+	}
+	if err := writeJSON(zw, "likes.json", likesOut); err != nil {
+		return err
+	}
+
+	reposts, err := s.exportRepo.Reposts(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to load reposts: %w", err)
+	}
+	repostsOut := make([]exportInteraction, len(reposts))
+	for i, r := range reposts {
+		repostsOut[i] = exportInteraction{PostID: r.PostID, CreatedAt: r.CreatedAt}
+	}
+	if err := writeJSON(zw, "reposts.json", repostsOut); err != nil {
+		return err
+	}
+
+	followers, err := s.userRepo.GetFollowers(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to load followers: %w", err)
+	}
+	following, err := s.userRepo.GetFollowing(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to load following: %w", err)
+	}
+	if err := writeJSON(zw, "follows.json", map[string][]exportFollow{
+		"followers": toExportFollows(followers),
+		"following": toExportFollows(following),
+	}); err != nil {
+		return err
+	}
+
+	notifications, err := s.exportRepo.Notifications(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to load notifications: %w", err)
+	}
+	notificationsOut := make([]exportNotification, len(notifications))
+	for i, n := range notifications {
+		notificationsOut[i] = exportNotification{ID: n.ID, Type: n.Type, Message: n.Message, Read: n.Read, CreatedAt: n.CreatedAt}
+	}
+	return writeJSON(zw, "notifications.json", notificationsOut)
+}
+
+// writeMedia downloads a media file into the archive and returns its path there
+func (s *ExportService) writeMedia(ctx context.Context, zw *zip.Writer, m model.Media) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, m.URL, nil)
+	if err != nil || (req.URL.Scheme != "http" && req.URL.Scheme != "https") {
+		return "", errors.New("unsupported media url")
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", errors.New("download failed")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("download failed with status %d", resp.StatusCode)
+	}
+
+	// Read up to one byte past the limit so oversized files are detected
+	// before anything is written to the archive
+	limit := int64(s.cfg.ExportMediaMaxBytes)
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return "", errors.New("download failed")
+	}
+	if int64(len(data)) > limit {
+		return "", errors.New("file too large to include")
+	}
+
+	name := "media/" + m.ID + mediaExtension(req.URL.Path, resp.Header.Get("Content-Type"))
+	w, err := zw.Create(name)
+	if err != nil {
+		return "", err
+	}
+	if _, err := w.Write(data); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// cleanup deletes expired exports and any archive whose export row is gone,
+// e.g. because the account was deleted
+func (s *ExportService) cleanup(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "ExportService.cleanup")
+	defer span.End()
+
+	if err := s.exportRepo.DeleteExpired(ctx, time.Now()); err != nil {
+		return fmt.Errorf("failed to delete expired exports: %w", err)
+	}
+
+	entries, err := os.ReadDir(s.cfg.ExportDir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	var ids []string
+	for _, e := range entries {
+		if id, ok := strings.CutSuffix(e.Name(), ".zip"); ok {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	existing, err := s.exportRepo.ExistingIDs(ctx, ids)
+	if err != nil {
+		return fmt.Errorf("failed to look up exports: %w", err)
+	}
+	for _, id := range ids {
+		if !existing[id] {
+			if err := os.Remove(s.archivePath(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+				log.Printf("[export] failed to remove %s: %v", id, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *ExportService) archivePath(id string) string {
+	return filepath.Join(s.cfg.ExportDir, id+".zip")
+}
+
+func writeJSON(zw *zip.Writer, name string, v interface{}) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func toExportFollows(users []model.User) []exportFollow {
+	out := make([]exportFollow, len(users))
+	for i, u := range users {
+		out[i] = exportFollow{ID: u.ID, Username: u.Username}
+	}
+	return out
+}
+
+// mediaExtension picks a file extension from the URL, falling back to the
+// response's content type
+func mediaExtension(urlPath, contentType string) string {
+	if ext := strings.ToLower(path.Ext(urlPath)); len(ext) > 1 && len(ext) <= 6 {
+		return ext
+	}
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		if exts, _ := mime.ExtensionsByType(mediaType); len(exts) > 0 {
+			return exts[0]
+		}
+	}
+	return ""
+}
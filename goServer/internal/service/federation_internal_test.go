@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"goServer/internal/config"
+	"goServer/internal/model"
+	"goServer/internal/oidc"
+	"goServer/internal/oidc/oidctest"
+	"goServer/internal/repository"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// These tests cover the callback checks that don't touch the database; the
+// full flows are in federation_service_test.go
+
+const testVerifier = "verifier-0123456789-0123456789-0123456789"
+
+func newTestProvider(t *testing.T) (*oidctest.Server, *oidc.Provider) {
+	t.Helper()
+
+	srv := oidctest.NewServer(t, "client")
+	registry := oidc.NewRegistry(config.Config{
+		AppBaseURL: "http://app.test",
+		OIDCProviders: []config.OIDCProviderConfig{
+			{Name: "mock", Issuer: srv.Issuer, ClientID: "client", ClientSecret: "secret"},
+		},
+	})
+	p, err := registry.Get("mock")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	return srv, p
+}
+
+func TestRedeemAuthRequest(t *testing.T) {
+	srv, p := newTestProvider(t)
+	now := time.Now()
+	live := &model.OIDCAuthRequest{Provider: "mock", Nonce: "nonce", CodeVerifier: testVerifier, ExpiresAt: now.Add(time.Minute)}
+
+	_, code := srv.Authorize(t, p.AuthCodeURL("state", "nonce", testVerifier), oidctest.Claims{Subject: "sub"})
+	claims, err := redeemAuthRequest(context.Background(), p, "mock", code, live, now)
+	if err != nil {
+		t.Fatalf("redeemAuthRequest: %v", err)
+	}
+	if claims.Subject != "sub" {
+		t.Errorf("Subject = %q, want sub", claims.Subject)
+	}
+}
+
+func TestRedeemAuthRequestRejectsState(t *testing.T) {
+	srv, p := newTestProvider(t)
+	now := time.Now()
+
+	for name, req := range map[string]*model.OIDCAuthRequest{
+		"unknown or replayed state": nil,
+		"other provider":            {Provider: "other", Nonce: "nonce", CodeVerifier: testVerifier, ExpiresAt: now.Add(time.Minute)},
+		"expired":                   {Provider: "mock", Nonce: "nonce", CodeVerifier: testVerifier, ExpiresAt: now.Add(-time.Second)},
+	} {
+		_, code := srv.Authorize(t, p.AuthCodeURL("state", "nonce", testVerifier), oidctest.Claims{Subject: "sub"})
+		if _, err := redeemAuthRequest(context.Background(), p, "mock", code, req, now); !errors.Is(err, ErrInvalidOIDCState) {
+			t.Errorf("%s: redeemAuthRequest = %v, want ErrInvalidOIDCState", name, err)
+		}
+	}
+}
+
+func TestRedeemAuthRequestRejectsNonce(t *testing.T) {
+	srv, p := newTestProvider(t)
+	now := time.Now()
+
+	// The ID token carries the nonce from the authorization URL, which
+	// belongs to a different request than the one being completed
+	_, code := srv.Authorize(t, p.AuthCodeURL("state", "attacker-nonce", testVerifier), oidctest.Claims{Subject: "sub"})
+	req := &model.OIDCAuthRequest{Provider: "mock", Nonce: "nonce", CodeVerifier: testVerifier, ExpiresAt: now.Add(time.Minute)}
+	if _, err := redeemAuthRequest(context.Background(), p, "mock", code, req, now); err == nil {
+		t.Error("redeemAuthRequest accepted an ID token for another nonce")
+	}
+}
+
+func TestCheckNewIdentityNeverLinksByEmail(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		claims     oidc.Claims
+		emailTaken bool
+		want       error
+	}{
+		{"verified email of an existing account", oidc.Claims{Subject: "sub", Email: "a@example.com", EmailVerified: true}, true, ErrOIDCEmailInUse},
+		{"unverified email of an existing account", oidc.Claims{Subject: "sub", Email: "a@example.com"}, true, ErrOIDCEmailInUse},
+		{"new email", oidc.Claims{Subject: "sub", Email: "a@example.com"}, false, nil},
+	} {
+		if err := checkNewIdentity(&tc.claims, tc.emailTaken); !errors.Is(err, tc.want) {
+			t.Errorf("%s: checkNewIdentity = %v, want %v", tc.name, err, tc.want)
+		}
+	}
+
+	if err := checkNewIdentity(&oidc.Claims{Subject: "sub"}, false); err == nil {
+		t.Error("checkNewIdentity allowed a signup without an email")
+	}
+}
+
+func TestSignupToken(t *testing.T) {
+	s := NewFederationService(repository.FederationRepository{}, repository.UserRepository{}, repository.UsernameHistoryRepository{}, nil,
+		config.Config{JWTSecret: "test-secret", OIDCSignupTTL: time.Minute})
+
+	token, err := s.signupToken("mock", &oidc.Claims{Subject: "sub", Email: "Alice@Example.com", EmailVerified: true, Name: "Alice"})
+	if err != nil {
+		t.Fatalf("signupToken: %v", err)
+	}
+	provider, claims, err := s.parseSignupToken(token)
+	if err != nil {
+		t.Fatalf("parseSignupToken: %v", err)
+	}
+	if provider != "mock" || claims.Subject != "sub" || claims.Email != "alice@example.com" || !claims.EmailVerified || claims.Name != "Alice" {
+		t.Errorf("parseSignupToken = %q, %+v", provider, claims)
+	}
+
+	sign := func(method jwt.SigningMethod, key any, claims jwt.MapClaims) string {
+		signed, err := jwt.NewWithClaims(method, claims).SignedString(key)
+		if err != nil {
+			t.Fatalf("SignedString: %v", err)
+		}
+		return signed
+	}
+	valid := jwt.MapClaims{"typ": "oidc_signup", "provider": "mock", "oidc_sub": "sub", "email": "a@example.com", "exp": time.Now().Add(time.Minute).Unix()}
+	with := func(key string, value any) jwt.MapClaims {
+		c := jwt.MapClaims{}
+		for k, v := range valid {
+			c[k] = v
+		}
+		c[key] = value
+		return c
+	}
+
+	for name, forged := range map[string]string{
+		"garbage":       "not-a-token",
+		"other secret":  sign(jwt.SigningMethodHS256, []byte("other-secret"), valid),
+		"unsigned":      sign(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, valid),
+		"expired":       sign(jwt.SigningMethodHS256, []byte("test-secret"), with("exp", time.Now().Add(-time.Minute).Unix())),
+		"other purpose": sign(jwt.SigningMethodHS256, []byte("test-secret"), with("typ", "mfa")),
+		"missing sub":   sign(jwt.SigningMethodHS256, []byte("test-secret"), with("oidc_sub", "")),
+		"missing email": sign(jwt.SigningMethodHS256, []byte("test-secret"), with("email", "")),
+		"tampered":      token[:len(token)-2] + "xx",
+	} {
+		if _, _, err := s.parseSignupToken(forged); !errors.Is(err, ErrInvalidOIDCState) {
+			t.Errorf("%s: parseSignupToken = %v, want ErrInvalidOIDCState", name, err)
+		}
+	}
+}
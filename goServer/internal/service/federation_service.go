@@ -0,0 +1,405 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"goServer/internal/config"
+	"goServer/internal/model"
+	"goServer/internal/oidc"
+	"goServer/internal/repository"
+	"goServer/internal/tracing"
+	"goServer/pkg/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidOIDCState = errors.New("invalid or expired login request")
+	ErrOIDCEmailInUse   = errors.New("an account with this email already exists; sign in and link the provider from your account settings")
+)
+
+// OIDCCallbackResult is the outcome of a provider callback. Exactly one of
+// User (existing account signed in), Linked, or SignupToken is set.
+type OIDCCallbackResult struct {
+	User              *model.User
+	Linked            bool
+	SignupToken       string
+	SuggestedUsername string
+	Email             string
+}
+
+// FederationService signs users in through external OpenID Connect
+// providers and manages the identities linked to each account
+type FederationService struct {
+	fedRepo     repository.FederationRepository
+	userRepo    repository.UserRepository
+	historyRepo repository.UsernameHistoryRepository
+	providers   *oidc.Registry
+	cfg         config.Config
+}
+
+func NewFederationService(fr repository.FederationRepository, ur repository.UserRepository, hr repository.UsernameHistoryRepository, providers *oidc.Registry, cfg config.Config) *FederationService {
+	return &FederationService{
+		fedRepo:     fr,
+		userRepo:    ur,
+		historyRepo: hr,
+		providers:   providers,
+		cfg:         cfg,
+	}
+}
+
+// Providers lists the configured provider names
+func (s *FederationService) Providers() []string {
+	return s.providers.Names()
+}
+
+// BeginLogin starts a sign-in and returns the provider URL to send the user to
+func (s *FederationService) BeginLogin(ctx context.Context, provider string) (string, error) {
+	ctx, span := tracing.Start(ctx, "FederationService.BeginLogin")
+	defer span.End()
+
+	return s.begin(ctx, provider, nil)
+}
+
+// BeginLink starts linking a provider account to an existing user
+func (s *FederationService) BeginLink(ctx context.Context, userID, provider string) (string, error) {
+	ctx, span := tracing.Start(ctx, "FederationService.BeginLink")
+	defer span.End()
+
+	if userID == "" {
+		return "", errors.New("user id is required")
+	}
+	return s.begin(ctx, provider, &userID)
+}
+
+// Callback completes an authorization request: it redeems the code, then
+// signs in the linked user, finishes a pending link, or hands back a
+// signup token so the user can pick a username for a new account
+func (s *FederationService) Callback(ctx context.Context, provider, code, state string) (*OIDCCallbackResult, error) {
+	ctx, span := tracing.Start(ctx, "FederationService.Callback")
+	defer span.End()
+
+	p, err := s.providers.Get(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.fedRepo.ConsumeAuthRequest(ctx, utils.HashToken(state))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load login request: %w", err)
+	}
+	claims, err := redeemAuthRequest(ctx, p, provider, code, req, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	identity, err := s.fedRepo.FindIdentity(ctx, provider, claims.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find identity: %w", err)
+	}
+
+	if req.LinkUserID != nil {
+		if err := s.link(ctx, *req.LinkUserID, provider, identity, claims); err != nil {
+			return nil, err
+		}
+		return &OIDCCallbackResult{Linked: true}, nil
+	}
+
+	if identity != nil {
+		user, err := s.userRepo.FindByID(ctx, identity.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find user: %w", err)
+		}
+		if user == nil {
+			return nil, errors.New("user not found")
+		}
+		if err := s.fedRepo.TouchLogin(ctx, identity.ID); err != nil {
+			return nil, fmt.Errorf("failed to record login: %w", err)
+		}
+		return &OIDCCallbackResult{User: user}, nil
+	}
+
+	emailTaken := false
+	if claims.Email != "" {
+		if emailTaken, err = s.userRepo.ExistsEmail(ctx, claims.Email); err != nil {
+			return nil, fmt.Errorf("failed to check email: %w", err)
+		}
+	}
+	if err := checkNewIdentity(claims, emailTaken); err != nil {
+		return nil, err
+	}
+
+	token, err := s.signupToken(provider, claims)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign signup token: %w", err)
+	}
+
+	return &OIDCCallbackResult{
+		SignupToken:       token,
+		SuggestedUsername: s.suggestUsername(ctx, claims),
+		Email:             utils.NormalizeEmail(claims.Email),
+	}, nil
+}
+
+// CompleteSignup creates the account for a first-time provider login
+// with the username the user picked
+func (s *FederationService) CompleteSignup(ctx context.Context, signupToken, username string) (*model.User, error) {
+	ctx, span := tracing.Start(ctx, "FederationService.CompleteSignup")
+	defer span.End()
+
+	provider, claims, err := s.parseSignupToken(signupToken)
+	if err != nil {
+		return nil, err
+	}
+	email := claims.Email
+
+	username = utils.NormalizeUsername(username)
+	if err := utils.ValidateUsername(username); err != nil {
+		return nil, err
+	}
+	if err := checkUsernameAvailable(ctx, s.userRepo, s.historyRepo, username, ""); err != nil {
+		return nil, err
+	}
+	exists, err := s.userRepo.ExistsEmail(ctx, email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check email: %w", err)
+	}
+	if exists {
+		return nil, ErrOIDCEmailInUse
+	}
+
+	now := time.Now()
+	user := &model.User{
+		Email:       email,
+		Username:    username,
+		DisplayName: claims.Name,
+		Role:        "USER",
+	}
+	if claims.EmailVerified {
+		user.EmailVerifiedAt = &now
+	}
+
+	err = s.fedRepo.Transaction(ctx, func(tx *gorm.DB) error {
+		if err := s.userRepo.WithTx(tx).Create(ctx, user); err != nil {
+			return err
+		}
+		return s.fedRepo.WithTx(tx).CreateIdentity(ctx, &model.FederatedIdentity{
+			UserID:      user.ID,
+			Provider:    provider,
+			Subject:     claims.Subject,
+			Email:       email,
+			LastLoginAt: &now,
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	return user, nil
+}
+
+// ListIdentities lists the provider accounts linked to a user
+func (s *FederationService) ListIdentities(ctx context.Context, userID string) ([]model.FederatedIdentity, error) {
+	ctx, span := tracing.Start(ctx, "FederationService.ListIdentities")
+	defer span.End()
+
+	identities, err := s.fedRepo.ListForUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list identities: %w", err)
+	}
+	return identities, nil
+}
+
+// Unlink removes a provider from a user, unless it's their only way to sign in
+func (s *FederationService) Unlink(ctx context.Context, userID, provider string) error {
+	ctx, span := tracing.Start(ctx, "FederationService.Unlink")
+	defer span.End()
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to find user: %w", err)
+	}
+	if user == nil {
+		return errors.New("user not found")
+	}
+
+	identities, err := s.fedRepo.ListForUser(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to list identities: %w", err)
+	}
+	if user.Password == "" && len(identities) <= 1 {
+		return errors.New("cannot unlink your only sign-in method; set a password first")
+	}
+
+	deleted, err := s.fedRepo.DeleteIdentity(ctx, userID, provider)
+	if err != nil {
+		return fmt.Errorf("failed to unlink identity: %w", err)
+	}
+	if !deleted {
+		return errors.New("provider is not linked")
+	}
+	return nil
+}
+
+// begin stores a fresh state, nonce and PKCE verifier and builds the
+// authorization URL
+func (s *FederationService) begin(ctx context.Context, provider string, linkUserID *string) (string, error) {
+	p, err := s.providers.Get(provider)
+	if err != nil {
+		return "", err
+	}
+
+	var secrets [3]string
+	for i := range secrets {
+		if secrets[i], err = utils.RandomToken(32); err != nil {
+			return "", fmt.Errorf("failed to generate state: %w", err)
+		}
+	}
+	state, nonce, verifier := secrets[0], secrets[1], secrets[2]
+
+	if err := s.fedRepo.CreateAuthRequest(ctx, &model.OIDCAuthRequest{
+		StateHash:    utils.HashToken(state),
+		Provider:     provider,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		LinkUserID:   linkUserID,
+		ExpiresAt:    time.Now().Add(s.cfg.OIDCAuthRequestTTL),
+	}); err != nil {
+		return "", fmt.Errorf("failed to store login request: %w", err)
+	}
+
+	return p.AuthCodeURL(state, nonce, verifier), nil
+}
+
+// redeemAuthRequest checks that req is a live authorization request started
+// for provider, then redeems code with its PKCE verifier. The provider
+// rejects an ID token issued for any other nonce.
+func redeemAuthRequest(ctx context.Context, p *oidc.Provider, provider, code string, req *model.OIDCAuthRequest, now time.Time) (*oidc.Claims, error) {
+	if req == nil || req.Provider != provider || !now.Before(req.ExpiresAt) {
+		return nil, ErrInvalidOIDCState
+	}
+	return p.Exchange(ctx, code, req.CodeVerifier, req.Nonce)
+}
+
+// checkNewIdentity decides whether a provider identity that isn't linked to
+// anyone may start a signup. It is never attached to a local account just
+// because the emails match, verified or not; the owner has to sign in and
+// link it themselves.
+func checkNewIdentity(claims *oidc.Claims, emailTaken bool) error {
+	if claims.Email == "" {
+		return errors.New("the identity provider did not share an email address")
+	}
+	if emailTaken {
+		return ErrOIDCEmailInUse
+	}
+	return nil
+}
+
+func (s *FederationService) link(ctx context.Context, userID, provider string, existing *model.FederatedIdentity, claims *oidc.Claims) error {
+	if existing != nil {
+		if existing.UserID == userID {
+			return nil
+		}
+		return errors.New("this provider account is already linked to another user")
+	}
+
+	identities, err := s.fedRepo.ListForUser(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to list identities: %w", err)
+	}
+	for _, id := range identities {
+		if id.Provider == provider {
+			return fmt.Errorf("a different %s account is already linked", provider)
+		}
+	}
+
+	if err := s.fedRepo.CreateIdentity(ctx, &model.FederatedIdentity{
+		UserID:   userID,
+		Provider: provider,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	}); err != nil {
+		return fmt.Errorf("failed to link identity: %w", err)
+	}
+	return nil
+}
+
+// signupToken carries the verified provider claims to CompleteSignup
+func (s *FederationService) signupToken(provider string, claims *oidc.Claims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"typ":            "oidc_signup",
+		"provider":       provider,
+		"oidc_sub":       claims.Subject,
+		"email":          utils.NormalizeEmail(claims.Email),
+		"email_verified": claims.EmailVerified,
+		"name":           claims.Name,
+		"exp":            time.Now().Add(s.cfg.OIDCSignupTTL).Unix(),
+	})
+	return token.SignedString([]byte(s.cfg.JWTSecret))
+}
+
+// parseSignupToken checks a token made by signupToken and returns the
+// provider and claims it carries
+func (s *FederationService) parseSignupToken(signupToken string) (string, *oidc.Claims, error) {
+	tok, err := jwt.Parse(signupToken, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, ErrInvalidOIDCState
+		}
+		return []byte(s.cfg.JWTSecret), nil
+	})
+	if err != nil || !tok.Valid {
+		return "", nil, ErrInvalidOIDCState
+	}
+
+	mc := tok.Claims.(jwt.MapClaims)
+	typ, _ := mc["typ"].(string)
+	provider, _ := mc["provider"].(string)
+	claims := &oidc.Claims{}
+	claims.Subject, _ = mc["oidc_sub"].(string)
+	claims.Email, _ = mc["email"].(string)
+	claims.EmailVerified, _ = mc["email_verified"].(bool)
+	claims.Name, _ = mc["name"].(string)
+	if typ != "oidc_signup" || provider == "" || claims.Subject == "" || claims.Email == "" {
+		return "", nil, ErrInvalidOIDCState
+	}
+	return provider, claims, nil
+}
+
+var usernameUnsafeChars = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// suggestUsername derives an available handle from the provider's preferred
+// username or the email's local part. It returns "" if nothing fits.
+func (s *FederationService) suggestUsername(ctx context.Context, claims *oidc.Claims) string {
+	local, _, _ := strings.Cut(claims.Email, "@")
+	for _, candidate := range []string{claims.PreferredUsername, local} {
+		base := usernameUnsafeChars.ReplaceAllString(utils.NormalizeUsername(candidate), "_")
+		base = strings.Trim(base, "_")
+		if len(base) > 24 {
+			base = base[:24]
+		}
+		if utils.ValidateUsername(base) != nil {
+			continue
+		}
+
+		if checkUsernameAvailable(ctx, s.userRepo, s.historyRepo, base, "") == nil {
+			return base
+		}
+		for i := 0; i < 3; i++ {
+			suffix, err := utils.RandomToken(3)
+			if err != nil {
+				break
+			}
+			name := base + "_" + usernameUnsafeChars.ReplaceAllString(strings.ToLower(suffix), "")
+			if utils.ValidateUsername(name) == nil && checkUsernameAvailable(ctx, s.userRepo, s.historyRepo, name, "") == nil {
+				return name
+			}
+		}
+	}
+	return ""
+}
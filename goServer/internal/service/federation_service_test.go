@@ -0,0 +1,277 @@
+package service_test
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"goServer/internal/config"
+	"goServer/internal/db"
+	"goServer/internal/model"
+	"goServer/internal/oidc"
+	"goServer/internal/oidc/oidctest"
+	"goServer/internal/repository"
+	"goServer/internal/service"
+
+	"gorm.io/gorm"
+)
+
+var (
+	testDBOnce sync.Once
+	testDB     *gorm.DB
+	testDBErr  error
+)
+
+// openTestDB connects to the database in TEST_DATABASE_URL, skipping the
+// test when it isn't set. Tests share it, so they use unique names and
+// emails instead of cleaning up.
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	testDBOnce.Do(func() {
+		if testDB, testDBErr = db.Connect(config.Config{DatabaseURL: url}); testDBErr != nil {
+			return
+		}
+		testDBErr = testDB.AutoMigrate(&model.UsernameHistory{}, &model.FederatedIdentity{}, &model.OIDCAuthRequest{})
+	})
+	if testDBErr != nil {
+		t.Fatalf("failed to set up test database: %v", testDBErr)
+	}
+	return testDB
+}
+
+// unique returns a name no other test run has used
+func unique(prefix string) string {
+	return prefix + strings.ToLower(rand.Text()[:10])
+}
+
+type federationFixture struct {
+	svc      *service.FederationService
+	userRepo *repository.UserRepository
+	provider *oidctest.Server
+}
+
+// newFederationFixture wires a FederationService to the test database and a
+// mock issuer registered twice, as "mock" and "other"
+func newFederationFixture(t *testing.T) *federationFixture {
+	t.Helper()
+
+	database := openTestDB(t)
+	provider := oidctest.NewServer(t, "client")
+	cfg := config.Config{
+		JWTSecret:  "test-secret",
+		AppBaseURL: "http://app.test",
+		OIDCProviders: []config.OIDCProviderConfig{
+			{Name: "mock", Issuer: provider.Issuer, ClientID: "client", ClientSecret: "secret"},
+			{Name: "other", Issuer: provider.Issuer, ClientID: "client", ClientSecret: "secret"},
+		},
+		OIDCAuthRequestTTL: 10 * time.Minute,
+		OIDCSignupTTL:      15 * time.Minute,
+	}
+
+	userRepo := repository.NewUserRepository(database)
+	svc := service.NewFederationService(
+		*repository.NewFederationRepository(database),
+		*userRepo,
+		*repository.NewUsernameHistoryRepository(database),
+		oidc.NewRegistry(cfg),
+		cfg,
+	)
+	return &federationFixture{svc: svc, userRepo: userRepo, provider: provider}
+}
+
+// login runs a sign-in through the mock issuer as claims, returning the
+// state and code the callback receives
+func (f *federationFixture) login(t *testing.T, provider string, claims oidctest.Claims) (state, code string) {
+	t.Helper()
+
+	authURL, err := f.svc.BeginLogin(context.Background(), provider)
+	if err != nil {
+		t.Fatalf("BeginLogin: %v", err)
+	}
+	return f.provider.Authorize(t, authURL, claims)
+}
+
+// link runs a provider link for userID through the mock issuer as claims
+func (f *federationFixture) link(t *testing.T, userID, provider string, claims oidctest.Claims) (*service.OIDCCallbackResult, error) {
+	t.Helper()
+
+	authURL, err := f.svc.BeginLink(context.Background(), userID, provider)
+	if err != nil {
+		t.Fatalf("BeginLink: %v", err)
+	}
+	state, code := f.provider.Authorize(t, authURL, claims)
+	return f.svc.Callback(context.Background(), provider, code, state)
+}
+
+func (f *federationFixture) createUser(t *testing.T, password string) *model.User {
+	t.Helper()
+
+	name := unique("u_")
+	user := &model.User{Email: name + "@example.com", Username: name, Password: password, Role: "USER"}
+	if err := f.userRepo.Create(context.Background(), user); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	return user
+}
+
+func TestFederationSignupAndLogin(t *testing.T) {
+	f := newFederationFixture(t)
+	ctx := context.Background()
+
+	name := unique("new_")
+	claims := oidctest.Claims{
+		Subject:           unique("sub-"),
+		Email:             strings.ToUpper(name) + "@Example.com",
+		EmailVerified:     true,
+		Name:              "New User",
+		PreferredUsername: name,
+	}
+
+	state, code := f.login(t, "mock", claims)
+	res, err := f.svc.Callback(ctx, "mock", code, state)
+	if err != nil {
+		t.Fatalf("Callback: %v", err)
+	}
+	if res.User != nil || res.Linked || res.SignupToken == "" {
+		t.Fatalf("Callback = %+v, want a signup token", res)
+	}
+	if res.Email != name+"@example.com" {
+		t.Errorf("Email = %q, want the normalized provider email", res.Email)
+	}
+	if res.SuggestedUsername != name {
+		t.Errorf("SuggestedUsername = %q, want %q", res.SuggestedUsername, name)
+	}
+
+	user, err := f.svc.CompleteSignup(ctx, res.SignupToken, res.SuggestedUsername)
+	if err != nil {
+		t.Fatalf("CompleteSignup: %v", err)
+	}
+	if user.Username != name || user.Email != name+"@example.com" || user.DisplayName != "New User" {
+		t.Errorf("CompleteSignup created %+v", user)
+	}
+	if user.EmailVerifiedAt == nil {
+		t.Error("CompleteSignup didn't trust the provider's verified email")
+	}
+
+	if _, err := f.svc.CompleteSignup(ctx, res.SignupToken, unique("again_")); !errors.Is(err, service.ErrOIDCEmailInUse) {
+		t.Errorf("reusing the signup token = %v, want ErrOIDCEmailInUse", err)
+	}
+
+	// Signing in again finds the linked account
+	state, code = f.login(t, "mock", claims)
+	res, err = f.svc.Callback(ctx, "mock", code, state)
+	if err != nil {
+		t.Fatalf("Callback: %v", err)
+	}
+	if res.User == nil || res.User.ID != user.ID {
+		t.Errorf("Callback = %+v, want user %s", res, user.ID)
+	}
+}
+
+func TestFederationCompleteSignupRejectsForgedToken(t *testing.T) {
+	f := newFederationFixture(t)
+
+	if _, err := f.svc.CompleteSignup(context.Background(), "not-a-token", unique("u_")); !errors.Is(err, service.ErrInvalidOIDCState) {
+		t.Errorf("CompleteSignup = %v, want ErrInvalidOIDCState", err)
+	}
+}
+
+func TestFederationCallbackRejectsReplayedState(t *testing.T) {
+	f := newFederationFixture(t)
+	ctx := context.Background()
+
+	claims := oidctest.Claims{Subject: unique("sub-"), Email: unique("u_") + "@example.com"}
+	state, code := f.login(t, "mock", claims)
+	if _, err := f.svc.Callback(ctx, "mock", code, state); err != nil {
+		t.Fatalf("Callback: %v", err)
+	}
+
+	_, code = f.login(t, "mock", claims)
+	if _, err := f.svc.Callback(ctx, "mock", code, state); !errors.Is(err, service.ErrInvalidOIDCState) {
+		t.Errorf("replayed state = %v, want ErrInvalidOIDCState", err)
+	}
+}
+
+func TestFederationCallbackRejectsProviderMismatch(t *testing.T) {
+	f := newFederationFixture(t)
+
+	state, code := f.login(t, "mock", oidctest.Claims{Subject: unique("sub-"), Email: unique("u_") + "@example.com"})
+	if _, err := f.svc.Callback(context.Background(), "other", code, state); !errors.Is(err, service.ErrInvalidOIDCState) {
+		t.Errorf("Callback for another provider = %v, want ErrInvalidOIDCState", err)
+	}
+}
+
+func TestFederationCallbackRefusesExistingEmail(t *testing.T) {
+	f := newFederationFixture(t)
+	user := f.createUser(t, "hash")
+
+	state, code := f.login(t, "mock", oidctest.Claims{
+		Subject:       unique("sub-"),
+		Email:         strings.ToUpper(user.Email),
+		EmailVerified: true,
+	})
+	if _, err := f.svc.Callback(context.Background(), "mock", code, state); !errors.Is(err, service.ErrOIDCEmailInUse) {
+		t.Errorf("Callback = %v, want ErrOIDCEmailInUse", err)
+	}
+}
+
+func TestFederationLinkAndUnlink(t *testing.T) {
+	f := newFederationFixture(t)
+	ctx := context.Background()
+	user := f.createUser(t, "") // signs in through providers only
+
+	claims := oidctest.Claims{Subject: unique("sub-"), Email: unique("u_") + "@example.com"}
+	res, err := f.link(t, user.ID, "mock", claims)
+	if err != nil {
+		t.Fatalf("link: %v", err)
+	}
+	if !res.Linked {
+		t.Fatalf("Callback = %+v, want Linked", res)
+	}
+
+	// The same provider account can't be linked to a second user
+	other := f.createUser(t, "hash")
+	if _, err := f.link(t, other.ID, "mock", claims); err == nil {
+		t.Error("linked one provider account to two users")
+	}
+
+	if err := f.svc.Unlink(ctx, user.ID, "mock"); err == nil {
+		t.Error("Unlink removed the only sign-in method")
+	}
+
+	if _, err := f.link(t, user.ID, "other", oidctest.Claims{Subject: unique("sub-")}); err != nil {
+		t.Fatalf("link: %v", err)
+	}
+	identities, err := f.svc.ListIdentities(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("ListIdentities: %v", err)
+	}
+	if len(identities) != 2 {
+		t.Fatalf("ListIdentities returned %d identities, want 2", len(identities))
+	}
+
+	if err := f.svc.Unlink(ctx, user.ID, "mock"); err != nil {
+		t.Errorf("Unlink with another provider left: %v", err)
+	}
+	if err := f.svc.Unlink(ctx, user.ID, "other"); err == nil {
+		t.Error("Unlink removed the only sign-in method")
+	}
+
+	// A password counts as a sign-in method
+	if _, err := f.link(t, other.ID, "other", oidctest.Claims{Subject: unique("sub-")}); err != nil {
+		t.Fatalf("link: %v", err)
+	}
+	if err := f.svc.Unlink(ctx, other.ID, "other"); err != nil {
+		t.Errorf("Unlink for an account with a password: %v", err)
+	}
+}
@@ -0,0 +1,234 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"goServer/internal/config"
+	"goServer/internal/model"
+	"goServer/internal/repository"
+	"goServer/internal/tracing"
+	"goServer/pkg/utils"
+)
+
+// FeedScore breaks a ranked post's score into its parts, for explain mode
+type FeedScore struct {
+	Source          string
+	Recency         float64 // halves every FeedRecencyHalfLife
+	Velocity        float64 // engagement per hour since posting
+	Affinity        float64 // how often the viewer interacts with the author
+	SourceFactor    float64
+	DiversityFactor float64
+	Total           float64
+}
+
+// RankedPost is a post placed in a ranked feed
+type RankedPost struct {
+	Post    model.Post
+	Likes   int64
+	Reposts int64
+	Replies int64
+	Score   FeedScore
+}
+
+// FeedService builds the ranked "For You" feed. The chronological feed is
+// served by PostService.GetFeed.
+type FeedService struct {
+	feedRepo repository.FeedRepository
+	postRepo repository.PostRepository
+	cfg      config.Config
+}
+
+func NewFeedService(fr repository.FeedRepository, pr repository.PostRepository, cfg config.Config) *FeedService {
+	return &FeedService{
+		feedRepo: fr,
+		postRepo: pr,
+		cfg:      cfg,
+	}
+}
+
+// GetRankedFeed scores recent posts from the viewer's network and trending
+// posts, then returns one page of them best first. Pages are ranked as of
+// the moment the first one was, so paging with the returned cursor walks one
+// stable order even as new posts and engagement come in.
+func (s *FeedService) GetRankedFeed(ctx context.Context, viewerID, cursor string, limit int) ([]RankedPost, string, error) {
+	ctx, span := tracing.Start(ctx, "FeedService.GetRankedFeed")
+	defer span.End()
+
+	if viewerID == "" {
+		return nil, "", errors.New("user id is required")
+	}
+
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	after, err := decodeFeedCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	asOf := time.Now()
+	if after != nil {
+		asOf = after.asOf
+	}
+
+	candidates, err := s.feedRepo.Candidates(ctx, viewerID, repository.FeedCandidateQuery{
+		Until:         asOf,
+		Since:         asOf.Add(-s.cfg.FeedRankWindow),
+		TrendingSince: asOf.Add(-s.cfg.FeedTrendingWindow),
+		AffinitySince: asOf.Add(-s.cfg.FeedAffinityWindow),
+		TrendingLimit: s.cfg.FeedTrendingPosts,
+		SourceLimit:   s.cfg.FeedRankCandidates,
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get feed candidates: %w", err)
+	}
+
+	ranked := s.rank(candidates, asOf, len(candidates))
+	if after != nil {
+		// Scores never increase down the ranking, so the page starts at the
+		// first post ordered after the cursor's
+		start := sort.Search(len(ranked), func(i int) bool {
+			return rankedBefore(after.score, after.postID, ranked[i].Score.Total, ranked[i].Post.ID)
+		})
+		ranked = ranked[start:]
+	}
+
+	next := ""
+	if len(ranked) > limit {
+		ranked = ranked[:limit]
+		last := ranked[limit-1]
+		next = feedCursor{asOf: asOf, score: last.Score.Total, postID: last.Post.ID}.encode()
+	}
+
+	ids := make([]string, len(ranked))
+	for i, r := range ranked {
+		ids[i] = r.Post.ID
+	}
+	posts, err := s.postRepo.FindByIDs(ctx, ids)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to load posts: %w", err)
+	}
+	byID := make(map[string]model.Post, len(posts))
+	for _, p := range posts {
+		byID[p.ID] = p
+	}
+
+	// A post deleted between ranking and loading is simply skipped
+	page := make([]RankedPost, 0, len(ranked))
+	for _, r := range ranked {
+		if p, ok := byID[r.Post.ID]; ok {
+			r.Post = p
+			page = append(page, r)
+		}
+	}
+	return page, next, nil
+}
+
+// feedCursor marks the last post of a ranked feed page, and the moment the
+// feed was ranked as of
+type feedCursor struct {
+	asOf   time.Time
+	score  float64
+	postID string
+}
+
+func (c feedCursor) encode() string {
+	return utils.Cursor{At: c.asOf, ID: strconv.FormatFloat(c.score, 'g', -1, 64) + "|" + c.postID}.Encode()
+}
+
+// decodeFeedCursor parses a cursor made by encode. An empty string means the
+// first page and decodes to nil.
+func decodeFeedCursor(s string) (*feedCursor, error) {
+	c, err := utils.DecodeCursor(s)
+	if err != nil || c == nil {
+		return nil, err
+	}
+	score, postID, ok := strings.Cut(c.ID, "|")
+	if !ok || postID == "" {
+		return nil, utils.ErrInvalidCursor
+	}
+	total, err := strconv.ParseFloat(score, 64)
+	if err != nil {
+		return nil, utils.ErrInvalidCursor
+	}
+	return &feedCursor{asOf: c.At, score: total, postID: postID}, nil
+}
+
+// rankedBefore reports whether a post scored (score, id) is placed before
+// one scored (otherScore, otherID): higher scores first, ties by post id
+func rankedBefore(score float64, id string, otherScore float64, otherID string) bool {
+	if score != otherScore {
+		return score > otherScore
+	}
+	return id < otherID
+}
+
+// rank scores candidates and orders the best n of them. Placement is greedy:
+// each pick discounts the remaining posts by the same author, so one prolific
+// account can't fill the page. Since discounts only ever lower the scores
+// left, the placed totals never increase, and ties go to the lower post id.
+func (s *FeedService) rank(candidates []repository.FeedCandidate, now time.Time, n int) []RankedPost {
+	scored := make([]RankedPost, len(candidates))
+	for i, c := range candidates {
+		scored[i] = RankedPost{
+			Post:    model.Post{ID: c.PostID, UserID: c.AuthorID},
+			Likes:   c.Likes,
+			Reposts: c.Reposts,
+			Replies: c.Replies,
+			Score:   s.score(c, now),
+		}
+	}
+
+	placed := make(map[string]int)
+	ranked := make([]RankedPost, 0, min(n, len(scored)))
+	for len(ranked) < n && len(scored) > 0 {
+		best, bestTotal := -1, math.Inf(-1)
+		for i, p := range scored {
+			total := p.Score.Total * math.Pow(s.cfg.FeedAuthorRepeatPenalty, float64(placed[p.Post.UserID]))
+			if best < 0 || rankedBefore(total, p.Post.ID, bestTotal, scored[best].Post.ID) {
+				best, bestTotal = i, total
+			}
+		}
+
+		p := scored[best]
+		p.Score.DiversityFactor = math.Pow(s.cfg.FeedAuthorRepeatPenalty, float64(placed[p.Post.UserID]))
+		p.Score.Total = bestTotal
+		ranked = append(ranked, p)
+		placed[p.Post.UserID]++
+
+		scored[best] = scored[len(scored)-1]
+		scored = scored[:len(scored)-1]
+	}
+	return ranked
+}
+
+// score computes a candidate's score before the diversity adjustment
+func (s *FeedService) score(c repository.FeedCandidate, now time.Time) FeedScore {
+	ageHours := math.Max(now.Sub(c.CreatedAt).Hours(), 0)
+	halfLife := math.Max(s.cfg.FeedRecencyHalfLife.Hours(), 0.01)
+
+	// +2 keeps a brand new post's first like from reading as a huge rate
+	engagement := float64(c.Likes + c.Reposts + c.Replies)
+	velocity := engagement / (ageHours + 2)
+
+	score := FeedScore{
+		Source:          c.Source,
+		Recency:         s.cfg.FeedRecencyWeight * math.Pow(0.5, ageHours/halfLife),
+		Velocity:        s.cfg.FeedVelocityWeight * math.Log1p(velocity),
+		Affinity:        s.cfg.FeedAffinityWeight * math.Log1p(float64(c.Affinity)),
+		SourceFactor:    1,
+		DiversityFactor: 1,
+	}
+	if c.Source != repository.FeedSourceFollowee {
+		score.SourceFactor = s.cfg.FeedOutOfNetworkFactor
+	}
+	score.Total = (score.Recency + score.Velocity + score.Affinity) * score.SourceFactor
+	return score
+}
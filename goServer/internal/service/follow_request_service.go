@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"goServer/internal/metrics"
+	"goServer/internal/model"
+	"goServer/internal/repository"
+	"goServer/internal/tracing"
+)
+
+var ErrPrivateAccount = errors.New("this account is private")
+
+// FollowRequestService lets private accounts review who asked to follow them
+type FollowRequestService struct {
+	requestRepo repository.FollowRequestRepository
+}
+
+func NewFollowRequestService(fr repository.FollowRequestRepository) *FollowRequestService {
+	return &FollowRequestService{requestRepo: fr}
+}
+
+// ListIncoming lists the follow requests waiting on the user's approval
+func (s *FollowRequestService) ListIncoming(ctx context.Context, userID string, limit, offset int) ([]model.FollowRequest, error) {
+	ctx, span := tracing.Start(ctx, "FollowRequestService.ListIncoming")
+	defer span.End()
+
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	requests, err := s.requestRepo.ListIncoming(ctx, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list follow requests: %w", err)
+	}
+	return requests, nil
+}
+
+// ListOutgoing lists the follow requests the user sent that are still pending
+func (s *FollowRequestService) ListOutgoing(ctx context.Context, userID string, limit, offset int) ([]model.FollowRequest, error) {
+	ctx, span := tracing.Start(ctx, "FollowRequestService.ListOutgoing")
+	defer span.End()
+
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	requests, err := s.requestRepo.ListOutgoing(ctx, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list follow requests: %w", err)
+	}
+	return requests, nil
+}
+
+// Approve makes the requester a follower of the user
+func (s *FollowRequestService) Approve(ctx context.Context, userID, requesterID string) error {
+	ctx, span := tracing.Start(ctx, "FollowRequestService.Approve")
+	defer span.End()
+
+	approved, err := s.requestRepo.Approve(ctx, requesterID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to approve follow request: %w", err)
+	}
+	if !approved {
+		return errors.New("follow request not found")
+	}
+	metrics.FollowsCreated.Inc()
+
+	return nil
+}
+
+// Reject drops a follow request without telling the requester
+func (s *FollowRequestService) Reject(ctx context.Context, userID, requesterID string) error {
+	ctx, span := tracing.Start(ctx, "FollowRequestService.Reject")
+	defer span.End()
+
+	removed, err := s.requestRepo.Delete(ctx, requesterID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to reject follow request: %w", err)
+	}
+	if !removed {
+		return errors.New("follow request not found")
+	}
+
+	return nil
+}
+
+// checkCanSeePosts returns ErrPrivateAccount if author is private and the
+// viewer is neither the author nor an approved follower
+func checkCanSeePosts(ctx context.Context, userRepo repository.UserRepository, viewerID string, author *model.User) error {
+	if !author.IsPrivate || viewerID == author.ID {
+		return nil
+	}
+	if viewerID == "" {
+		return ErrPrivateAccount
+	}
+
+	following, err := userRepo.IsFollowing(ctx, viewerID, author.ID)
+	if err != nil {
+		return fmt.Errorf("failed to check follow status: %w", err)
+	}
+	if !following {
+		return ErrPrivateAccount
+	}
+	return nil
+}
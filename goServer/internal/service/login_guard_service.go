@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"goServer/internal/config"
+	"goServer/internal/mailer"
+	"goServer/internal/metrics"
+	"goServer/internal/model"
+	"goServer/internal/repository"
+	"goServer/internal/tracing"
+	"goServer/pkg/utils"
+)
+
+// LoginGuardService throttles password guessing. Failures are counted per
+// IP and per account; once a counter passes its threshold the key is
+// locked out for a period that doubles with every further failure.
+type LoginGuardService struct {
+	throttleRepo repository.LoginThrottleRepository
+	userRepo     repository.UserRepository
+	mailer       mailer.Mailer
+	cfg          config.Config
+}
+
+func NewLoginGuardService(tr repository.LoginThrottleRepository, ur repository.UserRepository, m mailer.Mailer, cfg config.Config) *LoginGuardService {
+	return &LoginGuardService{
+		throttleRepo: tr,
+		userRepo:     ur,
+		mailer:       m,
+		cfg:          cfg,
+	}
+}
+
+// Check returns how long the caller must wait before trying again, or zero
+// if the IP and the account behind identifier are both allowed to attempt
+// a login. identifier is a username or an email.
+func (s *LoginGuardService) Check(ctx context.Context, ip, identifier string) (time.Duration, error) {
+	ctx, span := tracing.Start(ctx, "LoginGuardService.Check")
+	defer span.End()
+
+	_, key, err := s.resolve(ctx, identifier)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	locked, err := s.throttleRepo.FindLocked(ctx, ip, key, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to check login throttle: %w", err)
+	}
+
+	var wait time.Duration
+	for _, t := range locked {
+		if d := t.LockedUntil.Sub(now); d > wait {
+			wait = d
+		}
+	}
+
+	if wait > 0 {
+		metrics.RateLimitRejections.WithLabelValues("login").Inc()
+	}
+	return wait, nil
+}
+
+// RecordFailure counts a failed attempt against the IP and the account
+// behind identifier and locks whichever crossed its threshold. The account
+// owner is emailed when their account first gets locked.
+func (s *LoginGuardService) RecordFailure(ctx context.Context, ip, identifier string) error {
+	ctx, span := tracing.Start(ctx, "LoginGuardService.RecordFailure")
+	defer span.End()
+
+	windowStart := time.Now().Add(-s.cfg.LoginFailureWindow)
+
+	ipThrottle, err := s.throttleRepo.RecordFailure(ctx, model.LoginThrottleScopeIP, ip, windowStart)
+	if err != nil {
+		return fmt.Errorf("failed to record login failure: %w", err)
+	}
+	if d := s.lockout(ipThrottle.Failures, s.cfg.LoginMaxFailuresPerIP); d > 0 {
+		if err := s.throttleRepo.Lock(ctx, model.LoginThrottleScopeIP, ip, time.Now().Add(d)); err != nil {
+			return fmt.Errorf("failed to lock ip: %w", err)
+		}
+	}
+
+	user, key, err := s.resolve(ctx, identifier)
+	if err != nil {
+		return err
+	}
+	if key == "" {
+		return nil
+	}
+
+	userThrottle, err := s.throttleRepo.RecordFailure(ctx, model.LoginThrottleScopeUsername, key, windowStart)
+	if err != nil {
+		return fmt.Errorf("failed to record login failure: %w", err)
+	}
+	if d := s.lockout(userThrottle.Failures, s.cfg.LoginMaxFailures); d > 0 {
+		if err := s.throttleRepo.Lock(ctx, model.LoginThrottleScopeUsername, key, time.Now().Add(d)); err != nil {
+			return fmt.Errorf("failed to lock username: %w", err)
+		}
+		// Sent in the background so the response time doesn't reveal
+		// whether the username belongs to an account
+		if user != nil && userThrottle.Failures == s.cfg.LoginMaxFailures {
+			go s.notifyLockout(context.WithoutCancel(ctx), user, ip, userThrottle.Failures, d)
+		}
+	}
+
+	return nil
+}
+
+// RecordSuccess clears the account's failure count. The IP counter is left
+// alone so an attacker can't reset it by logging into their own account.
+func (s *LoginGuardService) RecordSuccess(ctx context.Context, identifier string) error {
+	ctx, span := tracing.Start(ctx, "LoginGuardService.RecordSuccess")
+	defer span.End()
+
+	_, key, err := s.resolve(ctx, identifier)
+	if err != nil {
+		return err
+	}
+	if err := s.throttleRepo.Reset(ctx, model.LoginThrottleScopeUsername, key); err != nil {
+		return fmt.Errorf("failed to reset login throttle: %w", err)
+	}
+	return nil
+}
+
+// lockout returns the lock duration after the given number of failures:
+// the base duration at the threshold, doubling for each failure beyond it
+func (s *LoginGuardService) lockout(failures, threshold int) time.Duration {
+	if threshold <= 0 || failures < threshold {
+		return 0
+	}
+
+	d := s.cfg.LoginLockoutBase
+	for i := threshold; i < failures && d < s.cfg.LoginLockoutMax; i++ {
+		d *= 2
+	}
+	if d > s.cfg.LoginLockoutMax {
+		d = s.cfg.LoginLockoutMax
+	}
+	return d
+}
+
+func (s *LoginGuardService) notifyLockout(ctx context.Context, user *model.User, ip string, failures int, d time.Duration) {
+	if err := s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Sign-in to your account was temporarily blocked",
+		Body: fmt.Sprintf("Hi %s,\r\n\r\nWe blocked sign-in to your account for %s after %d failed attempts. "+
+			"The last attempt came from %s.\r\n\r\n"+
+			"If this was you, wait and try again. If it wasn't, consider resetting your password "+
+			"and enabling two-factor authentication.\r\n",
+			user.Username, d, failures, ip),
+	}); err != nil {
+		log.Printf("[login-guard] failed to send lockout email to user %s: %v", user.ID, err)
+	}
+}
+
+// resolve finds the account a login identifier names and the key its
+// failures are counted under. Every identifier for an account shares the
+// account's ID as key, so switching between username and email doesn't buy
+// extra guesses. Unknown identifiers are counted under their normalized
+// form, so case variations still share one counter.
+func (s *LoginGuardService) resolve(ctx context.Context, identifier string) (*model.User, string, error) {
+	key := utils.NormalizeUsername(identifier)
+	if key == "" {
+		return nil, "", nil
+	}
+
+	var user *model.User
+	var err error
+	if strings.Contains(key, "@") {
+		user, err = s.userRepo.FindByEmail(ctx, key)
+	} else {
+		user, err = s.userRepo.FindByUsername(ctx, key)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to find user: %w", err)
+	}
+	if user == nil {
+		return nil, key, nil
+	}
+	return user, user.ID, nil
+}
@@ -0,0 +1,259 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"goServer/internal/config"
+	"goServer/internal/dto"
+	"goServer/internal/model"
+	"goServer/internal/repository"
+	"goServer/internal/tracing"
+	"goServer/pkg/utils"
+
+	"gorm.io/gorm"
+)
+
+const recoveryCodeCount = 10
+
+var ErrInvalidMFACode = errors.New("invalid authentication code")
+
+// MFAService manages TOTP two-factor authentication and recovery codes
+type MFAService struct {
+	userRepo         repository.UserRepository
+	recoveryCodeRepo repository.RecoveryCodeRepository
+	hasher           utils.PasswordHasher
+	cfg              config.Config
+}
+
+func NewMFAService(ur repository.UserRepository, rr repository.RecoveryCodeRepository, hasher utils.PasswordHasher, cfg config.Config) *MFAService {
+	return &MFAService{
+		userRepo:         ur,
+		recoveryCodeRepo: rr,
+		hasher:           hasher,
+		cfg:              cfg,
+	}
+}
+
+// Status reports whether 2FA is on and how many recovery codes are left
+func (s *MFAService) Status(ctx context.Context, userID string) (*dto.MFAStatusRes, error) {
+	ctx, span := tracing.Start(ctx, "MFAService.Status")
+	defer span.End()
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+
+	res := &dto.MFAStatusRes{Enabled: user.TOTPEnabledAt != nil}
+	if res.Enabled {
+		remaining, err := s.recoveryCodeRepo.CountUnused(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count recovery codes: %w", err)
+		}
+		res.RecoveryCodesRemaining = int(remaining)
+	}
+	return res, nil
+}
+
+// Enroll generates a new pending TOTP secret. 2FA stays off until the user
+// proves their authenticator works by calling Confirm.
+func (s *MFAService) Enroll(ctx context.Context, userID string) (*dto.MFAEnrollRes, error) {
+	ctx, span := tracing.Start(ctx, "MFAService.Enroll")
+	defer span.End()
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+	if user.TOTPEnabledAt != nil {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate secret: %w", err)
+	}
+	sealed, err := utils.SealSecret(s.cfg.MFASecretKey, secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt secret: %w", err)
+	}
+	if err := s.userRepo.SetTOTPSecret(ctx, userID, sealed); err != nil {
+		return nil, fmt.Errorf("failed to store secret: %w", err)
+	}
+
+	return &dto.MFAEnrollRes{
+		Secret:          secret,
+		ProvisioningURI: utils.TOTPProvisioningURI(s.cfg.MFAIssuer, user.Username, secret),
+	}, nil
+}
+
+// Confirm enables 2FA once the user submits a valid code for the pending
+// secret, and returns the recovery codes. They are only ever shown here.
+func (s *MFAService) Confirm(ctx context.Context, userID, code string) ([]string, error) {
+	ctx, span := tracing.Start(ctx, "MFAService.Confirm")
+	defer span.End()
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+	if user.TOTPEnabledAt != nil {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+	if user.TOTPSecret == "" {
+		return nil, errors.New("two-factor enrollment has not been started")
+	}
+
+	step, ok := s.validateTOTP(user, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.recoveryCodeRepo.Transaction(ctx, func(tx *gorm.DB) error {
+		if err := s.userRepo.WithTx(tx).EnableTOTP(ctx, userID, step); err != nil {
+			return err
+		}
+		return s.recoveryCodeRepo.WithTx(tx).Replace(ctx, userID, hashes)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to enable two-factor authentication: %w", err)
+	}
+
+	return codes, nil
+}
+
+// Disable turns 2FA off. It asks for both the password and a current code
+// so a stolen session alone can't remove the second factor, and signs the
+// user out everywhere.
+func (s *MFAService) Disable(ctx context.Context, userID, password, code string) error {
+	ctx, span := tracing.Start(ctx, "MFAService.Disable")
+	defer span.End()
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to find user: %w", err)
+	}
+	if user == nil {
+		return errors.New("user not found")
+	}
+	if user.TOTPEnabledAt == nil {
+		return errors.New("two-factor authentication is not enabled")
+	}
+	if ok, err := s.hasher.Verify(password, user.Password); err != nil || !ok {
+		return ErrInvalidCredentials
+	}
+	if err := s.Verify(ctx, userID, code); err != nil {
+		return err
+	}
+
+	return s.recoveryCodeRepo.Transaction(ctx, func(tx *gorm.DB) error {
+		if err := s.userRepo.WithTx(tx).DisableTOTP(ctx, userID); err != nil {
+			return fmt.Errorf("failed to disable two-factor authentication: %w", err)
+		}
+		if err := s.recoveryCodeRepo.WithTx(tx).DeleteForUser(ctx, userID); err != nil {
+			return fmt.Errorf("failed to delete recovery codes: %w", err)
+		}
+		return nil
+	})
+}
+
+// RegenerateRecoveryCodes replaces every recovery code with a fresh set
+func (s *MFAService) RegenerateRecoveryCodes(ctx context.Context, userID, code string) ([]string, error) {
+	ctx, span := tracing.Start(ctx, "MFAService.RegenerateRecoveryCodes")
+	defer span.End()
+
+	if err := s.Verify(ctx, userID, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.recoveryCodeRepo.Replace(ctx, userID, hashes); err != nil {
+		return nil, fmt.Errorf("failed to store recovery codes: %w", err)
+	}
+	return codes, nil
+}
+
+// Verify accepts either a TOTP code or an unused recovery code. Each TOTP
+// step and each recovery code can only be used once.
+func (s *MFAService) Verify(ctx context.Context, userID, code string) error {
+	ctx, span := tracing.Start(ctx, "MFAService.Verify")
+	defer span.End()
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to find user: %w", err)
+	}
+	if user == nil || user.TOTPEnabledAt == nil {
+		return ErrInvalidMFACode
+	}
+
+	if step, ok := s.validateTOTP(user, code, time.Now()); ok {
+		advanced, err := s.userRepo.AdvanceTOTPStep(ctx, userID, step)
+		if err != nil {
+			return fmt.Errorf("failed to record code use: %w", err)
+		}
+		if !advanced {
+			return ErrInvalidMFACode
+		}
+		return nil
+	}
+
+	used, err := s.recoveryCodeRepo.Consume(ctx, userID, utils.HashToken(utils.NormalizeRecoveryCode(code)))
+	if err != nil {
+		return fmt.Errorf("failed to check recovery code: %w", err)
+	}
+	if !used {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+// validateTOTP checks code against the user's stored secret, which is
+// sealed with MFASecretKey. Secrets stored before they were encrypted are
+// still read as plaintext.
+func (s *MFAService) validateTOTP(user *model.User, code string, now time.Time) (int64, bool) {
+	secret := user.TOTPSecret
+	if utils.IsSealedSecret(secret) {
+		var err error
+		if secret, err = utils.OpenSecret(s.cfg.MFASecretKey, secret); err != nil {
+			log.Printf("[mfa] failed to decrypt TOTP secret for user %s: %v", user.ID, err)
+			return 0, false
+		}
+	}
+	return utils.ValidateTOTP(secret, code, now)
+}
+
+// newRecoveryCodes returns plaintext codes for the user and the hashes to store
+func newRecoveryCodes() ([]string, []string, error) {
+	codes, err := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate recovery codes: %w", err)
+	}
+
+	hashes := make([]string, len(codes))
+	for i, c := range codes {
+		hashes[i] = utils.HashToken(c)
+	}
+	return codes, hashes, nil
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"goServer/internal/config"
+	"goServer/internal/model"
+	"goServer/internal/repository"
+	"goServer/pkg/utils"
+)
+
+// rfc6238Secret is the SHA-1 seed from RFC 6238 appendix B,
+// "12345678901234567890", base32 encoded
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// rfc6238Vectors are the SHA-1 test vectors from RFC 6238 appendix B, cut
+// to the 6 digits authenticator apps show
+var rfc6238Vectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "287082"},
+	{1111111109, "081804"},
+	{1111111111, "050471"},
+	{1234567890, "005924"},
+	{2000000000, "279037"},
+	{20000000000, "353130"},
+}
+
+func newTestMFAService(t *testing.T) (*MFAService, *model.User) {
+	t.Helper()
+
+	s := NewMFAService(repository.UserRepository{}, repository.RecoveryCodeRepository{}, nil, config.Config{MFASecretKey: "test-key"})
+	sealed, err := utils.SealSecret(s.cfg.MFASecretKey, rfc6238Secret)
+	if err != nil {
+		t.Fatalf("SealSecret: %v", err)
+	}
+	return s, &model.User{ID: "user", TOTPSecret: sealed}
+}
+
+func TestValidateTOTPVectors(t *testing.T) {
+	s, user := newTestMFAService(t)
+
+	for _, v := range rfc6238Vectors {
+		now := time.Unix(v.unix, 0)
+		step, ok := s.validateTOTP(user, v.code, now)
+		if !ok {
+			t.Errorf("code %s at %d was rejected", v.code, v.unix)
+			continue
+		}
+		if step != utils.TOTPStep(now) {
+			t.Errorf("code %s at %d matched step %d, want %d", v.code, v.unix, step, utils.TOTPStep(now))
+		}
+	}
+}
+
+func TestValidateTOTPDrift(t *testing.T) {
+	s, user := newTestMFAService(t)
+	issued := time.Unix(1111111109, 0) // code 081804
+
+	for _, tc := range []struct {
+		offset time.Duration
+		ok     bool
+	}{
+		{-utils.TOTPPeriod * time.Second, true},
+		{utils.TOTPPeriod * time.Second, true},
+		{-2 * utils.TOTPPeriod * time.Second, false},
+		{2 * utils.TOTPPeriod * time.Second, false},
+	} {
+		if _, ok := s.validateTOTP(user, "081804", issued.Add(tc.offset)); ok != tc.ok {
+			t.Errorf("code checked %v from its step: ok = %v, want %v", tc.offset, ok, tc.ok)
+		}
+	}
+}
+
+func TestValidateTOTPRejectsBadInput(t *testing.T) {
+	s, user := newTestMFAService(t)
+	now := time.Unix(59, 0)
+
+	for _, code := range []string{"", "28708", "2870821", "287083", "abcdef"} {
+		if _, ok := s.validateTOTP(user, code, now); ok {
+			t.Errorf("code %q was accepted", code)
+		}
+	}
+
+	other := NewMFAService(repository.UserRepository{}, repository.RecoveryCodeRepository{}, nil, config.Config{MFASecretKey: "other-key"})
+	if _, ok := other.validateTOTP(user, "287082", now); ok {
+		t.Error("a secret sealed under another key was accepted")
+	}
+}
+
+func TestValidateTOTPReadsPlaintextSecrets(t *testing.T) {
+	s, _ := newTestMFAService(t)
+
+	legacy := &model.User{ID: "user", TOTPSecret: rfc6238Secret}
+	if _, ok := s.validateTOTP(legacy, "287082", time.Unix(59, 0)); !ok {
+		t.Error("a secret stored before encryption was rejected")
+	}
+}
@@ -5,24 +5,31 @@ import (
 	"errors"
 	"fmt"
 
+	"goServer/internal/metrics"
 	"goServer/internal/model"
 	"goServer/internal/repository"
+	"goServer/internal/tracing"
 )
 
 type NotificationService struct {
 	notificationRepo repository.NotificationRepository
 	userRepo         repository.UserRepository
+	blockRepo        repository.BlockRepository
 }
 
-func NewNotificationService(nr repository.NotificationRepository, ur repository.UserRepository) *NotificationService {
+func NewNotificationService(nr repository.NotificationRepository, ur repository.UserRepository, br repository.BlockRepository) *NotificationService {
 	return &NotificationService{
 		notificationRepo: nr,
 		userRepo:         ur,
+		blockRepo:        br,
 	}
 }
 
 // CreateNotification creates a new notification
 func (s *NotificationService) CreateNotification(ctx context.Context, userID, notificationType string) (*model.Notification, error) {
+	ctx, span := tracing.Start(ctx, "NotificationService.CreateNotification")
+	defer span.End()
+
 	if userID == "" || notificationType == "" {
 		return nil, errors.New("user id and notification type are required")
 	}
@@ -42,7 +49,7 @@ func (s *NotificationService) CreateNotification(ctx context.Context, userID, no
 		Read:   false,
 	}
 
-	if err := s.notificationRepo.Create(ctx, notification); err != nil {
+	if err := s.deliver(ctx, notification); err != nil {
 		return nil, fmt.Errorf("failed to create notification: %w", err)
 	}
 
@@ -51,6 +58,9 @@ func (s *NotificationService) CreateNotification(ctx context.Context, userID, no
 
 // GetNotifications retrieves user's notifications with pagination
 func (s *NotificationService) GetNotifications(ctx context.Context, userID string, limit, offset int) ([]model.Notification, error) {
+	ctx, span := tracing.Start(ctx, "NotificationService.GetNotifications")
+	defer span.End()
+
 	if userID == "" {
 		return nil, errors.New("user id is required")
 	}
@@ -72,6 +82,9 @@ func (s *NotificationService) GetNotifications(ctx context.Context, userID strin
 
 // GetUnreadCount gets count of unread notifications
 func (s *NotificationService) GetUnreadCount(ctx context.Context, userID string) (int64, error) {
+	ctx, span := tracing.Start(ctx, "NotificationService.GetUnreadCount")
+	defer span.End()
+
 	if userID == "" {
 		return 0, errors.New("user id is required")
 	}
@@ -86,6 +99,9 @@ func (s *NotificationService) GetUnreadCount(ctx context.Context, userID string)
 
 // MarkAsRead marks a notification as read
 func (s *NotificationService) MarkAsRead(ctx context.Context, notificationID, userID string) (*model.Notification, error) {
+	ctx, span := tracing.Start(ctx, "NotificationService.MarkAsRead")
+	defer span.End()
+
 	if notificationID == "" || userID == "" {
 		return nil, errors.New("notification id and user id are required")
 	}
@@ -113,6 +129,9 @@ func (s *NotificationService) MarkAsRead(ctx context.Context, notificationID, us
 
 // DeleteNotification deletes a notification
 func (s *NotificationService) DeleteNotification(ctx context.Context, notificationID, userID string) error {
+	ctx, span := tracing.Start(ctx, "NotificationService.DeleteNotification")
+	defer span.End()
+
 	if notificationID == "" || userID == "" {
 		return errors.New("notification id and user id are required")
 	}
@@ -138,6 +157,9 @@ func (s *NotificationService) DeleteNotification(ctx context.Context, notificati
 
 // MarkAllAsRead marks all notifications as read for a user
 func (s *NotificationService) MarkAllAsRead(ctx context.Context, userID string) error {
+	ctx, span := tracing.Start(ctx, "NotificationService.MarkAllAsRead")
+	defer span.End()
+
 	if userID == "" {
 		return errors.New("user id is required")
 	}
@@ -151,6 +173,9 @@ func (s *NotificationService) MarkAllAsRead(ctx context.Context, userID string)
 
 // GetNotificationsByType retrieves notifications of a specific type
 func (s *NotificationService) GetNotificationsByType(ctx context.Context, userID, notificationType string, limit, offset int) ([]model.Notification, error) {
+	ctx, span := tracing.Start(ctx, "NotificationService.GetNotificationsByType")
+	defer span.End()
+
 	if userID == "" || notificationType == "" {
 		return nil, errors.New("user id and notification type are required")
 	}
@@ -172,6 +197,9 @@ func (s *NotificationService) GetNotificationsByType(ctx context.Context, userID
 
 // NotifyPostLike creates a notification when someone likes a post
 func (s *NotificationService) NotifyPostLike(ctx context.Context, postOwnerID, likerID string) error {
+	ctx, span := tracing.Start(ctx, "NotificationService.NotifyPostLike")
+	defer span.End()
+
 	if postOwnerID == "" || likerID == "" {
 		return errors.New("post owner id and liker id are required")
 	}
@@ -180,13 +208,18 @@ func (s *NotificationService) NotifyPostLike(ctx context.Context, postOwnerID, l
 		return nil // Don't notify on self-like
 	}
 
+	if suppressed, err := s.suppressed(ctx, postOwnerID, likerID); err != nil || suppressed {
+		return err
+	}
+
 	notification := &model.Notification{
-		UserID: postOwnerID,
-		Type:   "LIKE",
-		Read:   false,
+		UserID:  postOwnerID,
+		ActorID: &likerID,
+		Type:    "LIKE",
+		Read:    false,
 	}
 
-	if err := s.notificationRepo.Create(ctx, notification); err != nil {
+	if err := s.deliver(ctx, notification); err != nil {
 		return fmt.Errorf("failed to create like notification: %w", err)
 	}
 
@@ -195,6 +228,9 @@ func (s *NotificationService) NotifyPostLike(ctx context.Context, postOwnerID, l
 
 // NotifyPostRepost creates a notification when someone reposts a post
 func (s *NotificationService) NotifyPostRepost(ctx context.Context, postOwnerID, reposterID string) error {
+	ctx, span := tracing.Start(ctx, "NotificationService.NotifyPostRepost")
+	defer span.End()
+
 	if postOwnerID == "" || reposterID == "" {
 		return errors.New("post owner id and reposter id are required")
 	}
@@ -203,13 +239,18 @@ func (s *NotificationService) NotifyPostRepost(ctx context.Context, postOwnerID,
 		return nil // Don't notify on self-repost
 	}
 
+	if suppressed, err := s.suppressed(ctx, postOwnerID, reposterID); err != nil || suppressed {
+		return err
+	}
+
 	notification := &model.Notification{
-		UserID: postOwnerID,
-		Type:   "REPOST",
-		Read:   false,
+		UserID:  postOwnerID,
+		ActorID: &reposterID,
+		Type:    "REPOST",
+		Read:    false,
 	}
 
-	if err := s.notificationRepo.Create(ctx, notification); err != nil {
+	if err := s.deliver(ctx, notification); err != nil {
 		return fmt.Errorf("failed to create repost notification: %w", err)
 	}
 
@@ -218,6 +259,9 @@ func (s *NotificationService) NotifyPostRepost(ctx context.Context, postOwnerID,
 
 // NotifyPostReply creates a notification when someone replies to a post
 func (s *NotificationService) NotifyPostReply(ctx context.Context, postOwnerID, replierID string) error {
+	ctx, span := tracing.Start(ctx, "NotificationService.NotifyPostReply")
+	defer span.End()
+
 	if postOwnerID == "" || replierID == "" {
 		return errors.New("post owner id and replier id are required")
 	}
@@ -226,13 +270,18 @@ func (s *NotificationService) NotifyPostReply(ctx context.Context, postOwnerID,
 		return nil // Don't notify on self-reply
 	}
 
+	if suppressed, err := s.suppressed(ctx, postOwnerID, replierID); err != nil || suppressed {
+		return err
+	}
+
 	notification := &model.Notification{
-		UserID: postOwnerID,
-		Type:   "REPLY",
-		Read:   false,
+		UserID:  postOwnerID,
+		ActorID: &replierID,
+		Type:    "REPLY",
+		Read:    false,
 	}
 
-	if err := s.notificationRepo.Create(ctx, notification); err != nil {
+	if err := s.deliver(ctx, notification); err != nil {
 		return fmt.Errorf("failed to create reply notification: %w", err)
 	}
 
@@ -241,6 +290,9 @@ func (s *NotificationService) NotifyPostReply(ctx context.Context, postOwnerID,
 
 // NotifyMention creates a notification when someone mentions a user
 func (s *NotificationService) NotifyMention(ctx context.Context, mentionedUserID, mentionerID string) error {
+	ctx, span := tracing.Start(ctx, "NotificationService.NotifyMention")
+	defer span.End()
+
 	if mentionedUserID == "" || mentionerID == "" {
 		return errors.New("mentioned user id and mentioner id are required")
 	}
@@ -249,13 +301,18 @@ func (s *NotificationService) NotifyMention(ctx context.Context, mentionedUserID
 		return nil // Don't notify on self-mention
 	}
 
+	if suppressed, err := s.suppressed(ctx, mentionedUserID, mentionerID); err != nil || suppressed {
+		return err
+	}
+
 	notification := &model.Notification{
-		UserID: mentionedUserID,
-		Type:   "MENTION",
-		Read:   false,
+		UserID:  mentionedUserID,
+		ActorID: &mentionerID,
+		Type:    "MENTION",
+		Read:    false,
 	}
 
-	if err := s.notificationRepo.Create(ctx, notification); err != nil {
+	if err := s.deliver(ctx, notification); err != nil {
 		return fmt.Errorf("failed to create mention notification: %w", err)
 	}
 
@@ -264,25 +321,146 @@ func (s *NotificationService) NotifyMention(ctx context.Context, mentionedUserID
 
 // NotifyFollow creates a notification when someone follows a user
 func (s *NotificationService) NotifyFollow(ctx context.Context, followeeID, followerID string) error {
+	ctx, span := tracing.Start(ctx, "NotificationService.NotifyFollow")
+	defer span.End()
+
 	if followeeID == "" || followerID == "" {
 		return errors.New("followee id and follower id are required")
 	}
 
+	if suppressed, err := s.suppressed(ctx, followeeID, followerID); err != nil || suppressed {
+		return err
+	}
+
 	notification := &model.Notification{
-		UserID: followeeID,
-		Type:   "FOLLOW",
-		Read:   false,
+		UserID:  followeeID,
+		ActorID: &followerID,
+		Type:    "FOLLOW",
+		Read:    false,
 	}
 
-	if err := s.notificationRepo.Create(ctx, notification); err != nil {
+	if err := s.deliver(ctx, notification); err != nil {
 		return fmt.Errorf("failed to create follow notification: %w", err)
 	}
 
 	return nil
 }
 
+// NotifyFollowRequest tells a private account someone asked to follow it
+func (s *NotificationService) NotifyFollowRequest(ctx context.Context, targetID, requesterID string) error {
+	ctx, span := tracing.Start(ctx, "NotificationService.NotifyFollowRequest")
+	defer span.End()
+
+	if targetID == "" || requesterID == "" {
+		return errors.New("target id and requester id are required")
+	}
+
+	if suppressed, err := s.suppressed(ctx, targetID, requesterID); err != nil || suppressed {
+		return err
+	}
+
+	notification := &model.Notification{
+		UserID:  targetID,
+		ActorID: &requesterID,
+		Type:    "FOLLOW_REQUEST",
+		Read:    false,
+	}
+
+	if err := s.deliver(ctx, notification); err != nil {
+		return fmt.Errorf("failed to create follow request notification: %w", err)
+	}
+
+	return nil
+}
+
+// NotifyFollowRequestApproved tells a user their follow request was approved
+func (s *NotificationService) NotifyFollowRequestApproved(ctx context.Context, requesterID, targetID string) error {
+	ctx, span := tracing.Start(ctx, "NotificationService.NotifyFollowRequestApproved")
+	defer span.End()
+
+	if requesterID == "" || targetID == "" {
+		return errors.New("requester id and target id are required")
+	}
+
+	if suppressed, err := s.suppressed(ctx, requesterID, targetID); err != nil || suppressed {
+		return err
+	}
+
+	notification := &model.Notification{
+		UserID:  requesterID,
+		ActorID: &targetID,
+		Type:    "FOLLOW_REQUEST_APPROVED",
+		Read:    false,
+	}
+
+	if err := s.deliver(ctx, notification); err != nil {
+		return fmt.Errorf("failed to create follow request approved notification: %w", err)
+	}
+
+	return nil
+}
+
+// NotifyPollResults sends a closed poll's final results to its author or to
+// one of its voters
+func (s *NotificationService) NotifyPollResults(ctx context.Context, recipientID, authorID, postID, summary string) error {
+	ctx, span := tracing.Start(ctx, "NotificationService.NotifyPollResults")
+	defer span.End()
+
+	if recipientID == "" || authorID == "" || postID == "" {
+		return errors.New("recipient id, author id and post id are required")
+	}
+
+	if recipientID != authorID {
+		if suppressed, err := s.suppressed(ctx, recipientID, authorID); err != nil || suppressed {
+			return err
+		}
+	}
+
+	notification := &model.Notification{
+		UserID:  recipientID,
+		ActorID: &authorID,
+		PostID:  &postID,
+		Type:    "POLL_RESULTS",
+		Message: summary,
+		Read:    false,
+	}
+
+	if err := s.deliver(ctx, notification); err != nil {
+		return fmt.Errorf("failed to create poll results notification: %w", err)
+	}
+
+	return nil
+}
+
+// NotifyNewDeviceLogin tells a user their account was signed in to from a
+// device it hasn't been used on before
+func (s *NotificationService) NotifyNewDeviceLogin(ctx context.Context, userID, device, ip string) error {
+	ctx, span := tracing.Start(ctx, "NotificationService.NotifyNewDeviceLogin")
+	defer span.End()
+
+	if userID == "" {
+		return errors.New("user id is required")
+	}
+
+	notification := &model.Notification{
+		UserID:  userID,
+		Type:    "NEW_DEVICE_LOGIN",
+		Message: fmt.Sprintf("New sign-in from %s (%s). If this wasn't you, end the session and change your password.", device, ip),
+		Read:    false,
+	}
+
+	if err := s.deliver(ctx, notification); err != nil {
+		return fmt.Errorf("failed to create new device notification: %w", err)
+	}
+
+	return nil
+}
+
 // DeleteNotificationsByUserID deletes all notifications for a user
 func (s *NotificationService) DeleteNotificationsByUserID(ctx context.Context, userID string) error {
+	ctx, span := tracing.Start(ctx, "NotificationService.DeleteNotificationsByUserID")
+	defer span.End()
+
 	if userID == "" {
 		return errors.New("user id is required")
 	}
@@ -293,3 +471,29 @@ func (s *NotificationService) DeleteNotificationsByUserID(ctx context.Context, u
 
 	return nil
 }
+
+// suppressed reports whether a notification from actor to recipient should
+// be dropped: a block in either direction, or the recipient muted the actor
+func (s *NotificationService) suppressed(ctx context.Context, recipientID, actorID string) (bool, error) {
+	if err := checkNotBlocked(ctx, s.blockRepo, recipientID, actorID); err != nil {
+		if errors.Is(err, ErrBlocked) {
+			return true, nil
+		}
+		return false, err
+	}
+
+	muted, err := s.blockRepo.IsMuted(ctx, recipientID, actorID)
+	if err != nil {
+		return false, fmt.Errorf("failed to check mutes: %w", err)
+	}
+	return muted, nil
+}
+
+// deliver persists a notification and records it as delivered
+func (s *NotificationService) deliver(ctx context.Context, n *model.Notification) error {
+	if err := s.notificationRepo.Create(ctx, n); err != nil {
+		return err
+	}
+	metrics.NotificationsDelivered.WithLabelValues(n.Type).Inc()
+	return nil
+}
@@ -0,0 +1,307 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"goServer/internal/dto"
+	"goServer/internal/model"
+	"goServer/internal/repository"
+	"goServer/internal/tracing"
+
+	"gorm.io/gorm"
+)
+
+const (
+	pollMinDuration = 5 * time.Minute
+	pollMaxDuration = 7 * 24 * time.Hour
+)
+
+var (
+	ErrPollNotFound = errors.New("poll not found")
+	ErrPollClosed   = errors.New("this poll has closed")
+	ErrAlreadyVoted = errors.New("you have already voted in this poll")
+)
+
+// PollResultsNotifier delivers a closed poll's results to one user;
+// NotificationService.NotifyPollResults is one
+type PollResultsNotifier func(ctx context.Context, recipientID, authorID, postID, summary string) error
+
+// PollView is a poll as one viewer sees it. Counts stays nil until the
+// viewer has voted or the poll has closed.
+type PollView struct {
+	Poll        model.Poll
+	Closed      bool
+	VotedOption string           // "" if the viewer hasn't voted
+	Counts      map[string]int64 // votes per option ID
+	Total       int64
+}
+
+// PollService handles voting in polls attached to posts and sends their
+// final results once they close
+type PollService struct {
+	pollRepo  repository.PollRepository
+	postRepo  repository.PostRepository
+	userRepo  repository.UserRepository
+	blockRepo repository.BlockRepository
+}
+
+func NewPollService(plr repository.PollRepository, pr repository.PostRepository, ur repository.UserRepository, br repository.BlockRepository) *PollService {
+	return &PollService{pollRepo: plr, postRepo: pr, userRepo: ur, blockRepo: br}
+}
+
+// Views returns the polls attached to any of postIDs as viewerID sees them,
+// keyed by post ID. viewerID may be empty for signed-out viewers.
+func (s *PollService) Views(ctx context.Context, viewerID string, postIDs []string) (map[string]*PollView, error) {
+	ctx, span := tracing.Start(ctx, "PollService.Views")
+	defer span.End()
+
+	polls, err := s.pollRepo.FindByPostIDs(ctx, postIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find polls: %w", err)
+	}
+	views := make(map[string]*PollView, len(polls))
+	if len(polls) == 0 {
+		return views, nil
+	}
+
+	pollIDs := make([]string, len(polls))
+	for i, p := range polls {
+		pollIDs[i] = p.ID
+	}
+	voted, err := s.pollRepo.VotedOptions(ctx, viewerID, pollIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find votes: %w", err)
+	}
+
+	now := time.Now()
+	var visible []string
+	for _, p := range polls {
+		view := &PollView{
+			Poll:        p,
+			Closed:      !p.ClosesAt.After(now),
+			VotedOption: voted[p.ID],
+		}
+		if view.Closed || view.VotedOption != "" {
+			visible = append(visible, p.ID)
+		}
+		views[p.PostID] = view
+	}
+
+	counts, err := s.pollRepo.Counts(ctx, visible)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count votes: %w", err)
+	}
+	for _, view := range views {
+		if view.Closed || view.VotedOption != "" {
+			view.Counts = make(map[string]int64, len(view.Poll.Options))
+			for _, o := range view.Poll.Options {
+				view.Counts[o.ID] = counts[o.ID]
+				view.Total += counts[o.ID]
+			}
+		}
+	}
+	return views, nil
+}
+
+// Vote casts userID's vote in the poll on a post and returns the poll with
+// its results. Each user votes once and can't change their vote.
+func (s *PollService) Vote(ctx context.Context, userID, postID, optionID string) (*PollView, error) {
+	ctx, span := tracing.Start(ctx, "PollService.Vote")
+	defer span.End()
+
+	post, err := s.postRepo.FindByID(ctx, postID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find post: %w", err)
+	}
+	if post == nil {
+		return nil, errors.New("post not found")
+	}
+	if err := checkNotBlocked(ctx, s.blockRepo, userID, post.UserID); err != nil {
+		return nil, errors.New("post not found")
+	}
+	if err := checkCanSeePosts(ctx, s.userRepo, userID, &post.User); err != nil {
+		return nil, errors.New("post not found")
+	}
+
+	poll, err := s.pollRepo.FindByPostID(ctx, postID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find poll: %w", err)
+	}
+	if poll == nil {
+		return nil, ErrPollNotFound
+	}
+	if !poll.ClosesAt.After(time.Now()) {
+		return nil, ErrPollClosed
+	}
+
+	valid := false
+	for _, o := range poll.Options {
+		if o.ID == optionID {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return nil, errors.New("option is not part of this poll")
+	}
+
+	voted, err := s.pollRepo.Vote(ctx, &model.PollVote{PollID: poll.ID, UserID: userID, OptionID: optionID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to vote: %w", err)
+	}
+	if !voted {
+		return nil, ErrAlreadyVoted
+	}
+
+	views, err := s.Views(ctx, userID, []string{postID})
+	if err != nil {
+		return nil, err
+	}
+	return views[postID], nil
+}
+
+// Run sends the results of closed polls every interval until ctx is
+// cancelled
+func (s *PollService) Run(ctx context.Context, interval time.Duration, notify PollResultsNotifier) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			processed, err := s.sendNextResults(ctx, notify)
+			if err != nil {
+				log.Printf("[polls] %v", err)
+				break
+			}
+			if !processed {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sendNextResults tells the author and voters of the next closed poll how
+// it ended, reporting whether there was one. The poll is marked as sent
+// first, so a crash part way through skips the remaining recipients rather
+// than notifying anyone twice.
+func (s *PollService) sendNextResults(ctx context.Context, notify PollResultsNotifier) (bool, error) {
+	ctx, span := tracing.Start(ctx, "PollService.sendNextResults")
+	defer span.End()
+
+	var poll *model.Poll
+	err := s.pollRepo.Transaction(ctx, func(tx *gorm.DB) error {
+		var err error
+		poll, err = s.pollRepo.WithTx(tx).ClaimClosed(ctx, time.Now())
+		return err
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to claim closed poll: %w", err)
+	}
+	if poll == nil {
+		return false, nil
+	}
+
+	post, err := s.postRepo.FindByID(ctx, poll.PostID)
+	if err != nil {
+		return true, fmt.Errorf("failed to find post: %w", err)
+	}
+	if post == nil {
+		return true, nil // deleted since; nobody to tell
+	}
+
+	counts, err := s.pollRepo.Counts(ctx, []string{poll.ID})
+	if err != nil {
+		return true, fmt.Errorf("failed to count votes: %w", err)
+	}
+	voters, err := s.pollRepo.VoterIDs(ctx, poll.ID)
+	if err != nil {
+		return true, fmt.Errorf("failed to find voters: %w", err)
+	}
+
+	summary := pollSummary(poll, counts)
+	recipients := []string{post.UserID}
+	for _, id := range voters {
+		if id != post.UserID {
+			recipients = append(recipients, id)
+		}
+	}
+	for _, id := range recipients {
+		if err := notify(ctx, id, post.UserID, post.ID, summary); err != nil {
+			log.Printf("[polls] failed to send results of poll %s to %s: %v", poll.ID, id, err)
+		}
+	}
+	return true, nil
+}
+
+// pollSummary describes a closed poll's results, e.g.
+// `Final results (10 votes): Yes 70%, No 30%`
+func pollSummary(poll *model.Poll, counts map[string]int64) string {
+	var total int64
+	for _, o := range poll.Options {
+		total += counts[o.ID]
+	}
+
+	parts := make([]string, len(poll.Options))
+	for i, o := range poll.Options {
+		pct := int64(0)
+		if total > 0 {
+			pct = (counts[o.ID]*100 + total/2) / total
+		}
+		parts[i] = fmt.Sprintf("%s %d%%", o.Text, pct)
+	}
+
+	votes := "votes"
+	if total == 1 {
+		votes = "vote"
+	}
+	return fmt.Sprintf("Final results (%d %s): %s", total, votes, strings.Join(parts, ", "))
+}
+
+// checkPoll validates a poll about to be attached to a new post
+func checkPoll(req *dto.PollReq) error {
+	if len(req.Options) < 2 || len(req.Options) > 4 {
+		return errors.New("a poll needs 2 to 4 options")
+	}
+
+	seen := make(map[string]bool, len(req.Options))
+	for _, option := range req.Options {
+		option = strings.TrimSpace(option)
+		if option == "" || len([]rune(option)) > 50 {
+			return errors.New("poll options must be 1 to 50 characters")
+		}
+		if seen[strings.ToLower(option)] {
+			return errors.New("poll options must be different")
+		}
+		seen[strings.ToLower(option)] = true
+	}
+
+	duration := time.Duration(req.DurationMinutes) * time.Minute
+	if duration < pollMinDuration || duration > pollMaxDuration {
+		return errors.New("poll duration must be between 5 minutes and 7 days")
+	}
+	return nil
+}
+
+// newPoll builds the poll for a post being published now
+func newPoll(postID string, req *dto.PollReq) *model.Poll {
+	options := make([]model.PollOption, len(req.Options))
+	for i, text := range req.Options {
+		options[i] = model.PollOption{Position: i, Text: strings.TrimSpace(text)}
+	}
+	return &model.Poll{
+		PostID:   postID,
+		ClosesAt: time.Now().Add(time.Duration(req.DurationMinutes) * time.Minute),
+		Options:  options,
+	}
+}
@@ -5,44 +5,118 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
+	"goServer/internal/config"
 	"goServer/internal/dto"
+	"goServer/internal/metrics"
 	"goServer/internal/model"
 	"goServer/internal/repository"
+	"goServer/internal/tracing"
+	"goServer/pkg/utils"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrEditNotAllowed = errors.New("this post can no longer be edited")
+	ErrPinLimit       = errors.New("too many pinned posts")
+	ErrPostNotFound   = errors.New("post not found")
 )
 
 type PostService struct {
-	postRepo repository.PostRepository
-	userRepo repository.UserRepository
+	postRepo  repository.PostRepository
+	userRepo  repository.UserRepository
+	blockRepo repository.BlockRepository
+	cfg       config.Config
+}
+
+func NewPostService(pr repository.PostRepository, ur repository.UserRepository, br repository.BlockRepository, cfg config.Config) *PostService {
+	return &PostService{postRepo: pr, userRepo: ur, blockRepo: br, cfg: cfg}
 }
 
-func NewPostService(pr repository.PostRepository, ur repository.UserRepository) *PostService {
-	return &PostService{postRepo: pr, userRepo: ur}
+// CreatePost creates a new post. It also returns the users mentioned in it,
+// who should be notified.
+func (s *PostService) CreatePost(ctx context.Context, userID string, req dto.CreatePostReq) (*model.Post, []string, error) {
+	ctx, span := tracing.Start(ctx, "PostService.CreatePost")
+	defer span.End()
+
+	if err := checkNewPost(ctx, s.postRepo, s.userRepo, s.blockRepo, userID, req); err != nil {
+		return nil, nil, err
+	}
+
+	var post *model.Post
+	var mentioned []string
+	err := s.postRepo.Transaction(ctx, func(tx *gorm.DB) error {
+		var err error
+		post, mentioned, err = publishPost(ctx, *s.postRepo.WithTx(tx), s.userRepo, s.blockRepo, userID, req)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return post, mentioned, nil
 }
 
-// CreatePost creates a new post
-func (s *PostService) CreatePost(ctx context.Context, userID string, req dto.CreatePostReq) (*model.Post, error) {
+// checkNewPost validates a post userID is about to publish: the text, the
+// author, and that any post it replies to or quotes is one they can
+// interact with
+func checkNewPost(ctx context.Context, postRepo repository.PostRepository, userRepo repository.UserRepository, blockRepo repository.BlockRepository, userID string, req dto.CreatePostReq) error {
 	if userID == "" {
-		return nil, errors.New("user id is required")
+		return errors.New("user id is required")
 	}
 
 	if req.Text == "" {
-		return nil, errors.New("post text is required")
+		return errors.New("post text is required")
 	}
 
 	if len(req.Text) > 500 {
-		return nil, errors.New("post text exceeds 500 characters")
+		return errors.New("post text exceeds 500 characters")
+	}
+
+	if req.Poll != nil {
+		if err := checkPoll(req.Poll); err != nil {
+			return err
+		}
 	}
 
 	// Verify user exists
-	user, err := s.userRepo.FindByID(ctx, userID)
+	user, err := userRepo.FindByID(ctx, userID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to find user: %w", err)
+		return fmt.Errorf("failed to find user: %w", err)
 	}
 	if user == nil {
-		return nil, errors.New("user not found")
+		return errors.New("user not found")
+	}
+
+	// Replying to or quoting a post is an interaction with its author
+	for _, targetID := range []*string{req.ReplyTo, req.QuotedPostID} {
+		if targetID == nil {
+			continue
+		}
+		target, err := postRepo.FindByID(ctx, *targetID)
+		if err != nil {
+			return fmt.Errorf("failed to find post: %w", err)
+		}
+		if target == nil {
+			return errors.New("post not found")
+		}
+		if err := checkNotBlocked(ctx, blockRepo, userID, target.UserID); err != nil {
+			return err
+		}
+		if err := checkCanSeePosts(ctx, userRepo, userID, &target.User); err != nil {
+			return errors.New("post not found")
+		}
 	}
 
+	return nil
+}
+
+// publishPost creates a post checked by checkNewPost, with its media, poll,
+// mentions and hashtags. Run it in a transaction with postRepo bound to it,
+// so a failure part way through doesn't leave a half-built post. It also
+// returns the users mentioned in it, who should be notified.
+func publishPost(ctx context.Context, postRepo repository.PostRepository, userRepo repository.UserRepository, blockRepo repository.BlockRepository, userID string, req dto.CreatePostReq) (*model.Post, []string, error) {
 	post := &model.Post{
 		UserID:        userID,
 		Text:          strings.TrimSpace(req.Text),
@@ -52,76 +126,191 @@ func (s *PostService) CreatePost(ctx context.Context, userID string, req dto.Cre
 		QuotedTweetID: req.QuotedPostID,
 	}
 
-	if err := s.postRepo.Create(ctx, post); err != nil {
-		return nil, fmt.Errorf("failed to create post: %w", err)
+	if err := postRepo.Create(ctx, post); err != nil {
+		return nil, nil, fmt.Errorf("failed to create post: %w", err)
 	}
 
 	// Add media if provided
-	if len(req.MediaURLs) > 0 {
-		for i, url := range req.MediaURLs {
-			media := &model.Media{
-				PostID:   post.ID,
-				URL:      url,
-				Position: i,
-			}
-			s.postRepo.AddMedia(ctx, media)
+	for i, url := range req.MediaURLs {
+		media := &model.Media{
+			PostID:   post.ID,
+			URL:      url,
+			Position: i,
+		}
+		if err := postRepo.AddMedia(ctx, media); err != nil {
+			return nil, nil, fmt.Errorf("failed to add media: %w", err)
 		}
 	}
 
-	return post, nil
+	if req.Poll != nil {
+		poll := newPoll(post.ID, req.Poll)
+		if err := postRepo.CreatePoll(ctx, poll); err != nil {
+			return nil, nil, fmt.Errorf("failed to create poll: %w", err)
+		}
+		post.Poll = poll
+	}
+
+	mentioned, err := resolveMentions(ctx, userRepo, blockRepo, userID, post.Text)
+	if err != nil {
+		return nil, nil, err
+	}
+	mentioned, err = postRepo.SetTags(ctx, post.ID, mentioned, utils.ExtractHashtags(post.Text))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to save mentions: %w", err)
+	}
+	metrics.PostsCreated.Inc()
+
+	return post, mentioned, nil
 }
 
-// GetPostByID retrieves a post by ID
-func (s *PostService) GetPostByID(ctx context.Context, postID string) (*model.Post, error) {
+// GetPostByID retrieves a post by ID. Posts by users on either side of a
+// block with the viewer, or by private accounts the viewer doesn't follow,
+// are reported as not found.
+func (s *PostService) GetPostByID(ctx context.Context, postID, viewerID string) (*model.Post, error) {
+	ctx, span := tracing.Start(ctx, "PostService.GetPostByID")
+	defer span.End()
+
 	if postID == "" {
 		return nil, errors.New("post id is required")
 	}
 
-	post, err := s.postRepo.FindByID(ctx, postID)
+	// Deleted posts are returned too so threads can render them as tombstones
+	post, err := s.postRepo.FindByIDWithDeleted(ctx, postID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to find post: %w", err)
 	}
 	if post == nil {
 		return nil, errors.New("post not found")
 	}
+	if err := checkNotBlocked(ctx, s.blockRepo, viewerID, post.UserID); err != nil {
+		return nil, errors.New("post not found")
+	}
+	if err := checkCanSeePosts(ctx, s.userRepo, viewerID, &post.User); err != nil {
+		return nil, errors.New("post not found")
+	}
 
 	return post, nil
 }
 
-// UpdatePost updates a post's text (only by creator)
-func (s *PostService) UpdatePost(ctx context.Context, postID, userID, text string) (*model.Post, error) {
+// UpdatePost edits a post's text (only by creator), keeping the previous
+// text in the post's history. Edits are allowed within PostEditWindow of
+// posting, at most PostMaxEdits times. It also returns the users newly
+// mentioned by the edit, who should be notified.
+func (s *PostService) UpdatePost(ctx context.Context, postID, userID, text string) (*model.Post, []string, error) {
+	ctx, span := tracing.Start(ctx, "PostService.UpdatePost")
+	defer span.End()
+
 	if postID == "" || userID == "" {
-		return nil, errors.New("post id and user id are required")
+		return nil, nil, errors.New("post id and user id are required")
 	}
 
+	text = strings.TrimSpace(text)
 	if text == "" || len(text) > 500 {
-		return nil, errors.New("invalid post text")
+		return nil, nil, errors.New("invalid post text")
 	}
 
 	post, err := s.postRepo.FindByID(ctx, postID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to find post: %w", err)
+		return nil, nil, fmt.Errorf("failed to find post: %w", err)
 	}
 	if post == nil {
-		return nil, errors.New("post not found")
+		return nil, nil, errors.New("post not found")
 	}
 
 	if post.UserID != userID {
-		return nil, errors.New("unauthorized: can only edit your own posts")
+		return nil, nil, errors.New("unauthorized: can only edit your own posts")
 	}
 
-	post.Text = strings.TrimSpace(text)
-	post.CharCount = len(text)
+	editableSince := time.Now().Add(-s.cfg.PostEditWindow)
+	if post.CreatedAt.Before(editableSince) {
+		return nil, nil, fmt.Errorf("%w: posts can only be edited within %s of posting", ErrEditNotAllowed, s.cfg.PostEditWindow)
+	}
+	if post.EditCount >= s.cfg.PostMaxEdits {
+		return nil, nil, fmt.Errorf("%w: posts can be edited at most %d times", ErrEditNotAllowed, s.cfg.PostMaxEdits)
+	}
+	if text == post.Text {
+		return post, nil, nil
+	}
 
-	if err := s.postRepo.Update(ctx, post); err != nil {
-		return nil, fmt.Errorf("failed to update post: %w", err)
+	mentioned, err := resolveMentions(ctx, s.userRepo, s.blockRepo, userID, text)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	return post, nil
+	err = s.postRepo.Transaction(ctx, func(tx *gorm.DB) error {
+		repo := s.postRepo.WithTx(tx)
+		revised, err := repo.Revise(ctx, post, text, s.cfg.PostMaxEdits, editableSince)
+		if err != nil {
+			return fmt.Errorf("failed to update post: %w", err)
+		}
+		if !revised {
+			// Edited concurrently, or the window closed in the meantime
+			return ErrEditNotAllowed
+		}
+
+		mentioned, err = repo.SetTags(ctx, post.ID, mentioned, utils.ExtractHashtags(text))
+		if err != nil {
+			return fmt.Errorf("failed to save mentions: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return post, mentioned, nil
+}
+
+// GetPostHistory gets the prior versions of a post, oldest first. The same
+// visibility rules as GetPostByID apply.
+func (s *PostService) GetPostHistory(ctx context.Context, postID, viewerID string) (*model.Post, []model.PostRevision, error) {
+	ctx, span := tracing.Start(ctx, "PostService.GetPostHistory")
+	defer span.End()
+
+	post, err := s.GetPostByID(ctx, postID, viewerID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if post.DeletedAt.Valid {
+		return nil, nil, errors.New("post not found")
+	}
+
+	revisions, err := s.postRepo.GetRevisions(ctx, post.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get post history: %w", err)
+	}
+	return post, revisions, nil
+}
+
+// resolveMentions turns the @usernames in text into user IDs, skipping the
+// author and anyone on either side of a block with them
+func resolveMentions(ctx context.Context, userRepo repository.UserRepository, blockRepo repository.BlockRepository, authorID, text string) ([]string, error) {
+	users, err := userRepo.FindByUsernames(ctx, utils.ExtractMentions(text))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve mentions: %w", err)
+	}
+
+	ids := make([]string, 0, len(users))
+	for _, u := range users {
+		if u.ID == authorID {
+			continue
+		}
+		if err := checkNotBlocked(ctx, blockRepo, authorID, u.ID); err != nil {
+			if errors.Is(err, ErrBlocked) {
+				continue
+			}
+			return nil, err
+		}
+		ids = append(ids, u.ID)
+	}
+	return ids, nil
 }
 
 // DeletePost deletes a post (only by creator or admin)
 func (s *PostService) DeletePost(ctx context.Context, postID, userID string) error {
+	ctx, span := tracing.Start(ctx, "PostService.DeletePost")
+	defer span.End()
+
 	if postID == "" || userID == "" {
 		return errors.New("post id and user id are required")
 	}
@@ -147,6 +336,9 @@ func (s *PostService) DeletePost(ctx context.Context, postID, userID string) err
 
 // GetFeed retrieves the user's feed (posts from following)
 func (s *PostService) GetFeed(ctx context.Context, userID string, limit, offset int) ([]model.Post, error) {
+	ctx, span := tracing.Start(ctx, "PostService.GetFeed")
+	defer span.End()
+
 	if userID == "" {
 		return nil, errors.New("user id is required")
 	}
@@ -166,18 +358,30 @@ func (s *PostService) GetFeed(ctx context.Context, userID string, limit, offset
 	return posts, nil
 }
 
-// GetUserTimeline retrieves all posts from a specific user
-func (s *PostService) GetUserTimeline(ctx context.Context, username string, limit, offset int) ([]model.Post, error) {
+// GetUserTimeline retrieves all posts from a specific user, along with the
+// posts they pinned on the first page; pinned posts are left out of the
+// rest of the timeline. A private account's timeline is only shown to the
+// owner and approved followers.
+func (s *PostService) GetUserTimeline(ctx context.Context, username, viewerID string, limit, offset int) ([]model.Post, []model.Post, error) {
+	ctx, span := tracing.Start(ctx, "PostService.GetUserTimeline")
+	defer span.End()
+
 	if username == "" {
-		return nil, errors.New("username is required")
+		return nil, nil, errors.New("username is required")
 	}
 
 	user, err := s.userRepo.FindByUsername(ctx, username)
 	if err != nil {
-		return nil, fmt.Errorf("failed to find user: %w", err)
+		return nil, nil, fmt.Errorf("failed to find user: %w", err)
 	}
 	if user == nil {
-		return nil, errors.New("user not found")
+		return nil, nil, errors.New("user not found")
+	}
+	if err := checkNotBlocked(ctx, s.blockRepo, viewerID, user.ID); err != nil {
+		return nil, nil, errors.New("user not found")
+	}
+	if err := checkCanSeePosts(ctx, s.userRepo, viewerID, user); err != nil {
+		return nil, nil, err
 	}
 
 	if limit <= 0 || limit > 100 {
@@ -187,16 +391,64 @@ func (s *PostService) GetUserTimeline(ctx context.Context, username string, limi
 		offset = 0
 	}
 
+	var pinned []model.Post
+	if offset == 0 {
+		if pinned, err = s.postRepo.GetPinnedPosts(ctx, user.ID); err != nil {
+			return nil, nil, fmt.Errorf("failed to get pinned posts: %w", err)
+		}
+	}
+
 	posts, err := s.postRepo.GetUserPosts(ctx, user.ID, limit, offset)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user timeline: %w", err)
+		return nil, nil, fmt.Errorf("failed to get user timeline: %w", err)
 	}
 
-	return posts, nil
+	return pinned, posts, nil
+}
+
+// PinPost pins one of the user's own posts to the top of their profile
+func (s *PostService) PinPost(ctx context.Context, userID, postID string) error {
+	ctx, span := tracing.Start(ctx, "PostService.PinPost")
+	defer span.End()
+
+	post, err := s.postRepo.FindByID(ctx, postID)
+	if err != nil {
+		return fmt.Errorf("failed to find post: %w", err)
+	}
+	if post == nil {
+		return errors.New("post not found")
+	}
+	if post.UserID != userID {
+		return errors.New("unauthorized: can only pin your own posts")
+	}
+
+	pinned, err := s.postRepo.Pin(ctx, userID, postID, s.cfg.PostMaxPins)
+	if err != nil {
+		return fmt.Errorf("failed to pin post: %w", err)
+	}
+	if !pinned {
+		return fmt.Errorf("%w: you can pin at most %d posts", ErrPinLimit, s.cfg.PostMaxPins)
+	}
+	return nil
+}
+
+// UnpinPost unpins a post from the user's profile. Unpinning a post that
+// isn't pinned is not an error.
+func (s *PostService) UnpinPost(ctx context.Context, userID, postID string) error {
+	ctx, span := tracing.Start(ctx, "PostService.UnpinPost")
+	defer span.End()
+
+	if err := s.postRepo.Unpin(ctx, userID, postID); err != nil {
+		return fmt.Errorf("failed to unpin post: %w", err)
+	}
+	return nil
 }
 
 // LikePost likes a post
 func (s *PostService) LikePost(ctx context.Context, userID, postID string) error {
+	ctx, span := tracing.Start(ctx, "PostService.LikePost")
+	defer span.End()
+
 	if userID == "" || postID == "" {
 		return errors.New("user id and post id are required")
 	}
@@ -209,6 +461,12 @@ func (s *PostService) LikePost(ctx context.Context, userID, postID string) error
 	if post == nil {
 		return errors.New("post not found")
 	}
+	if err := checkNotBlocked(ctx, s.blockRepo, userID, post.UserID); err != nil {
+		return err
+	}
+	if err := checkCanSeePosts(ctx, s.userRepo, userID, &post.User); err != nil {
+		return errors.New("post not found")
+	}
 
 	// Check if already liked
 	isLiked, err := s.postRepo.IsPostLiked(ctx, userID, postID)
@@ -222,12 +480,16 @@ func (s *PostService) LikePost(ctx context.Context, userID, postID string) error
 	if err := s.postRepo.LikePost(ctx, userID, postID); err != nil {
 		return fmt.Errorf("failed to like post: %w", err)
 	}
+	metrics.LikesCreated.Inc()
 
 	return nil
 }
 
 // UnlikePost unlikes a post
 func (s *PostService) UnlikePost(ctx context.Context, userID, postID string) error {
+	ctx, span := tracing.Start(ctx, "PostService.UnlikePost")
+	defer span.End()
+
 	if userID == "" || postID == "" {
 		return errors.New("user id and post id are required")
 	}
@@ -250,6 +512,9 @@ func (s *PostService) UnlikePost(ctx context.Context, userID, postID string) err
 
 // RepostPost reposts a post
 func (s *PostService) RepostPost(ctx context.Context, userID, postID string) error {
+	ctx, span := tracing.Start(ctx, "PostService.RepostPost")
+	defer span.End()
+
 	if userID == "" || postID == "" {
 		return errors.New("user id and post id are required")
 	}
@@ -265,6 +530,12 @@ func (s *PostService) RepostPost(ctx context.Context, userID, postID string) err
 	if post.UserID == userID {
 		return errors.New("cannot repost your own post")
 	}
+	if err := checkNotBlocked(ctx, s.blockRepo, userID, post.UserID); err != nil {
+		return err
+	}
+	if err := checkCanSeePosts(ctx, s.userRepo, userID, &post.User); err != nil {
+		return errors.New("post not found")
+	}
 
 	isReposted, err := s.postRepo.IsPostReposted(ctx, userID, postID)
 	if err != nil {
@@ -283,6 +554,9 @@ func (s *PostService) RepostPost(ctx context.Context, userID, postID string) err
 
 // UndoRepost removes a repost
 func (s *PostService) UndoRepost(ctx context.Context, userID, postID string) error {
+	ctx, span := tracing.Start(ctx, "PostService.UndoRepost")
+	defer span.End()
+
 	if userID == "" || postID == "" {
 		return errors.New("user id and post id are required")
 	}
@@ -304,18 +578,51 @@ func (s *PostService) UndoRepost(ctx context.Context, userID, postID string) err
 
 // IsPostLiked checks if a post is liked by a user
 func (s *PostService) IsPostLiked(ctx context.Context, userID, postID string) (bool, error) {
+	ctx, span := tracing.Start(ctx, "PostService.IsPostLiked")
+	defer span.End()
+
 	return s.postRepo.IsPostLiked(ctx, userID, postID)
 }
 
 // IsPostReposted checks if a post is reposted by a user
 func (s *PostService) IsPostReposted(ctx context.Context, userID, postID string) (bool, error) {
+	ctx, span := tracing.Start(ctx, "PostService.IsPostReposted")
+	defer span.End()
+
 	return s.postRepo.IsPostReposted(ctx, userID, postID)
 }
 
-// GetPostLikes gets all users who liked a post
-func (s *PostService) GetPostLikes(ctx context.Context, postID string, limit, offset int) ([]model.User, error) {
+// checkCanSeePost returns ErrPostNotFound unless postID is a live post whose
+// author has no block with viewerID either way, and ErrPrivateAccount when
+// the author's posts are hidden from viewerID
+func (s *PostService) checkCanSeePost(ctx context.Context, postID, viewerID string) error {
 	if postID == "" {
-		return nil, errors.New("post id is required")
+		return errors.New("post id is required")
+	}
+
+	post, err := s.postRepo.FindByID(ctx, postID)
+	if err != nil {
+		return fmt.Errorf("failed to find post: %w", err)
+	}
+	if post == nil {
+		return ErrPostNotFound
+	}
+	if err := checkNotBlocked(ctx, s.blockRepo, viewerID, post.UserID); err != nil {
+		if errors.Is(err, ErrBlocked) {
+			return ErrPostNotFound
+		}
+		return err
+	}
+	return checkCanSeePosts(ctx, s.userRepo, viewerID, &post.User)
+}
+
+// GetPostLikes gets all users who liked a post
+func (s *PostService) GetPostLikes(ctx context.Context, postID, viewerID string, limit, offset int) ([]model.User, error) {
+	ctx, span := tracing.Start(ctx, "PostService.GetPostLikes")
+	defer span.End()
+
+	if err := s.checkCanSeePost(ctx, postID, viewerID); err != nil {
+		return nil, err
 	}
 
 	if limit <= 0 || limit > 100 {
@@ -325,7 +632,7 @@ func (s *PostService) GetPostLikes(ctx context.Context, postID string, limit, of
 		offset = 0
 	}
 
-	users, err := s.postRepo.GetPostLikes(ctx, postID, limit, offset)
+	users, err := s.postRepo.GetPostLikes(ctx, postID, viewerID, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get post likes: %w", err)
 	}
@@ -334,9 +641,12 @@ func (s *PostService) GetPostLikes(ctx context.Context, postID string, limit, of
 }
 
 // GetPostReposts gets all users who reposted a post
-func (s *PostService) GetPostReposts(ctx context.Context, postID string, limit, offset int) ([]model.User, error) {
-	if postID == "" {
-		return nil, errors.New("post id is required")
+func (s *PostService) GetPostReposts(ctx context.Context, postID, viewerID string, limit, offset int) ([]model.User, error) {
+	ctx, span := tracing.Start(ctx, "PostService.GetPostReposts")
+	defer span.End()
+
+	if err := s.checkCanSeePost(ctx, postID, viewerID); err != nil {
+		return nil, err
 	}
 
 	if limit <= 0 || limit > 100 {
@@ -346,7 +656,7 @@ func (s *PostService) GetPostReposts(ctx context.Context, postID string, limit,
 		offset = 0
 	}
 
-	users, err := s.postRepo.GetPostReposts(ctx, postID, limit, offset)
+	users, err := s.postRepo.GetPostReposts(ctx, postID, viewerID, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get post reposts: %w", err)
 	}
@@ -355,7 +665,10 @@ func (s *PostService) GetPostReposts(ctx context.Context, postID string, limit,
 }
 
 // GetReplies gets all replies to a post
-func (s *PostService) GetReplies(ctx context.Context, postID string, limit, offset int) ([]model.Post, error) {
+func (s *PostService) GetReplies(ctx context.Context, postID, viewerID string, limit, offset int) ([]model.Post, error) {
+	ctx, span := tracing.Start(ctx, "PostService.GetReplies")
+	defer span.End()
+
 	if postID == "" {
 		return nil, errors.New("post id is required")
 	}
@@ -367,7 +680,7 @@ func (s *PostService) GetReplies(ctx context.Context, postID string, limit, offs
 		offset = 0
 	}
 
-	posts, err := s.postRepo.GetReplies(ctx, postID, limit, offset)
+	posts, err := s.postRepo.GetReplies(ctx, postID, viewerID, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get replies: %w", err)
 	}
@@ -376,7 +689,10 @@ func (s *PostService) GetReplies(ctx context.Context, postID string, limit, offs
 }
 
 // SearchPosts searches for posts by text
-func (s *PostService) SearchPosts(ctx context.Context, query string, limit, offset int) ([]model.Post, error) {
+func (s *PostService) SearchPosts(ctx context.Context, query, viewerID string, limit, offset int) ([]model.Post, error) {
+	ctx, span := tracing.Start(ctx, "PostService.SearchPosts")
+	defer span.End()
+
 	if query == "" {
 		return nil, errors.New("search query is required")
 	}
@@ -388,23 +704,10 @@ func (s *PostService) SearchPosts(ctx context.Context, query string, limit, offs
 		offset = 0
 	}
 
-	posts, err := s.postRepo.SearchPosts(ctx, query, limit, offset)
+	posts, err := s.postRepo.SearchPosts(ctx, query, viewerID, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search posts: %w", err)
 	}
 
 	return posts, nil
 }
-
-// DeletePostAdmin deletes a post (admin only)
-func (s *PostService) DeletePostAdmin(ctx context.Context, postID string) error {
-	if postID == "" {
-		return errors.New("post id is required")
-	}
-
-	if err := s.postRepo.Delete(ctx, postID); err != nil {
-		return fmt.Errorf("failed to delete post: %w", err)
-	}
-
-	return nil
-}
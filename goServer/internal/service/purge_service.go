@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"goServer/internal/repository"
+	"goServer/internal/tracing"
+)
+
+// PurgeService hard-deletes soft-deleted rows once they are older than the retention period
+type PurgeService struct {
+	userRepo         repository.UserRepository
+	postRepo         repository.PostRepository
+	notificationRepo repository.NotificationRepository
+	tokenRepo        repository.TokenRepository
+	throttleRepo     repository.LoginThrottleRepository
+	federationRepo   repository.FederationRepository
+	sessionRepo      repository.SessionRepository
+	retention        time.Duration
+	sessionRetention time.Duration
+}
+
+func NewPurgeService(ur repository.UserRepository, pr repository.PostRepository, nr repository.NotificationRepository, tr repository.TokenRepository, lr repository.LoginThrottleRepository, fr repository.FederationRepository, sr repository.SessionRepository, retention, sessionRetention time.Duration) *PurgeService {
+	return &PurgeService{
+		userRepo:         ur,
+		postRepo:         pr,
+		notificationRepo: nr,
+		tokenRepo:        tr,
+		throttleRepo:     lr,
+		federationRepo:   fr,
+		sessionRepo:      sr,
+		retention:        retention,
+		sessionRetention: sessionRetention,
+	}
+}
+
+// Run purges expired rows every interval until ctx is cancelled
+func (s *PurgeService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Purge(ctx); err != nil {
+			log.Printf("[purge] %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Purge hard-deletes everything soft-deleted before now minus the retention period
+func (s *PurgeService) Purge(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "PurgeService.Purge")
+	defer span.End()
+
+	if err := s.tokenRepo.DeleteExpired(ctx, time.Now()); err != nil {
+		return err
+	}
+	if err := s.throttleRepo.DeleteStale(ctx, time.Now().Add(-24*time.Hour)); err != nil {
+		return err
+	}
+	if err := s.federationRepo.DeleteExpiredAuthRequests(ctx, time.Now()); err != nil {
+		return err
+	}
+	// Ended sessions are kept a while so returning devices aren't reported as new
+	if err := s.sessionRepo.DeleteEnded(ctx, time.Now().Add(-s.sessionRetention)); err != nil {
+		return err
+	}
+
+	scheduled, err := s.userRepo.DeleteScheduled(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	if scheduled > 0 {
+		log.Printf("[purge] deleted %d accounts whose deletion grace period ended", scheduled)
+	}
+
+	cutoff := time.Now().Add(-s.retention)
+
+	notifications, err := s.notificationRepo.PurgeDeleted(ctx, cutoff)
+	if err != nil {
+		return err
+	}
+
+	posts, err := s.postRepo.PurgeDeleted(ctx, cutoff)
+	if err != nil {
+		return err
+	}
+
+	users, err := s.userRepo.PurgeDeleted(ctx, cutoff)
+	if err != nil {
+		return err
+	}
+
+	if notifications+posts+users > 0 {
+		log.Printf("[purge] removed %d users, %d posts, %d notifications deleted before %s",
+			users, posts, notifications, cutoff.Format(time.RFC3339))
+	}
+
+	return nil
+}
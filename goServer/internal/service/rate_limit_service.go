@@ -8,6 +8,7 @@ import (
 
 	"goServer/internal/model"
 	"goServer/internal/repository"
+	"goServer/internal/tracing"
 )
 
 type RateLimitService struct {
@@ -20,6 +21,9 @@ func NewRateLimitService(rlr repository.RateLimitRepository) *RateLimitService {
 
 // CheckLimit checks if a user has exceeded rate limit
 func (s *RateLimitService) CheckLimit(ctx context.Context, userID, action string, limit int, window time.Duration) (bool, error) {
+	ctx, span := tracing.Start(ctx, "RateLimitService.CheckLimit")
+	defer span.End()
+
 	if userID == "" || action == "" {
 		return false, errors.New("user id and action are required")
 	}
@@ -48,6 +52,9 @@ func (s *RateLimitService) CheckLimit(ctx context.Context, userID, action string
 
 // GetRateLimit retrieves rate limit info
 func (s *RateLimitService) GetRateLimit(ctx context.Context, userID, action string) (*model.RateLimit, error) {
+	ctx, span := tracing.Start(ctx, "RateLimitService.GetRateLimit")
+	defer span.End()
+
 	if userID == "" || action == "" {
 		return nil, errors.New("user id and action are required")
 	}
@@ -62,6 +69,9 @@ func (s *RateLimitService) GetRateLimit(ctx context.Context, userID, action stri
 
 // ResetRateLimit resets rate limit for a user action
 func (s *RateLimitService) ResetRateLimit(ctx context.Context, userID, action string) error {
+	ctx, span := tracing.Start(ctx, "RateLimitService.ResetRateLimit")
+	defer span.End()
+
 	if userID == "" || action == "" {
 		return errors.New("user id and action are required")
 	}
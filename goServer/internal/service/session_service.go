@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"goServer/internal/config"
+	"goServer/internal/model"
+	"goServer/internal/repository"
+	"goServer/internal/tracing"
+	"goServer/pkg/utils"
+)
+
+// lastSeenInterval bounds how often a session's last-seen time is written
+const lastSeenInterval = time.Minute
+
+// SessionService tracks where users are logged in. Each access token
+// belongs to a session, and a token is only accepted while its session is.
+type SessionService struct {
+	sessionRepo repository.SessionRepository
+	userRepo    repository.UserRepository
+	cfg         config.Config
+}
+
+func NewSessionService(sr repository.SessionRepository, ur repository.UserRepository, cfg config.Config) *SessionService {
+	return &SessionService{
+		sessionRepo: sr,
+		userRepo:    ur,
+		cfg:         cfg,
+	}
+}
+
+// Create starts a session for a login. newDevice reports a login from a
+// device the user hasn't used before; a user's very first login doesn't count.
+// deviceID is an optional stable identifier sent by the client; without it
+// the device is recognized by its user agent.
+func (s *SessionService) Create(ctx context.Context, user *model.User, userAgent, deviceID, ip string, mfa bool) (*model.Session, bool, error) {
+	ctx, span := tracing.Start(ctx, "SessionService.Create")
+	defer span.End()
+
+	if deviceID == "" {
+		deviceID = userAgent
+	}
+	deviceHash := utils.HashToken(deviceID)
+
+	known, seen, err := s.sessionRepo.HasDevice(ctx, user.ID, deviceHash)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to check device: %w", err)
+	}
+
+	now := time.Now()
+	session := &model.Session{
+		UserID:       user.ID,
+		UserAgent:    userAgent,
+		Device:       utils.DescribeUserAgent(userAgent),
+		DeviceHash:   deviceHash,
+		IP:           ip,
+		TokenVersion: user.TokenVersion,
+		MFA:          mfa,
+		LastSeenAt:   now,
+		ExpiresAt:    now.Add(s.cfg.SessionTTL),
+	}
+	if err := s.sessionRepo.Create(ctx, session); err != nil {
+		return nil, false, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return session, seen && !known, nil
+}
+
+// ValidateToken accepts an access token only while the user's token version
+// matches and its session is active. It satisfies middleware.TokenValidator.
+func (s *SessionService) ValidateToken(ctx context.Context, userID string, tokenVersion int, sessionID string, ip string) error {
+	ctx, span := tracing.Start(ctx, "SessionService.ValidateToken")
+	defer span.End()
+
+	if sessionID == "" {
+		return errors.New("token has no session")
+	}
+
+	session, err := s.sessionRepo.FindActive(ctx, userID, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to find session: %w", err)
+	}
+	if session == nil || session.TokenVersion != tokenVersion {
+		return errors.New("session has ended")
+	}
+
+	if err := s.sessionRepo.Touch(ctx, session.ID, ip, lastSeenInterval); err != nil {
+		return fmt.Errorf("failed to record session activity: %w", err)
+	}
+	return nil
+}
+
+// List returns the user's active sessions
+func (s *SessionService) List(ctx context.Context, userID string) ([]model.Session, error) {
+	ctx, span := tracing.Start(ctx, "SessionService.List")
+	defer span.End()
+
+	return s.sessionRepo.ListActive(ctx, userID)
+}
+
+// Revoke logs one of the user's sessions out
+func (s *SessionService) Revoke(ctx context.Context, userID, sessionID string) error {
+	ctx, span := tracing.Start(ctx, "SessionService.Revoke")
+	defer span.End()
+
+	revoked, err := s.sessionRepo.Revoke(ctx, userID, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	if !revoked {
+		return errors.New("session not found")
+	}
+	return nil
+}
+
+// RevokeOthers logs the user out everywhere except the current session
+func (s *SessionService) RevokeOthers(ctx context.Context, userID, currentID string) (int64, error) {
+	ctx, span := tracing.Start(ctx, "SessionService.RevokeOthers")
+	defer span.End()
+
+	count, err := s.sessionRepo.RevokeAllExcept(ctx, userID, currentID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	return count, nil
+}
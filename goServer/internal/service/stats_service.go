@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"goServer/internal/dto"
+	"goServer/internal/repository"
+	"goServer/internal/tracing"
+)
+
+// statsRanges maps a series interval to how far back the series reaches
+var statsRanges = map[string]time.Duration{
+	"hour": 24 * time.Hour,
+	"day":  30 * 24 * time.Hour,
+	"week": 12 * 7 * 24 * time.Hour,
+}
+
+type StatsService struct {
+	statsRepo repository.StatsRepository
+	userRepo  repository.UserRepository
+	blockRepo repository.BlockRepository
+
+	mu          sync.RWMutex
+	totals      *repository.SystemTotals
+	refreshedAt time.Time
+	maxAge      time.Duration
+}
+
+func NewStatsService(sr repository.StatsRepository, ur repository.UserRepository, br repository.BlockRepository, maxAge time.Duration) *StatsService {
+	return &StatsService{statsRepo: sr, userRepo: ur, blockRepo: br, maxAge: maxAge}
+}
+
+// Run refreshes the cached system totals every interval until ctx is cancelled
+func (s *StatsService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.refresh(ctx); err != nil {
+			log.Printf("[stats] refresh failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refresh recomputes the system totals and stores them as the current snapshot
+func (s *StatsService) refresh(ctx context.Context) (*repository.SystemTotals, error) {
+	now := time.Now()
+	totals, err := s.statsRepo.GetSystemTotals(ctx, now.Add(-24*time.Hour), now)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.totals = totals
+	s.refreshedAt = now
+	s.mu.Unlock()
+
+	return totals, nil
+}
+
+// snapshot returns the cached totals, recomputing them if they are missing or stale
+func (s *StatsService) snapshot(ctx context.Context) (*repository.SystemTotals, time.Time, error) {
+	s.mu.RLock()
+	totals, refreshedAt := s.totals, s.refreshedAt
+	s.mu.RUnlock()
+
+	if totals != nil && time.Since(refreshedAt) < s.maxAge {
+		return totals, refreshedAt, nil
+	}
+
+	totals, err := s.refresh(ctx)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return totals, time.Now(), nil
+}
+
+// GetSystemStats returns platform-wide totals plus a time-series breakdown
+func (s *StatsService) GetSystemStats(ctx context.Context, interval string) (*dto.StatsRes, error) {
+	ctx, span := tracing.Start(ctx, "StatsService.GetSystemStats")
+	defer span.End()
+
+	if interval == "" {
+		interval = "day"
+	}
+	window, ok := statsRanges[interval]
+	if !ok {
+		return nil, errors.New("interval must be one of hour, day, week")
+	}
+
+	totals, generatedAt, err := s.snapshot(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get system totals: %w", err)
+	}
+
+	points, err := s.statsRepo.GetSeries(ctx, interval, time.Now().Add(-window))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stats series: %w", err)
+	}
+
+	return &dto.StatsRes{
+		TotalUsers:     totals.TotalUsers,
+		TotalPosts:     totals.TotalPosts,
+		TotalLikes:     totals.TotalLikes,
+		TotalReposts:   totals.TotalReposts,
+		ActiveUsers:    totals.ActiveUsers,
+		PostsLastHour:  totals.PostsLastHour,
+		PostsLastDay:   totals.PostsLastDay,
+		PostsLastWeek:  totals.PostsLastWeek,
+		EngagementRate: engagementRate(totals.TotalLikes+totals.TotalReposts+totals.TotalReplies, totals.TotalPosts),
+		Interval:       interval,
+		Series:         seriesToRes(points),
+		GeneratedAt:    generatedAt.Format(time.RFC3339),
+	}, nil
+}
+
+// GetUserStats returns engagement stats for a single user. Like their
+// timeline, a private account's stats are only shown to the owner and
+// approved followers.
+func (s *StatsService) GetUserStats(ctx context.Context, username, viewerID, interval string) (*dto.UserStatsRes, error) {
+	ctx, span := tracing.Start(ctx, "StatsService.GetUserStats")
+	defer span.End()
+
+	if username == "" {
+		return nil, errors.New("username is required")
+	}
+
+	if interval == "" {
+		interval = "day"
+	}
+	window, ok := statsRanges[interval]
+	if !ok {
+		return nil, errors.New("interval must be one of hour, day, week")
+	}
+
+	user, err := s.userRepo.FindByUsername(ctx, username)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+	if err := checkNotBlocked(ctx, s.blockRepo, viewerID, user.ID); err != nil {
+		return nil, errors.New("user not found")
+	}
+	if err := checkCanSeePosts(ctx, s.userRepo, viewerID, user); err != nil {
+		return nil, err
+	}
+
+	totals, err := s.statsRepo.GetUserTotals(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user totals: %w", err)
+	}
+
+	points, err := s.statsRepo.GetUserSeries(ctx, user.ID, interval, time.Now().Add(-window))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user stats series: %w", err)
+	}
+
+	return &dto.UserStatsRes{
+		UserID:         user.ID,
+		TotalPosts:     totals.TotalPosts,
+		TotalLikes:     totals.LikesReceived,
+		TotalReposts:   totals.RepostsReceived,
+		TotalFollowers: totals.TotalFollowers,
+		TotalFollowing: totals.TotalFollowing,
+		EngagementRate: engagementRate(totals.LikesReceived+totals.RepostsReceived+totals.RepliesReceived, totals.TotalPosts),
+		Interval:       interval,
+		Series:         seriesToRes(points),
+	}, nil
+}
+
+// engagementRate is the average number of interactions per post
+func engagementRate(interactions, posts int64) float64 {
+	if posts == 0 {
+		return 0
+	}
+	return float64(interactions) / float64(posts)
+}
+
+func seriesToRes(points []repository.SeriesPoint) []dto.StatsPointRes {
+	res := make([]dto.StatsPointRes, len(points))
+	for i, p := range points {
+		res[i] = dto.StatsPointRes{
+			Bucket:   p.Bucket.Format(time.RFC3339),
+			Posts:    p.Posts,
+			Likes:    p.Likes,
+			Reposts:  p.Reposts,
+			NewUsers: p.NewUsers,
+		}
+	}
+	return res
+}
@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"goServer/internal/config"
+	"goServer/internal/model"
+	"goServer/internal/repository"
+	"goServer/internal/tracing"
+)
+
+const (
+	// suggestionBatchSize is how many users the job loads per page
+	suggestionBatchSize = 500
+	// suggestionCandidateFactor widens the SQL shortlist so scoring has
+	// more than the final count to choose from
+	suggestionCandidateFactor = 4
+	// suggestionPopularPool is how many of the most followed accounts are
+	// always considered, so users with an empty graph still get suggestions
+	suggestionPopularPool = 20
+)
+
+// SuggestionService recommends accounts to follow. Suggestions are
+// precomputed by Run and served from the stored results.
+type SuggestionService struct {
+	suggestionRepo repository.SuggestionRepository
+	userRepo       repository.UserRepository
+	cfg            config.Config
+}
+
+func NewSuggestionService(sr repository.SuggestionRepository, ur repository.UserRepository, cfg config.Config) *SuggestionService {
+	return &SuggestionService{
+		suggestionRepo: sr,
+		userRepo:       ur,
+		cfg:            cfg,
+	}
+}
+
+// Run rebuilds stale suggestions every interval until ctx is cancelled
+func (s *SuggestionService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.RebuildStale(ctx, time.Now().Add(-interval)); err != nil {
+			log.Printf("[suggestions] %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RebuildStale recomputes the suggestions of every live account that wasn't
+// rebuilt since builtBefore. Only one instance runs it at a time; the others
+// skip the pass. A failure for one user is logged and doesn't stop the others.
+func (s *SuggestionService) RebuildStale(ctx context.Context, builtBefore time.Time) error {
+	ctx, span := tracing.Start(ctx, "SuggestionService.RebuildStale")
+	defer span.End()
+
+	rebuilt := 0
+	locked, err := s.suggestionRepo.WithRebuildLock(ctx, func() error {
+		after := ""
+		for {
+			ids, err := s.suggestionRepo.StaleUserIDsAfter(ctx, after, builtBefore, suggestionBatchSize)
+			if err != nil {
+				return fmt.Errorf("failed to list users: %w", err)
+			}
+			for _, id := range ids {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				if err := s.Rebuild(ctx, id); err != nil {
+					log.Printf("[suggestions] user %s: %v", id, err)
+					continue
+				}
+				rebuilt++
+			}
+			if len(ids) < suggestionBatchSize {
+				return nil
+			}
+			after = ids[len(ids)-1]
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if !locked {
+		log.Printf("[suggestions] another instance is rebuilding suggestions; skipping")
+		return nil
+	}
+
+	log.Printf("[suggestions] rebuilt suggestions for %d users", rebuilt)
+	return nil
+}
+
+// Rebuild recomputes and stores one user's suggestions
+func (s *SuggestionService) Rebuild(ctx context.Context, userID string) error {
+	ctx, span := tracing.Start(ctx, "SuggestionService.Rebuild")
+	defer span.End()
+
+	limit := s.cfg.SuggestionsPerUser
+	since := time.Now().Add(-s.cfg.SuggestionEngagementWindow)
+	candidates, err := s.suggestionRepo.Candidates(ctx, userID, since, suggestionPopularPool, limit*suggestionCandidateFactor)
+	if err != nil {
+		return fmt.Errorf("failed to find candidates: %w", err)
+	}
+
+	suggestions := make([]model.FollowSuggestion, len(candidates))
+	for i, c := range candidates {
+		suggestions[i] = model.FollowSuggestion{
+			UserID:      userID,
+			SuggestedID: c.SuggestedID,
+			Score:       s.score(c),
+			MutualCount: c.MutualCount,
+			SharedCount: c.SharedCount,
+		}
+	}
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].Score > suggestions[j].Score
+	})
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+
+	if err := s.suggestionRepo.Replace(ctx, userID, suggestions); err != nil {
+		return fmt.Errorf("failed to store suggestions: %w", err)
+	}
+	return nil
+}
+
+// score combines a candidate's signals. Each is log-scaled so one very
+// large count can't drown out the others.
+func (s *SuggestionService) score(c repository.SuggestionCandidate) float64 {
+	return s.cfg.SuggestionMutualWeight*math.Log1p(float64(c.MutualCount)) +
+		s.cfg.SuggestionEngagementWeight*math.Log1p(float64(c.SharedCount)) +
+		s.cfg.SuggestionPopularityWeight*math.Log1p(float64(c.Followers))
+}
+
+// List gets a user's current suggestions, best first
+func (s *SuggestionService) List(ctx context.Context, userID string, limit int) ([]model.FollowSuggestion, error) {
+	ctx, span := tracing.Start(ctx, "SuggestionService.List")
+	defer span.End()
+
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > s.cfg.SuggestionsPerUser {
+		limit = s.cfg.SuggestionsPerUser
+	}
+
+	suggestions, err := s.suggestionRepo.List(ctx, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list suggestions: %w", err)
+	}
+	return suggestions, nil
+}
+
+// Dismiss hides an account from a user's suggestions for good
+func (s *SuggestionService) Dismiss(ctx context.Context, userID, dismissedID string) error {
+	ctx, span := tracing.Start(ctx, "SuggestionService.Dismiss")
+	defer span.End()
+
+	if userID == "" || dismissedID == "" {
+		return errors.New("user id is required")
+	}
+
+	dismissed, err := s.userRepo.FindByID(ctx, dismissedID)
+	if err != nil {
+		return fmt.Errorf("failed to find user: %w", err)
+	}
+	if dismissed == nil {
+		return errors.New("user not found")
+	}
+
+	if err := s.suggestionRepo.Dismiss(ctx, userID, dismissedID); err != nil {
+		return fmt.Errorf("failed to dismiss suggestion: %w", err)
+	}
+	return nil
+}
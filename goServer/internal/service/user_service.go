@@ -4,29 +4,84 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
 	"strings"
+	"time"
 
+	"goServer/internal/config"
 	"goServer/internal/dto"
+	"goServer/internal/metrics"
 	"goServer/internal/model"
 	"goServer/internal/repository"
+	"goServer/internal/tracing"
 	"goServer/pkg/utils"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrUsernameTaken      = errors.New("username already taken")
+	ErrUsernameUnchanged  = errors.New("new username is the same as the current one")
+	ErrUsernameCooldown   = errors.New("username was changed too recently")
 )
 
 type UserService struct {
-	userRepo repository.UserRepository
+	userRepo    repository.UserRepository
+	historyRepo repository.UsernameHistoryRepository
+	blockRepo   repository.BlockRepository
+	requestRepo repository.FollowRequestRepository
+	hasher      utils.PasswordHasher
+	policy      *utils.PasswordPolicy
+	cfg         config.Config
+
+	// dummyHash is verified against when the username doesn't exist, so a
+	// miss costs the same hashing work as a wrong password
+	dummyHash string
 }
 
-func NewUserService(r repository.UserRepository) *UserService {
-	return &UserService{userRepo: r}
+// NewUserService fails if it can't prepare the dummy hash, since logins for
+// unknown users would otherwise skip the hashing work and be told apart by
+// their timing
+func NewUserService(r repository.UserRepository, hr repository.UsernameHistoryRepository, br repository.BlockRepository, fr repository.FollowRequestRepository, hasher utils.PasswordHasher, policy *utils.PasswordPolicy, cfg config.Config) (*UserService, error) {
+	dummyHash, err := hasher.Hash("not-a-real-password")
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare dummy password hash: %w", err)
+	}
+	return &UserService{
+		userRepo:    r,
+		historyRepo: hr,
+		blockRepo:   br,
+		requestRepo: fr,
+		hasher:      hasher,
+		policy:      policy,
+		cfg:         cfg,
+		dummyHash:   dummyHash,
+	}, nil
 }
 
 // Register creates a new user account
 func (s *UserService) Register(ctx context.Context, email, username, password string) (*model.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.Register")
+	defer span.End()
+
 	// Validate input
 	if email == "" || username == "" || password == "" {
 		return nil, errors.New("email, username, and password are required")
 	}
 
+	// Store the canonical forms; the handle as typed is kept as the display name
+	displayName := strings.TrimSpace(username)
+	username = utils.NormalizeUsername(username)
+	email = utils.NormalizeEmail(email)
+
+	if err := utils.ValidateUsername(username); err != nil {
+		return nil, err
+	}
+	if err := utils.ValidateEmail(email); err != nil {
+		return nil, err
+	}
+
 	// Check if email already exists
 	existsEmail, err := s.userRepo.ExistsEmail(ctx, email)
 	if err != nil {
@@ -36,26 +91,26 @@ func (s *UserService) Register(ctx context.Context, email, username, password st
 		return nil, errors.New("email already registered")
 	}
 
-	// Check if username already exists
-	existsUsername, err := s.userRepo.ExistsUsername(ctx, username)
-	if err != nil {
-		return nil, fmt.Errorf("failed to check username: %w", err)
+	if err := checkUsernameAvailable(ctx, s.userRepo, s.historyRepo, username, ""); err != nil {
+		return nil, err
 	}
-	if existsUsername {
-		return nil, errors.New("username already taken")
+
+	if err := s.policy.Validate(password, username, email); err != nil {
+		return nil, err
 	}
 
 	// Hash password
-	hashedPassword, err := utils.HashPassword(password)
+	hashedPassword, err := s.hasher.Hash(password)
 	if err != nil {
 		return nil, fmt.Errorf("failed to hash password: %w", err)
 	}
 
 	user := &model.User{
-		Email:    email,
-		Username: username,
-		Password: hashedPassword,
-		Role:     "USER",
+		Email:       email,
+		Username:    username,
+		DisplayName: displayName,
+		Password:    hashedPassword,
+		Role:        "USER",
 	}
 
 	if err := s.userRepo.Create(ctx, user); err != nil {
@@ -65,51 +120,195 @@ func (s *UserService) Register(ctx context.Context, email, username, password st
 	return user, nil
 }
 
-// Authenticate verifies user credentials
-// email and password
-// func (s *UserService) Authenticate(ctx context.Context, email, password string) (*model.User, error) {
-// 	if email == "" || password == "" {
-// 		return nil, errors.New("email and password are required")
-// 	}
-
-// 	user, err := s.userRepo.FindByEmail(ctx, email)
-// 	if err != nil {
-// 		return nil, fmt.Errorf("failed to find user: %w", err)
-// 	}
-// 	if user == nil {
-// 		return nil, errors.New("user not found")
-// 	}
-
-// 	if err := utils.CheckPassword(password, user.Password); err != nil {
-// 		return nil, errors.New("invalid credentials")
-// 	}
-
-//		return user, nil
-//	}
-//
-// username and password
-func (s *UserService) Authenticate(ctx context.Context, username, password string) (*model.User, error) {
-	if username == "" || password == "" {
-		return nil, errors.New("username and password are required")
+// Authenticate verifies user credentials. The identifier is an email
+// address if it contains "@", otherwise a username.
+func (s *UserService) Authenticate(ctx context.Context, identifier, password string) (*model.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.Authenticate")
+	defer span.End()
+
+	if identifier == "" || password == "" {
+		return nil, errors.New("username or email and password are required")
 	}
 
-	user, err := s.userRepo.FindByUsername(ctx, username)
+	var user *model.User
+	var err error
+	if strings.Contains(identifier, "@") {
+		user, err = s.userRepo.FindByEmail(ctx, identifier)
+	} else {
+		user, err = s.userRepo.FindByUsername(ctx, identifier)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	// Always run the hash comparison and return the same error for an
+	// unknown username and a wrong password, so neither the response nor
+	// its timing reveals which usernames exist
+	hash := s.dummyHash
+	if user != nil {
+		hash = user.Password
+	}
+	ok, err := s.hasher.Verify(password, hash)
+	if err != nil || !ok || user == nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	// Upgrade hashes made with an old algorithm or old parameters while
+	// the plaintext is at hand
+	if rehashed, ok, err := upgradeHash(s.hasher, user.Password, password); err != nil {
+		log.Printf("[user] failed to rehash password for user %s: %v", user.ID, err)
+	} else if ok {
+		if err := s.userRepo.SetPasswordHash(ctx, user.ID, rehashed); err != nil {
+			log.Printf("[user] failed to store rehashed password for user %s: %v", user.ID, err)
+		} else {
+			user.Password = rehashed
+		}
+	}
+
+	return user, nil
+}
+
+// upgradeHash returns a fresh hash of password when encoded was made with an
+// old algorithm or old parameters, and ok=false when it's already current
+func upgradeHash(hasher utils.PasswordHasher, encoded, password string) (rehashed string, ok bool, err error) {
+	if !hasher.NeedsRehash(encoded) {
+		return "", false, nil
+	}
+	if rehashed, err = hasher.Hash(password); err != nil {
+		return "", false, err
+	}
+	return rehashed, true, nil
+}
+
+// ChangeUsername gives the user a new handle. Changes are rate limited by a
+// cooldown, and the old handle keeps redirecting to the account (and stays
+// unavailable to others) for the grace period.
+func (s *UserService) ChangeUsername(ctx context.Context, userID, newUsername string) (*model.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.ChangeUsername")
+	defer span.End()
+
+	if userID == "" {
+		return nil, errors.New("user id is required")
+	}
+
+	newUsername = utils.NormalizeUsername(newUsername)
+	if err := utils.ValidateUsername(newUsername); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.FindByID(ctx, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to find user: %w", err)
 	}
 	if user == nil {
 		return nil, errors.New("user not found")
 	}
+	if utils.NormalizeUsername(user.Username) == newUsername {
+		return nil, ErrUsernameUnchanged
+	}
+
+	now := time.Now()
+	if user.UsernameChangedAt != nil {
+		if next := user.UsernameChangedAt.Add(s.cfg.UsernameChangeCooldown); now.Before(next) {
+			return nil, fmt.Errorf("%w; it can be changed again after %s", ErrUsernameCooldown, next.UTC().Format(time.RFC3339))
+		}
+	}
 
-	if err := utils.CheckPassword(password, user.Password); err != nil {
-		return nil, errors.New("invalid credentials")
+	if err := checkUsernameAvailable(ctx, s.userRepo, s.historyRepo, newUsername, userID); err != nil {
+		return nil, err
 	}
 
+	err = s.historyRepo.Transaction(ctx, func(tx *gorm.DB) error {
+		historyRepo := s.historyRepo.WithTx(tx)
+
+		// Taking back one of your own old handles ends its redirect
+		if err := historyRepo.Release(ctx, userID, newUsername, now); err != nil {
+			return err
+		}
+		if err := s.userRepo.WithTx(tx).UpdateUsername(ctx, userID, newUsername); err != nil {
+			return err
+		}
+		return historyRepo.Create(ctx, &model.UsernameHistory{
+			UserID:      userID,
+			OldUsername: user.Username,
+			NewUsername: newUsername,
+			ChangedAt:   now,
+			ReleasedAt:  now.Add(s.cfg.UsernameRedirectGrace),
+		})
+	})
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		// Someone took the name between the availability check and the update
+		return nil, ErrUsernameTaken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to change username: %w", err)
+	}
+
+	user.Username = newUsername
+	user.UsernameChangedAt = &now
 	return user, nil
 }
 
+// checkUsernameAvailable fails if username belongs to an account or is a
+// former handle still held for an account other than exceptUserID
+func checkUsernameAvailable(ctx context.Context, userRepo repository.UserRepository, historyRepo repository.UsernameHistoryRepository, username, exceptUserID string) error {
+	exists, err := userRepo.ExistsUsername(ctx, username)
+	if err != nil {
+		return fmt.Errorf("failed to check username: %w", err)
+	}
+	held, err := historyRepo.IsHeld(ctx, username, exceptUserID, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to check username: %w", err)
+	}
+	if exists || held {
+		return ErrUsernameTaken
+	}
+	return nil
+}
+
+// ResolveUsername finds a user by their current handle, or by a former one
+// that is still within its redirect grace period. movedFrom is the former
+// handle when the lookup was redirected.
+func (s *UserService) ResolveUsername(ctx context.Context, username string) (user *model.User, movedFrom string, err error) {
+	ctx, span := tracing.Start(ctx, "UserService.ResolveUsername")
+	defer span.End()
+
+	if username == "" {
+		return nil, "", errors.New("username is required")
+	}
+
+	user, err = s.userRepo.FindByUsername(ctx, username)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to find user: %w", err)
+	}
+	if user != nil {
+		return user, "", nil
+	}
+
+	h, err := s.historyRepo.FindActive(ctx, username, time.Now())
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to find username history: %w", err)
+	}
+	if h == nil {
+		return nil, "", errors.New("user not found")
+	}
+
+	user, err = s.userRepo.FindByID(ctx, h.UserID)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to find user: %w", err)
+	}
+	if user == nil {
+		return nil, "", errors.New("user not found")
+	}
+
+	return user, h.OldUsername, nil
+}
+
 // GetUserByID retrieves a user by ID
 func (s *UserService) GetUserByID(ctx context.Context, id string) (*model.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetUserByID")
+	defer span.End()
+
 	if id == "" {
 		return nil, errors.New("user id is required")
 	}
@@ -127,6 +326,9 @@ func (s *UserService) GetUserByID(ctx context.Context, id string) (*model.User,
 
 // GetUserByUsername retrieves a user by username
 func (s *UserService) GetUserByUsername(ctx context.Context, username string) (*model.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetUserByUsername")
+	defer span.End()
+
 	if username == "" {
 		return nil, errors.New("username is required")
 	}
@@ -144,6 +346,9 @@ func (s *UserService) GetUserByUsername(ctx context.Context, username string) (*
 
 // UpdateUser updates user profile information
 func (s *UserService) UpdateUser(ctx context.Context, userID string, req dto.UserUpdateReq) (*model.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.UpdateUser")
+	defer span.End()
+
 	if userID == "" {
 		return nil, errors.New("user id is required")
 	}
@@ -171,67 +376,86 @@ func (s *UserService) UpdateUser(ctx context.Context, userID string, req dto.Use
 		return nil, fmt.Errorf("failed to update user: %w", err)
 	}
 
-	return user, nil
-}
-
-// DeleteUser deletes a user account
-func (s *UserService) DeleteUser(ctx context.Context, userID string) error {
-	if userID == "" {
-		return errors.New("user id is required")
-	}
-
-	user, err := s.userRepo.FindByID(ctx, userID)
-	if err != nil {
-		return fmt.Errorf("failed to find user: %w", err)
-	}
-	if user == nil {
-		return errors.New("user not found")
-	}
+	if req.IsPrivate != nil && *req.IsPrivate != user.IsPrivate {
+		if err := s.userRepo.SetPrivate(ctx, userID, *req.IsPrivate); err != nil {
+			return nil, fmt.Errorf("failed to update privacy: %w", err)
+		}
+		user.IsPrivate = *req.IsPrivate
 
-	if err := s.userRepo.Delete(ctx, userID); err != nil {
-		return fmt.Errorf("failed to delete user: %w", err)
+		// Going public lets in everyone who was waiting
+		if !user.IsPrivate {
+			if _, err := s.requestRepo.ApproveAll(ctx, userID); err != nil {
+				return nil, fmt.Errorf("failed to approve follow requests: %w", err)
+			}
+		}
 	}
 
-	return nil
+	return user, nil
 }
 
-// FollowUser creates a follow relationship
-func (s *UserService) FollowUser(ctx context.Context, followerID, followeeID string) error {
+// FollowUser creates a follow relationship. Following a private account
+// instead sends a follow request, reported by requested.
+func (s *UserService) FollowUser(ctx context.Context, followerID, followeeID string) (requested bool, err error) {
+	ctx, span := tracing.Start(ctx, "UserService.FollowUser")
+	defer span.End()
+
 	if followerID == "" || followeeID == "" {
-		return errors.New("follower id and followee id are required")
+		return false, errors.New("follower id and followee id are required")
 	}
 
 	if followerID == followeeID {
-		return errors.New("cannot follow yourself")
+		return false, errors.New("cannot follow yourself")
 	}
 
 	// Check if followee exists
 	followee, err := s.userRepo.FindByID(ctx, followeeID)
 	if err != nil {
-		return fmt.Errorf("failed to find followee: %w", err)
+		return false, fmt.Errorf("failed to find followee: %w", err)
 	}
 	if followee == nil {
-		return errors.New("followee not found")
+		return false, errors.New("followee not found")
+	}
+	if err := checkNotBlocked(ctx, s.blockRepo, followerID, followeeID); err != nil {
+		return false, err
 	}
 
 	// Check if already following
 	isFollowing, err := s.userRepo.IsFollowing(ctx, followerID, followeeID)
 	if err != nil {
-		return fmt.Errorf("failed to check follow status: %w", err)
+		return false, fmt.Errorf("failed to check follow status: %w", err)
 	}
 	if isFollowing {
-		return errors.New("already following this user")
+		return false, errors.New("already following this user")
+	}
+
+	if followee.IsPrivate {
+		pending, err := s.requestRepo.Exists(ctx, followerID, followeeID)
+		if err != nil {
+			return false, fmt.Errorf("failed to check follow requests: %w", err)
+		}
+		if pending {
+			return false, errors.New("follow request already sent")
+		}
+		if err := s.requestRepo.Create(ctx, followerID, followeeID); err != nil {
+			return false, fmt.Errorf("failed to send follow request: %w", err)
+		}
+		return true, nil
 	}
 
 	if err := s.userRepo.FollowUser(ctx, followerID, followeeID); err != nil {
-		return fmt.Errorf("failed to follow user: %w", err)
+		return false, fmt.Errorf("failed to follow user: %w", err)
 	}
+	metrics.FollowsCreated.Inc()
 
-	return nil
+	return false, nil
 }
 
-// UnfollowUser removes a follow relationship
+// UnfollowUser removes a follow relationship, or withdraws a pending follow
+// request
 func (s *UserService) UnfollowUser(ctx context.Context, followerID, followeeID string) error {
+	ctx, span := tracing.Start(ctx, "UserService.UnfollowUser")
+	defer span.End()
+
 	if followerID == "" || followeeID == "" {
 		return errors.New("follower id and followee id are required")
 	}
@@ -242,7 +466,14 @@ func (s *UserService) UnfollowUser(ctx context.Context, followerID, followeeID s
 		return fmt.Errorf("failed to check follow status: %w", err)
 	}
 	if !isFollowing {
-		return errors.New("not following this user")
+		withdrawn, err := s.requestRepo.Delete(ctx, followerID, followeeID)
+		if err != nil {
+			return fmt.Errorf("failed to withdraw follow request: %w", err)
+		}
+		if !withdrawn {
+			return errors.New("not following this user")
+		}
+		return nil
 	}
 
 	if err := s.userRepo.UnfollowUser(ctx, followerID, followeeID); err != nil {
@@ -252,52 +483,125 @@ func (s *UserService) UnfollowUser(ctx context.Context, followerID, followeeID s
 	return nil
 }
 
-// GetFollowers retrieves all followers of a user
-func (s *UserService) GetFollowers(ctx context.Context, username string) ([]model.User, error) {
+// GetFollowers retrieves a page of a user's followers, newest first, and
+// the cursor for the next page ("" on the last page)
+func (s *UserService) GetFollowers(ctx context.Context, username, viewerID, cursor string, limit int) ([]model.User, string, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetFollowers")
+	defer span.End()
+
+	return s.followPage(ctx, username, viewerID, cursor, limit, s.userRepo.GetFollowersPage)
+}
+
+// GetFollowing retrieves a page of the users a user follows, newest first,
+// and the cursor for the next page ("" on the last page)
+func (s *UserService) GetFollowing(ctx context.Context, username, viewerID, cursor string, limit int) ([]model.User, string, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetFollowing")
+	defer span.End()
+
+	return s.followPage(ctx, username, viewerID, cursor, limit, s.userRepo.GetFollowingPage)
+}
+
+// followPage loads one page of a follow list with the given repository
+// query. A private account's lists are only shown to approved followers.
+func (s *UserService) followPage(ctx context.Context, username, viewerID, cursor string, limit int,
+	query func(context.Context, string, *utils.Cursor, int) ([]repository.FollowEdge, error)) ([]model.User, string, error) {
 	if username == "" {
-		return nil, errors.New("username is required")
+		return nil, "", errors.New("username is required")
+	}
+
+	after, err := utils.DecodeCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 20
 	}
 
 	user, err := s.userRepo.FindByUsername(ctx, username)
 	if err != nil {
-		return nil, fmt.Errorf("failed to find user: %w", err)
+		return nil, "", fmt.Errorf("failed to find user: %w", err)
 	}
 	if user == nil {
-		return nil, errors.New("user not found")
+		return nil, "", errors.New("user not found")
+	}
+	if err := checkNotBlocked(ctx, s.blockRepo, viewerID, user.ID); err != nil {
+		return nil, "", errors.New("user not found")
+	}
+	if err := checkCanSeePosts(ctx, s.userRepo, viewerID, user); err != nil {
+		return nil, "", err
 	}
 
-	followers, err := s.userRepo.GetFollowers(ctx, user.ID)
+	// Fetch one extra row to learn whether there is another page
+	edges, err := query(ctx, user.ID, after, limit+1)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get followers: %w", err)
+		return nil, "", fmt.Errorf("failed to get follows: %w", err)
 	}
 
-	return followers, nil
-}
-
-// GetFollowing retrieves all users that a user is following
-func (s *UserService) GetFollowing(ctx context.Context, username string) ([]model.User, error) {
-	if username == "" {
-		return nil, errors.New("username is required")
+	next := ""
+	if len(edges) > limit {
+		edges = edges[:limit]
+		last := edges[limit-1]
+		next = utils.Cursor{At: last.FollowedAt, ID: last.User.ID}.Encode()
 	}
 
-	user, err := s.userRepo.FindByUsername(ctx, username)
-	if err != nil {
-		return nil, fmt.Errorf("failed to find user: %w", err)
+	users := make([]model.User, len(edges))
+	for i, e := range edges {
+		users[i] = e.User
 	}
-	if user == nil {
-		return nil, errors.New("user not found")
+	return users, next, nil
+}
+
+// DescribeUsers builds the detailed view of users as seen by viewerID, with
+// counts and follow flags loaded in batch. Email is only included for the
+// viewer's own account.
+func (s *UserService) DescribeUsers(ctx context.Context, viewerID string, users []model.User) ([]dto.UserDetailRes, error) {
+	ctx, span := tracing.Start(ctx, "UserService.DescribeUsers")
+	defer span.End()
+
+	ids := make([]string, len(users))
+	for i, u := range users {
+		ids[i] = u.ID
 	}
 
-	following, err := s.userRepo.GetFollowing(ctx, user.ID)
+	counts, err := s.userRepo.GetCounts(ctx, ids)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get following: %w", err)
+		return nil, fmt.Errorf("failed to count follows: %w", err)
 	}
-
-	return following, nil
+	following, followedBy, err := s.userRepo.FollowFlags(ctx, viewerID, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check follow status: %w", err)
+	}
+
+	res := make([]dto.UserDetailRes, len(users))
+	for i, u := range users {
+		c := counts[u.ID]
+		res[i] = dto.UserDetailRes{
+			ID:             u.ID,
+			Username:       u.Username,
+			DisplayName:    u.DisplayName,
+			Bio:            u.Bio,
+			AvatarURL:      u.AvatarURL,
+			IsPrivate:      u.IsPrivate,
+			Role:           u.Role,
+			FollowerCount:  c.Followers,
+			FollowingCount: c.Following,
+			PostCount:      c.Posts,
+			IsFollowing:    following[u.ID],
+			FollowsYou:     followedBy[u.ID],
+			CreatedAt:      u.CreatedAt.String(),
+		}
+		if u.ID == viewerID {
+			res[i].Email = u.Email
+		}
+	}
+	return res, nil
 }
 
 // GetFollowerCount gets the number of followers
 func (s *UserService) GetFollowerCount(ctx context.Context, userID string) (int64, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetFollowerCount")
+	defer span.End()
+
 	if userID == "" {
 		return 0, errors.New("user id is required")
 	}
@@ -312,6 +616,9 @@ func (s *UserService) GetFollowerCount(ctx context.Context, userID string) (int6
 
 // GetFollowingCount gets the number of users being followed
 func (s *UserService) GetFollowingCount(ctx context.Context, userID string) (int64, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetFollowingCount")
+	defer span.End()
+
 	if userID == "" {
 		return 0, errors.New("user id is required")
 	}
@@ -325,7 +632,10 @@ func (s *UserService) GetFollowingCount(ctx context.Context, userID string) (int
 }
 
 // SearchUsers searches for users by username or display name
-func (s *UserService) SearchUsers(ctx context.Context, query string, limit, offset int) ([]model.User, error) {
+func (s *UserService) SearchUsers(ctx context.Context, query, viewerID string, limit, offset int) ([]model.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.SearchUsers")
+	defer span.End()
+
 	if query == "" {
 		return nil, errors.New("search query is required")
 	}
@@ -337,7 +647,7 @@ func (s *UserService) SearchUsers(ctx context.Context, query string, limit, offs
 		offset = 0
 	}
 
-	users, err := s.userRepo.SearchUsers(ctx, query, limit, offset)
+	users, err := s.userRepo.SearchUsers(ctx, query, viewerID, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search users: %w", err)
 	}
@@ -347,6 +657,9 @@ func (s *UserService) SearchUsers(ctx context.Context, query string, limit, offs
 
 // GetAllUsers retrieves all users with pagination
 func (s *UserService) GetAllUsers(ctx context.Context, limit, offset int) ([]model.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetAllUsers")
+	defer span.End()
+
 	if limit <= 0 || limit > 100 {
 		limit = 20
 	}
@@ -362,39 +675,30 @@ func (s *UserService) GetAllUsers(ctx context.Context, limit, offset int) ([]mod
 	return users, nil
 }
 
-// UpdateUserRole updates a user's role (admin only)
-func (s *UserService) UpdateUserRole(ctx context.Context, userID, role string) (*model.User, error) {
-	if userID == "" || role == "" {
-		return nil, errors.New("user id and role are required")
-	}
+// IsFollowing checks if one user follows another
+func (s *UserService) IsFollowing(ctx context.Context, followerID, followeeID string) (bool, error) {
+	ctx, span := tracing.Start(ctx, "UserService.IsFollowing")
+	defer span.End()
 
-	// Validate role
-	if role != "USER" && role != "ADMIN" {
-		return nil, errors.New("invalid role")
+	if followerID == "" || followeeID == "" {
+		return false, errors.New("follower id and followee id are required")
 	}
 
+	return s.userRepo.IsFollowing(ctx, followerID, followeeID)
+}
+
+// ValidateToken rejects tokens whose subject no longer exists or whose
+// token version was bumped after the token was issued
+func (s *UserService) ValidateToken(ctx context.Context, userID string, tokenVersion int) error {
 	user, err := s.userRepo.FindByID(ctx, userID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to find user: %w", err)
+		return fmt.Errorf("failed to find user: %w", err)
 	}
 	if user == nil {
-		return nil, errors.New("user not found")
-	}
-
-	user.Role = role
-
-	if err := s.userRepo.Update(ctx, user); err != nil {
-		return nil, fmt.Errorf("failed to update user role: %w", err)
+		return errors.New("user not found")
 	}
-
-	return user, nil
-}
-
-// IsFollowing checks if one user follows another
-func (s *UserService) IsFollowing(ctx context.Context, followerID, followeeID string) (bool, error) {
-	if followerID == "" || followeeID == "" {
-		return false, errors.New("follower id and followee id are required")
+	if user.TokenVersion != tokenVersion {
+		return errors.New("token has been revoked")
 	}
-
-	return s.userRepo.IsFollowing(ctx, followerID, followeeID)
+	return nil
 }
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"goServer/internal/config"
+	"goServer/internal/repository"
+	"goServer/pkg/utils"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// failingHasher can't hash anything, like a hasher whose random source broke
+type failingHasher struct{ utils.PasswordHasher }
+
+func (failingHasher) Hash(string) (string, error) { return "", errors.New("no entropy") }
+
+func TestNewUserServiceFailsWithoutDummyHash(t *testing.T) {
+	_, err := NewUserService(repository.UserRepository{}, repository.UsernameHistoryRepository{}, repository.BlockRepository{}, repository.FollowRequestRepository{},
+		failingHasher{}, nil, config.Config{})
+	if err == nil {
+		t.Error("NewUserService succeeded without a dummy hash")
+	}
+}
+
+func TestUpgradeHash(t *testing.T) {
+	hasher := utils.NewArgon2idHasher(utils.ArgonConfig{Time: 1, Memory: 1024, Threads: 1})
+
+	legacy, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("GenerateFromPassword: %v", err)
+	}
+	rehashed, ok, err := upgradeHash(hasher, string(legacy), "correct horse")
+	if err != nil || !ok {
+		t.Fatalf("upgradeHash(bcrypt) = %v, %v", ok, err)
+	}
+	if verified, err := hasher.Verify("correct horse", rehashed); err != nil || !verified {
+		t.Errorf("the upgraded hash doesn't verify: %v, %v", verified, err)
+	}
+
+	if _, ok, err := upgradeHash(hasher, rehashed, "correct horse"); err != nil || ok {
+		t.Errorf("upgradeHash(current hash) = %v, %v, want no upgrade", ok, err)
+	}
+}
@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"errors"
+	"net/mail"
+	"regexp"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+var (
+	ErrUsernameInvalid  = errors.New("username must be 3-30 characters of letters, digits and underscores, starting with a letter or digit")
+	ErrUsernameReserved = errors.New("username is reserved")
+	ErrEmailInvalid     = errors.New("invalid email address")
+)
+
+var usernamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_]{2,29}$`)
+
+// reservedUsernames can't be registered because they collide with routes
+// (/users/me, /users/search), could be used to impersonate staff, or
+// confuse clients
+var reservedUsernames = map[string]struct{}{
+	"about": {}, "account": {}, "accounts": {}, "admin": {}, "administrator": {},
+	"api": {}, "auth": {}, "billing": {}, "blog": {}, "bookmarks": {},
+	"contact": {}, "dashboard": {}, "explore": {}, "feed": {}, "help": {},
+	"home": {}, "info": {}, "login": {}, "logout": {}, "mail": {},
+	"me": {}, "mod": {}, "moderator": {}, "new": {}, "notifications": {},
+	"null": {}, "official": {}, "posts": {}, "privacy": {}, "register": {},
+	"root": {}, "search": {}, "security": {}, "settings": {}, "signup": {},
+	"staff": {}, "stats": {}, "status": {}, "support": {}, "system": {},
+	"terms": {}, "undefined": {}, "user": {}, "users": {}, "www": {},
+}
+
+// NormalizeUsername folds a username to its canonical form: NFKC so
+// look-alike compatibility characters collapse together, then lowercase
+func NormalizeUsername(username string) string {
+	return strings.ToLower(norm.NFKC.String(strings.TrimSpace(username)))
+}
+
+// NormalizeEmail folds an email address the same way as usernames
+func NormalizeEmail(email string) string {
+	return strings.ToLower(norm.NFKC.String(strings.TrimSpace(email)))
+}
+
+// ValidateUsername checks a normalized username against the character
+// policy and the reserved-words list
+func ValidateUsername(username string) error {
+	if !usernamePattern.MatchString(username) {
+		return ErrUsernameInvalid
+	}
+	if _, ok := reservedUsernames[username]; ok {
+		return ErrUsernameReserved
+	}
+	return nil
+}
+
+// ValidateEmail checks that a normalized email is a bare address
+func ValidateEmail(email string) error {
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return ErrEmailInvalid
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var ErrUnknownHashFormat = errors.New("unknown password hash format")
+
+// PasswordHasher hashes new passwords and verifies stored hashes, including
+// hashes written by older algorithms or parameters
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	Verify(password, encoded string) (bool, error)
+	// NeedsRehash reports whether a stored hash should be replaced with
+	// one produced by Hash, e.g. after the parameters were raised
+	NeedsRehash(encoded string) bool
+}
+
+type ArgonConfig struct {
+	Time    uint32
+	Memory  uint32 // KiB
+	Threads uint8
+	KeyLen  uint32
+	SaltLen uint32
+}
+
+var DefaultArgonConfig = ArgonConfig{
+	Time:    1,
+	Memory:  64 * 1024,
+	Threads: 4,
+	KeyLen:  32,
+	SaltLen: 16,
+}
+
+// Argon2idHasher writes argon2id hashes in PHC string format:
+//
+//	$argon2id$v=19$m=65536,t=1,p=4$<salt>$<hash>
+//
+// Because the parameters travel with each hash, they can be raised at any
+// time; older hashes still verify and get upgraded through NeedsRehash.
+// bcrypt hashes from before the switch are verified as well.
+type Argon2idHasher struct {
+	cfg ArgonConfig
+}
+
+func NewArgon2idHasher(cfg ArgonConfig) *Argon2idHasher {
+	if cfg.KeyLen == 0 {
+		cfg.KeyLen = DefaultArgonConfig.KeyLen
+	}
+	if cfg.SaltLen == 0 {
+		cfg.SaltLen = DefaultArgonConfig.SaltLen
+	}
+	return &Argon2idHasher{cfg: cfg}
+}
+
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.cfg.SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.cfg.Time, h.cfg.Memory, h.cfg.Threads, h.cfg.KeyLen)
+	return encodeArgon2id(argon2Hash{
+		version: argon2.Version,
+		memory:  h.cfg.Memory,
+		time:    h.cfg.Time,
+		threads: h.cfg.Threads,
+		salt:    salt,
+		key:     key,
+	}), nil
+}
+
+func (h *Argon2idHasher) Verify(password, encoded string) (bool, error) {
+	switch {
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		p, err := decodeArgon2id(encoded)
+		if err != nil {
+			return false, err
+		}
+		key := argon2.IDKey([]byte(password), p.salt, p.time, p.memory, p.threads, uint32(len(p.key)))
+		return subtle.ConstantTimeCompare(key, p.key) == 1, nil
+
+	case isBcrypt(encoded):
+		err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		return err == nil, err
+
+	default:
+		return false, ErrUnknownHashFormat
+	}
+}
+
+func (h *Argon2idHasher) NeedsRehash(encoded string) bool {
+	p, err := decodeArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+	return p.version != argon2.Version ||
+		p.memory != h.cfg.Memory ||
+		p.time != h.cfg.Time ||
+		p.threads != h.cfg.Threads ||
+		uint32(len(p.key)) != h.cfg.KeyLen ||
+		uint32(len(p.salt)) != h.cfg.SaltLen
+}
+
+type argon2Hash struct {
+	version int
+	memory  uint32
+	time    uint32
+	threads uint8
+	salt    []byte
+	key     []byte
+}
+
+func encodeArgon2id(p argon2Hash) string {
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		p.version, p.memory, p.time, p.threads,
+		base64.RawStdEncoding.EncodeToString(p.salt),
+		base64.RawStdEncoding.EncodeToString(p.key))
+}
+
+func decodeArgon2id(encoded string) (argon2Hash, error) {
+	var p argon2Hash
+
+	// "", "argon2id", "v=19", "m=..,t=..,p=..", salt, hash
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return p, ErrUnknownHashFormat
+	}
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &p.version); err != nil {
+		return p, fmt.Errorf("invalid argon2id version: %w", err)
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.time, &p.threads); err != nil {
+		return p, fmt.Errorf("invalid argon2id parameters: %w", err)
+	}
+
+	var err error
+	if p.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return p, fmt.Errorf("invalid argon2id salt: %w", err)
+	}
+	if p.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		return p, fmt.Errorf("invalid argon2id hash: %w", err)
+	}
+	if len(p.key) == 0 {
+		return p, ErrUnknownHashFormat
+	}
+	return p, nil
+}
+
+func isBcrypt(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") ||
+		strings.HasPrefix(encoded, "$2b$") ||
+		strings.HasPrefix(encoded, "$2y$")
+}
@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"bufio"
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+//go:embed common_passwords.txt
+var commonPasswords string
+
+var (
+	ErrPasswordTooShort   = errors.New("password is too short")
+	ErrPasswordTooLong    = errors.New("password is too long")
+	ErrPasswordCommon     = errors.New("password is too common or has appeared in a data breach")
+	ErrPasswordHasAccount = errors.New("password must not contain your username or email")
+)
+
+// PasswordPolicy validates new passwords
+type PasswordPolicy struct {
+	MinLength int
+	MaxLength int
+	blocklist map[string]struct{}
+}
+
+// NewPasswordPolicy builds a policy whose blocklist is the embedded list of
+// common passwords plus any extra entries, e.g. from a breach corpus
+func NewPasswordPolicy(minLength, maxLength int, extra []string) *PasswordPolicy {
+	p := &PasswordPolicy{
+		MinLength: minLength,
+		MaxLength: maxLength,
+		blocklist: make(map[string]struct{}),
+	}
+	for _, pw := range strings.Split(commonPasswords, "\n") {
+		p.block(pw)
+	}
+	for _, pw := range extra {
+		p.block(pw)
+	}
+	return p
+}
+
+func (p *PasswordPolicy) block(pw string) {
+	if pw = strings.ToLower(strings.TrimSpace(pw)); pw != "" {
+		p.blocklist[pw] = struct{}{}
+	}
+}
+
+// Validate checks a password for the account with the given username and email
+func (p *PasswordPolicy) Validate(password, username, email string) error {
+	n := utf8.RuneCountInString(password)
+	if n < p.MinLength {
+		return fmt.Errorf("%w: must be at least %d characters", ErrPasswordTooShort, p.MinLength)
+	}
+	if p.MaxLength > 0 && n > p.MaxLength {
+		return fmt.Errorf("%w: must be at most %d characters", ErrPasswordTooLong, p.MaxLength)
+	}
+
+	lower := strings.ToLower(password)
+	if _, ok := p.blocklist[lower]; ok {
+		return ErrPasswordCommon
+	}
+
+	local, _, _ := strings.Cut(email, "@")
+	for _, part := range []string{username, local} {
+		part = strings.ToLower(strings.TrimSpace(part))
+		// Very short handles would match too many unrelated passwords
+		if utf8.RuneCountInString(part) >= 3 && strings.Contains(lower, part) {
+			return ErrPasswordHasAccount
+		}
+	}
+
+	return nil
+}
+
+// LoadPasswordBlocklist reads one password per line from a file
+func LoadPasswordBlocklist(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var list []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		list = append(list, sc.Text())
+	}
+	return list, sc.Err()
+}
@@ -0,0 +1,127 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testArgonConfig keeps hashing fast; the format doesn't depend on the cost
+var testArgonConfig = ArgonConfig{Time: 1, Memory: 1024, Threads: 1}
+
+func TestArgon2idRoundTrip(t *testing.T) {
+	h := NewArgon2idHasher(testArgonConfig)
+
+	encoded, err := h.Hash("correct horse")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	if !strings.HasPrefix(encoded, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Errorf("Hash = %q, want a PHC string with the configured parameters", encoded)
+	}
+
+	if ok, err := h.Verify("correct horse", encoded); err != nil || !ok {
+		t.Errorf("Verify(right password) = %v, %v", ok, err)
+	}
+	if ok, err := h.Verify("wrong horse", encoded); err != nil || ok {
+		t.Errorf("Verify(wrong password) = %v, %v", ok, err)
+	}
+	if h.NeedsRehash(encoded) {
+		t.Error("NeedsRehash is true for a hash made with the current parameters")
+	}
+
+	other, err := h.Hash("correct horse")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	if other == encoded {
+		t.Error("two hashes of the same password share a salt")
+	}
+}
+
+func TestArgon2idEncodeDecode(t *testing.T) {
+	p := argon2Hash{
+		version: argon2.Version,
+		memory:  65536,
+		time:    3,
+		threads: 4,
+		salt:    []byte("0123456789abcdef"),
+		key:     bytes.Repeat([]byte{0xab}, 32),
+	}
+
+	encoded := encodeArgon2id(p)
+	if encoded != "$argon2id$v=19$m=65536,t=3,p=4$MDEyMzQ1Njc4OWFiY2RlZg$q6urq6urq6urq6urq6urq6urq6urq6urq6urq6urq6s" {
+		t.Errorf("encodeArgon2id = %q", encoded)
+	}
+
+	got, err := decodeArgon2id(encoded)
+	if err != nil {
+		t.Fatalf("decodeArgon2id: %v", err)
+	}
+	if got.version != p.version || got.memory != p.memory || got.time != p.time || got.threads != p.threads ||
+		!bytes.Equal(got.salt, p.salt) || !bytes.Equal(got.key, p.key) {
+		t.Errorf("decodeArgon2id = %+v, want %+v", got, p)
+	}
+}
+
+func TestArgon2idDecodeRejectsMalformed(t *testing.T) {
+	for _, encoded := range []string{
+		"",
+		"$argon2i$v=19$m=1024,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=1024,t=1,p=1$c2FsdA",
+		"$argon2id$v=x$m=1024,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=1024$c2FsdA$a2V5",
+		"$argon2id$v=19$m=1024,t=1,p=1$!!$a2V5",
+		"$argon2id$v=19$m=1024,t=1,p=1$c2FsdA$",
+	} {
+		if _, err := decodeArgon2id(encoded); err == nil {
+			t.Errorf("decodeArgon2id(%q) succeeded", encoded)
+		}
+	}
+
+	h := NewArgon2idHasher(testArgonConfig)
+	if _, err := h.Verify("password", "plaintext"); !errors.Is(err, ErrUnknownHashFormat) {
+		t.Errorf("Verify(unknown format) = %v, want ErrUnknownHashFormat", err)
+	}
+}
+
+func TestArgon2idNeedsRehashAfterParametersChange(t *testing.T) {
+	old := NewArgon2idHasher(testArgonConfig)
+	encoded, err := old.Hash("correct horse")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+
+	raised := testArgonConfig
+	raised.Time = 2
+	h := NewArgon2idHasher(raised)
+	if !h.NeedsRehash(encoded) {
+		t.Error("NeedsRehash is false after the time cost was raised")
+	}
+	if ok, err := h.Verify("correct horse", encoded); err != nil || !ok {
+		t.Errorf("Verify(hash with old parameters) = %v, %v", ok, err)
+	}
+}
+
+func TestArgon2idVerifiesLegacyBcrypt(t *testing.T) {
+	h := NewArgon2idHasher(testArgonConfig)
+
+	legacy, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("GenerateFromPassword: %v", err)
+	}
+
+	if ok, err := h.Verify("correct horse", string(legacy)); err != nil || !ok {
+		t.Errorf("Verify(right password) = %v, %v", ok, err)
+	}
+	if ok, err := h.Verify("wrong horse", string(legacy)); err != nil || ok {
+		t.Errorf("Verify(wrong password) = %v, %v", ok, err)
+	}
+	if !h.NeedsRehash(string(legacy)) {
+		t.Error("NeedsRehash is false for a bcrypt hash")
+	}
+}
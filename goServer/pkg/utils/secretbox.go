@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// sealedPrefix marks values produced by SealSecret
+const sealedPrefix = "enc:v1:"
+
+var ErrInvalidSealedSecret = errors.New("invalid sealed secret")
+
+// SealSecret encrypts plaintext with AES-256-GCM under a key derived from
+// passphrase, for secrets the server has to read back (unlike passwords,
+// which are only ever hashed)
+func SealSecret(passphrase, plaintext string) (string, error) {
+	aead, err := secretAEAD(passphrase)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return sealedPrefix + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// OpenSecret decrypts a value made by SealSecret with the same passphrase
+func OpenSecret(passphrase, sealed string) (string, error) {
+	body, ok := strings.CutPrefix(sealed, sealedPrefix)
+	if !ok {
+		return "", ErrInvalidSealedSecret
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(body)
+	if err != nil {
+		return "", ErrInvalidSealedSecret
+	}
+
+	aead, err := secretAEAD(passphrase)
+	if err != nil {
+		return "", err
+	}
+	if len(raw) < aead.NonceSize() {
+		return "", ErrInvalidSealedSecret
+	}
+	plain, err := aead.Open(nil, raw[:aead.NonceSize()], raw[aead.NonceSize():], nil)
+	if err != nil {
+		return "", ErrInvalidSealedSecret
+	}
+	return string(plain), nil
+}
+
+// IsSealedSecret reports whether v was made by SealSecret
+func IsSealedSecret(v string) bool {
+	return strings.HasPrefix(v, sealedPrefix)
+}
+
+func secretAEAD(passphrase string) (cipher.AEAD, error) {
+	key := sha256.Sum256([]byte("sealed-secret|" + passphrase))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// A mention or hashtag starts the text or follows a character that
+	// can't be part of a word, so emails and URL fragments don't match
+	mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_@])@([A-Za-z0-9][A-Za-z0-9_]{2,29})\b`)
+	hashtagPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_#&])#([\p{L}\p{N}_]{1,100})`)
+)
+
+// ExtractMentions returns the normalized usernames mentioned in text, once
+// each, in order of first appearance
+func ExtractMentions(text string) []string {
+	return extract(mentionPattern, text, NormalizeUsername)
+}
+
+// ExtractHashtags returns the lowercase hashtags in text, once each, in
+// order of first appearance. Tags made only of digits are ignored.
+func ExtractHashtags(text string) []string {
+	tags := extract(hashtagPattern, text, strings.ToLower)
+	kept := tags[:0]
+	for _, tag := range tags {
+		if strings.Trim(tag, "0123456789") != "" {
+			kept = append(kept, tag)
+		}
+	}
+	return kept
+}
+
+func extract(pattern *regexp.Regexp, text string, normalize func(string) string) []string {
+	seen := make(map[string]bool)
+	var found []string
+	for _, m := range pattern.FindAllStringSubmatch(text, -1) {
+		v := normalize(m[1])
+		if !seen[v] {
+			seen[v] = true
+			found = append(found, v)
+		}
+	}
+	return found
+}
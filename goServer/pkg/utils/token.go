@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GenerateSignedToken returns a random token bound to purpose by an HMAC
+// signature, plus the hash that should be stored in place of the token.
+// Format: base64url(random) "." base64url(hmac(secret, purpose|random))
+func GenerateSignedToken(secret, purpose string) (token string, hash string, err error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+
+	body := base64.RawURLEncoding.EncodeToString(raw)
+	token = body + "." + sign(secret, purpose, body)
+	return token, HashToken(token), nil
+}
+
+// VerifySignedToken checks the signature so forged or mistyped tokens are
+// rejected before any database lookup
+func VerifySignedToken(secret, purpose, token string) bool {
+	body, sig, ok := strings.Cut(token, ".")
+	if !ok || body == "" || sig == "" {
+		return false
+	}
+	return hmac.Equal([]byte(sig), []byte(sign(secret, purpose, body)))
+}
+
+// SignExpiring signs id together with an expiry time, for links that grant
+// access to a resource without a login
+func SignExpiring(secret, purpose, id string, expires time.Time) string {
+	return sign(secret, purpose, id+"|"+strconv.FormatInt(expires.Unix(), 10))
+}
+
+// VerifyExpiring checks a signature made by SignExpiring and that it hasn't expired
+func VerifyExpiring(secret, purpose, id string, expires int64, sig string) bool {
+	if time.Now().Unix() > expires {
+		return false
+	}
+	want := sign(secret, purpose, id+"|"+strconv.FormatInt(expires, 10))
+	return hmac.Equal([]byte(sig), []byte(want))
+}
+
+// RandomToken returns n random bytes, base64url encoded without padding
+func RandomToken(n int) (string, error) {
+	raw := make([]byte, n)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// HashToken returns the hex SHA-256 of a token for storage and lookup
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func sign(secret, purpose, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(purpose + "|" + body))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}